@@ -24,7 +24,7 @@ func (a *API) AcquisitionEras() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	err := executeAll(a.Writer, a.Separator, stm, args...)
+	err := a.query(stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.acquisitioners.AcquisitionEras")
 	}
@@ -150,6 +150,7 @@ func (a *API) InsertAcquisitionEras() error {
 	if err != nil {
 		return Error(err, InsertErrorCode, "", "dbs.acquisitioneras.InsertAcquisitionEras")
 	}
+	InvalidateCache("ACQUISITION_ERAS")
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
@@ -213,6 +214,7 @@ func (a *API) UpdateAcquisitionEras() error {
 		log.Println(e)
 		return e
 	}
+	InvalidateCache("ACQUISITION_ERAS")
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
@@ -21,7 +21,8 @@ func (a *API) AcquisitionErasCi() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	tx, err := DB.Begin()
+	db := a.readerDB()
+	tx, err := db.Begin()
 	if err != nil {
 		msg := "unable to get DB transaction"
 		return Error(err, TransactionErrorCode, msg, "dbs.acquisitionerasci.AcquisitionErasCi")
@@ -31,7 +32,7 @@ func (a *API) AcquisitionErasCi() error {
 		return Error(err, SessionErrorCode, "", "dbs.acquisitionerasci.AcquisitionErasCi")
 	}
 
-	e := executeAll(a.Writer, a.Separator, stm, args...)
+	e := executeAll(db, a.Writer, a.Separator, stm, args...)
 	if err := executeSessions(tx, postSession); err != nil {
 		return Error(err, SessionErrorCode, "", "dbs.acquisitionerasci.AcquisitionErasCi")
 	}
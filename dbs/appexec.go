@@ -90,5 +90,6 @@ func (a *API) InsertApplicationExecutables() error {
 	if err != nil {
 		return Error(err, InsertErrorCode, "", "dbs.appexec.InsertApplicationExecutables")
 	}
+	InvalidateCache("APPLICATION_EXECUTABLES")
 	return nil
 }
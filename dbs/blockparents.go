@@ -21,6 +21,9 @@ func (a *API) BlockParents() error {
 	// parse dataset argument
 	blockparent := getValues(a.Params, "block_name")
 	if len(blockparent) > 1 {
+		if err := checkPlainValues("block_name", blockparent); err != nil {
+			return Error(err, ParametersErrorCode, "", "dbs.blockparents.BlockParents")
+		}
 		cond := fmt.Sprintf("BC.BLOCK_NAME in %s", TokenCondition())
 		// 100 is max for # of allowed blocks
 		token, binds := TokenGenerator(blockparent, 100, "block_token")
@@ -40,7 +43,7 @@ func (a *API) BlockParents() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	err = executeAll(a.Writer, a.Separator, stm, args...)
+	err = a.query(stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.blockparents.BlockParents")
 	}
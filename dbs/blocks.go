@@ -73,6 +73,8 @@ func (a *API) Blocks() error {
 	conds, args = AddParam("block_name", "B.BLOCK_NAME", a.Params, conds, args)
 	conds, args = AddParam("dataset", "DS.DATASET", a.Params, conds, args)
 	conds, args = AddParam("origin_site_name", "B.ORIGIN_SITE_NAME", a.Params, conds, args)
+	conds, args = AddParam("block_size", "B.BLOCK_SIZE", a.Params, conds, args)
+	conds, args = AddParam("file_count", "B.FILE_COUNT", a.Params, conds, args)
 	conds, args = AddParam("cdate", "B.CREATION_DATE", a.Params, conds, args)
 
 	minDate := getValues(a.Params, "min_cdate")
@@ -130,7 +132,7 @@ func (a *API) Blocks() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	err = executeAll(a.Writer, a.Separator, stm, args...)
+	err = a.query(stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.blocks.Blocks")
 	}
@@ -353,6 +355,7 @@ func (a *API) InsertBlocks() error {
 		log.Println("fail to commit transaction", err)
 		return Error(err, CommitErrorCode, "", "dbs.blocks.InsertBlocks")
 	}
+	InvalidateCache("BLOCKS")
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
@@ -451,6 +454,7 @@ func (a *API) UpdateBlocks() error {
 		log.Println("unable to commit transaction", err)
 		return Error(err, CommitErrorCode, "", "dbs.blocks.UpdateBlocks")
 	}
+	InvalidateCache("BLOCKS")
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
@@ -84,7 +84,7 @@ func (a *API) BlockSummaries() error {
 		}
 	}
 	// use generic query API to fetch the results from DB
-	err = executeAll(a.Writer, a.Separator, genSQL+stm, args...)
+	err = executeAll(a.readerDB(), a.Writer, a.Separator, genSQL+stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.blocksummaries.BlockSummaries")
 	}
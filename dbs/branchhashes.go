@@ -91,5 +91,6 @@ func (a *API) InsertBranchHashes() error {
 	if err != nil {
 		return Error(err, InsertErrorCode, "", "dbs.branchhashes.InsertBranchHashes")
 	}
+	InvalidateCache("BRANCH_HASHES")
 	return nil
 }
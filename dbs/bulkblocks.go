@@ -25,8 +25,9 @@ type BulkBlocks struct {
 	Block             Block              `json:"block"`
 	FileParentList    []FileParentRecord `json:"file_parent_list"`
 	BlockParentList   []BlockParent      `json:"block_parent_list"`
-	DatasetParentList []string           `json:"dataset_parent_list"` // used by bulkblocks API
-	DsParentList      []DatasetParent    `json:"ds_parent_list"`      // provided by bulkdump API
+	DatasetParentList []string           `json:"dataset_parent_list"`          // used by bulkblocks API
+	DsParentList      []DatasetParent    `json:"ds_parent_list"`               // provided by bulkdump API
+	InferFileParents  bool               `json:"infer_file_parents,omitempty"` // infer file parents if file_parent_list is not provided
 }
 
 // DatasetConfig represents dataset config structure used in BulkBlocks structure
@@ -179,6 +180,10 @@ func (a *API) InsertBulkBlocks() error {
 		plfn := r.ParentLogicalFileName
 		pfid, err := QueryRow("FILES", "file_id", "logical_file_name", plfn)
 		if err != nil {
+			// missing parents are reported by parentage validation
+			if BulkBlocksParentageCheck != "" {
+				continue
+			}
 			msg := fmt.Sprintf("unable to find parent lfn %s", plfn)
 			return Error(err, DatabaseErrorCode, msg, "dbs.bulkblocks.InsertBulkBlocksConcurrently")
 		}
@@ -207,6 +212,15 @@ func (a *API) InsertBulkBlocks() error {
 	if !strings.Contains(string(data), "is_file_valid") {
 		isFileValid = 1
 	}
+	// check if injected lumis already exist in the dataset
+	err = checkBulkBlocksLumis(tx, rec.Dataset.Dataset, rec.Files, isFileValid, hash)
+	if err != nil {
+		return err
+	}
+	// validate parentage of injected files
+	if err = checkBulkBlocksParentage(tx, rec, hash); err != nil {
+		return err
+	}
 	// insert dataset configuration
 	if utils.VERBOSE > 1 {
 		log.Println("insert output configs")
@@ -761,6 +775,18 @@ func (a *API) InsertBulkBlocks() error {
 		}
 	}
 
+	// infer file parents from run/lumi overlap with files of parent datasets
+	// when it is requested and file parent list is not provided
+	if rec.InferFileParents && len(rec.FileParentList) == 0 {
+		_, err = insertInferredFileParents(tx, rec.Dataset.Dataset, rec.Block.BlockName)
+		if err != nil {
+			if utils.VERBOSE > 1 {
+				log.Println("unable to infer file parents", err)
+			}
+			return Error(err, InsertErrorCode, "", "dbs.bulkblocks.InsertBulkBlocks")
+		}
+	}
+
 	// commit transaction
 	err = tx.Commit()
 	if err != nil {
@@ -770,6 +796,7 @@ func (a *API) InsertBulkBlocks() error {
 		return Error(err, CommitErrorCode, "", "dbs.bulkblocks.InsertBulkBlocks")
 	}
 
+	InvalidateCache()
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
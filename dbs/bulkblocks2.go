@@ -490,6 +490,10 @@ func (a *API) InsertBulkBlocksConcurrently() error {
 		plfn := r.ParentLogicalFileName
 		pfid, err := QueryRow("FILES", "file_id", "logical_file_name", plfn)
 		if err != nil {
+			// missing parents are reported by parentage validation
+			if BulkBlocksParentageCheck != "" {
+				continue
+			}
 			msg := fmt.Sprintf("unable to find parent lfn %s", plfn)
 			return Error(err, DatabaseErrorCode, msg, "dbs.bulkblocks.InsertBulkBlocksConcurrently")
 		}
@@ -615,6 +619,15 @@ func (a *API) InsertBulkBlocksConcurrently() error {
 	}
 	defer tx.Rollback()
 
+	// check if injected lumis already exist in the dataset
+	if err = checkBulkBlocksLumis(tx, rec.Dataset.Dataset, rec.Files, isFileValid, hash); err != nil {
+		return err
+	}
+	// validate parentage of injected files
+	if err = checkBulkBlocksParentage(tx, rec, hash); err != nil {
+		return err
+	}
+
 	// get outputModConfigID using datasetID
 	// since we already inserted records from DatasetConfigList
 	for _, r := range rec.DatasetConfigList {
@@ -925,6 +938,17 @@ func (a *API) InsertBulkBlocksConcurrently() error {
 		}
 	}
 
+	// infer file parents from run/lumi overlap with files of parent datasets
+	// when it is requested and file parent list is not provided
+	if rec.InferFileParents && len(rec.FileParentList) == 0 {
+		_, err = insertInferredFileParents(tx, rec.Dataset.Dataset, rec.Block.BlockName)
+		if err != nil {
+			msg := fmt.Sprintf("%s unable to infer file parents, error %v", hash, err)
+			log.Println(msg)
+			return Error(err, InsertErrorCode, msg, "dbs.bulkblocks.InsertBulkBlocksConcurrently")
+		}
+	}
+
 	// commit transaction
 	err = tx.Commit()
 	if err != nil {
@@ -936,6 +960,7 @@ func (a *API) InsertBulkBlocksConcurrently() error {
 		log.Println(hash, "successfully finished bulkblocks.InsertBulkBlocksConcurrently")
 	}
 
+	InvalidateCache()
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
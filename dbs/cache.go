@@ -0,0 +1,52 @@
+package dbs
+
+// DBS response cache module
+//
+// Responses of hot reader APIs may be kept in ResponseCache. Writer APIs
+// invalidate cached responses via InvalidateCache function with list of
+// DBS tables they modified. The cacheDependencies map defines DBS tables
+// used by reader APIs, responses of APIs which are not listed there are
+// invalidated by any write.
+
+import "github.com/dmwm/dbs2go/utils"
+
+// ResponseCache represents cache of reader API responses, it is nil if
+// response cache is disabled
+var ResponseCache *utils.LRUCache
+
+// cacheDependencies defines DBS tables used by reader APIs
+var cacheDependencies = map[string][]string{
+	"datatiers":          {"DATA_TIERS"},
+	"primarydstypes":     {"PRIMARY_DS_TYPES"},
+	"datasetaccesstypes": {"DATASET_ACCESS_TYPES"},
+	"physicsgroups":      {"PHYSICS_GROUPS"},
+	"acquisitioneras":    {"ACQUISITION_ERAS"},
+	"acquisitioneras_ci": {"ACQUISITION_ERAS"},
+	"processingeras":     {"PROCESSING_ERAS"},
+	"datatypes":          {"PRIMARY_DS_TYPES", "PRIMARY_DATASETS", "DATASETS"},
+	"releaseversions": {
+		"RELEASE_VERSIONS", "OUTPUT_MODULE_CONFIGS",
+		"DATASET_OUTPUT_MOD_CONFIGS", "DATASETS", "FILES",
+	},
+	"blocksummaries": {"BLOCKS", "DATASETS", "FILES"},
+}
+
+// InvalidateCache removes cached responses of reader APIs which depend on
+// given DBS tables, all responses are removed if no tables are given
+func InvalidateCache(tables ...string) {
+	if ResponseCache == nil {
+		return
+	}
+	ResponseCache.Invalidate(func(api string) bool {
+		deps, ok := cacheDependencies[api]
+		if !ok || len(tables) == 0 {
+			return true
+		}
+		for _, t := range tables {
+			if utils.InList(t, deps) {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,242 @@
+package dbs
+
+// DBS dataset comparison module
+//
+// Compare API reports how two datasets differ, e.g. re-reco vs prompt reco.
+// The run/lumi coverage of both datasets is read from FILE_LUMIS table as
+// single stream of run/lumi pairs of both datasets ordered by run and lumi
+// numbers which is merged run by run, i.e. the memory footprint does not
+// depend on size of datasets. Every run with missing or extra lumis is
+// written as soon as it is processed and it is followed by summary record
+// with file, event and lumi totals. All queries run within single read only
+// transaction (one DB connection), on ORACLE it provides consistent snapshot
+// of both datasets.
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// CompareDataset represents totals of compared dataset
+type CompareDataset struct {
+	Dataset    string `json:"dataset"`
+	FileCount  int64  `json:"file_count"`
+	EventCount int64  `json:"event_count"`
+	RunCount   int64  `json:"run_count"`
+	LumiCount  int64  `json:"lumi_count"`
+}
+
+// CompareRun represents run/lumi coverage of a run which differs between
+// compared datasets, lumi ranges are given in lumi mask form
+type CompareRun struct {
+	Type    string    `json:"type"`
+	RunNum  int64     `json:"run_num"`
+	Common  [][]int64 `json:"common"`
+	OnlyInA [][]int64 `json:"only_in_a"`
+	OnlyInB [][]int64 `json:"only_in_b"`
+}
+
+// CompareSummary represents summary of datasets comparison
+type CompareSummary struct {
+	Type            string         `json:"type"`
+	A               CompareDataset `json:"a"`
+	B               CompareDataset `json:"b"`
+	CommonRunCount  int64          `json:"common_run_count"`
+	CommonLumiCount int64          `json:"common_lumi_count"`
+	OnlyInALumis    int64          `json:"only_in_a_lumi_count"`
+	OnlyInBLumis    int64          `json:"only_in_b_lumi_count"`
+	DiffRunCount    int64          `json:"diff_run_count"`
+	Identical       bool           `json:"identical"`
+}
+
+// helper function to add lumi to ordered list of lumi ranges
+func addLumi(ranges [][]int64, lumi int64) [][]int64 {
+	if n := len(ranges); n > 0 && lumi == ranges[n-1][1]+1 {
+		ranges[n-1][1] = lumi
+		return ranges
+	}
+	return append(ranges, []int64{lumi, lumi})
+}
+
+// Compare DBS API
+func (a *API) Compare() error {
+	var datasets []string
+	for _, key := range []string{"a", "b"} {
+		val, _ := getSingleValue(a.Params, key)
+		if val == "" || strings.ContainsAny(val, "*%") {
+			msg := fmt.Sprintf("compare API requires '%s' dataset without wildcards", key)
+			return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.compare.Compare")
+		}
+		datasets = append(datasets, val)
+	}
+	tmpl := make(Record)
+	tmpl["Owner"] = DBOWNER
+	if v, _ := getSingleValue(a.Params, "validFileOnly"); v == "1" {
+		tmpl["ValidFileOnly"] = true
+	}
+	if err := compareDatasets(a.readerDB(), a.Writer, a.Separator, datasets[0], datasets[1], tmpl); err != nil {
+		return Error(err, QueryErrorCode, "", "dbs.compare.Compare")
+	}
+	return nil
+}
+
+// helper function to compare two datasets and write their differences
+func compareDatasets(db *sql.DB, w io.Writer, sep, dsa, dsb string, tmpl Record) error {
+	summary := CompareSummary{Type: "summary"}
+	summary.A.Dataset = dsa
+	summary.B.Dataset = dsb
+	tx, err := db.Begin()
+	if err != nil {
+		return Error(err, TransactionErrorCode, "", "dbs.compare.compareDatasets")
+	}
+	defer tx.Rollback()
+	if DBOWNER != "sqlite" {
+		// read only transaction sees the same snapshot in all its queries
+		if _, err := tx.Exec("SET TRANSACTION READ ONLY"); err != nil {
+			return Error(err, TransactionErrorCode, "", "dbs.compare.compareDatasets")
+		}
+	}
+	stm, err := LoadTemplateSQL("compare_files", tmpl)
+	if err != nil {
+		return Error(err, LoadErrorCode, "", "dbs.compare.compareDatasets")
+	}
+	stm = CleanStatement(stm)
+	for _, ds := range []*CompareDataset{&summary.A, &summary.B} {
+		if _, err := GetID(tx, "DATASETS", "dataset_id", "dataset", ds.Dataset); err != nil {
+			msg := fmt.Sprintf("unable to find dataset %s", ds.Dataset)
+			return Error(err, GetIDErrorCode, msg, "dbs.compare.compareDatasets")
+		}
+		if utils.VERBOSE > 1 {
+			utils.PrintSQL(stm, []interface{}{ds.Dataset}, "execute")
+		}
+		var nfiles, nevents sql.NullInt64
+		if err := tx.QueryRow(stm, ds.Dataset).Scan(&nfiles, &nevents); err != nil {
+			return Error(err, QueryErrorCode, "", "dbs.compare.compareDatasets")
+		}
+		ds.FileCount = nfiles.Int64
+		ds.EventCount = nevents.Int64
+	}
+
+	// ordered run/lumi stream of both datasets, lumi present in both
+	// datasets comes as A row followed by B row
+	stm, err = LoadTemplateSQL("compare_lumis", tmpl)
+	if err != nil {
+		return Error(err, LoadErrorCode, "", "dbs.compare.compareDatasets")
+	}
+	stm = CleanStatement(stm)
+	if utils.VERBOSE > 1 {
+		utils.PrintSQL(stm, []interface{}{dsa, dsb}, "execute")
+	}
+	rows, err := tx.Query(stm, dsa, dsb)
+	if err != nil {
+		msg := fmt.Sprintf("unable to query statement: %v", stm)
+		return Error(err, QueryErrorCode, msg, "dbs.compare.compareDatasets")
+	}
+	defer rows.Close()
+
+	// merge stream run by run
+	rw := &recordWriter{w: w, sep: sep}
+	var cur *CompareRun
+	var inA, inB bool
+	flush := func() error {
+		if cur == nil {
+			return nil
+		}
+		if inA {
+			summary.A.RunCount++
+		}
+		if inB {
+			summary.B.RunCount++
+		}
+		if inA && inB {
+			summary.CommonRunCount++
+		}
+		if len(cur.OnlyInA) == 0 && len(cur.OnlyInB) == 0 {
+			return nil
+		}
+		summary.DiffRunCount++
+		return rw.Write(cur)
+	}
+	// add lumi of given side (A, B or common) to current run
+	add := func(side string, run, lumi int64) error {
+		if cur == nil || cur.RunNum != run {
+			if err := flush(); err != nil {
+				return err
+			}
+			cur = &CompareRun{Type: "run", RunNum: run, Common: [][]int64{}, OnlyInA: [][]int64{}, OnlyInB: [][]int64{}}
+			inA, inB = false, false
+		}
+		switch side {
+		case "A":
+			cur.OnlyInA = addLumi(cur.OnlyInA, lumi)
+			summary.OnlyInALumis++
+			summary.A.LumiCount++
+			inA = true
+		case "B":
+			cur.OnlyInB = addLumi(cur.OnlyInB, lumi)
+			summary.OnlyInBLumis++
+			summary.B.LumiCount++
+			inB = true
+		default:
+			cur.Common = addLumi(cur.Common, lumi)
+			summary.CommonLumiCount++
+			summary.A.LumiCount++
+			summary.B.LumiCount++
+			inA, inB = true, true
+		}
+		return nil
+	}
+	// lumi of dataset A is pending until we know if dataset B has it too
+	var pending bool
+	var prun, plumi int64
+	for rows.Next() {
+		var side string
+		var run, lumi int64
+		if err := rows.Scan(&side, &run, &lumi); err != nil {
+			return Error(err, RowsScanErrorCode, "", "dbs.compare.compareDatasets")
+		}
+		if side == "B" && pending && prun == run && plumi == lumi {
+			pending = false
+			if err := add("", run, lumi); err != nil {
+				return err
+			}
+			continue
+		}
+		if pending {
+			pending = false
+			if err := add("A", prun, plumi); err != nil {
+				return err
+			}
+		}
+		if side == "A" {
+			pending, prun, plumi = true, run, lumi
+			continue
+		}
+		if err := add("B", run, lumi); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return Error(err, RowsScanErrorCode, "", "dbs.compare.compareDatasets")
+	}
+	if pending {
+		if err := add("A", prun, plumi); err != nil {
+			return err
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	summary.Identical = summary.DiffRunCount == 0 &&
+		summary.A.FileCount == summary.B.FileCount &&
+		summary.A.EventCount == summary.B.EventCount
+	if err := rw.Write(summary); err != nil {
+		return err
+	}
+	rw.Close()
+	return nil
+}
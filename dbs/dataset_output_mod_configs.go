@@ -17,7 +17,7 @@ func (a *API) DatasetOutputModConfigs() error {
 	stm := getSQL("dataset_output_mod_configs")
 
 	// use generic query API to fetch the results from DB
-	err := executeAll(a.Writer, a.Separator, stm, args...)
+	err := executeAll(a.readerDB(), a.Writer, a.Separator, stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.dataset_output_configs.DatasetOutputModConfigs")
 	}
@@ -114,5 +114,6 @@ func (a *API) InsertDatasetOutputModConfigs() error {
 	if err != nil {
 		return Error(err, InsertErrorCode, "", "dbs.dataset_output_configs.InsertDatasetOutputModConfigs")
 	}
+	InvalidateCache("DATASET_OUTPUT_MOD_CONFIGS")
 	return nil
 }
@@ -21,7 +21,7 @@ func (a *API) DatasetAccessTypes() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	err := executeAll(a.Writer, a.Separator, stm, args...)
+	err := executeAll(a.readerDB(), a.Writer, a.Separator, stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.datasetaccesstypes.DatasetAccessTypes")
 	}
@@ -119,6 +119,7 @@ func (a *API) InsertDatasetAccessTypes() error {
 	if err != nil {
 		return Error(err, InsertErrorCode, "", "dbs.datasetaccesstypes.InsertDatasetAccessTypes")
 	}
+	InvalidateCache("DATASET_ACCESS_TYPES")
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
@@ -19,7 +19,7 @@ func (a *API) DatasetChildren() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	err := executeAll(a.Writer, a.Separator, stm, args...)
+	err := a.query(stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.datasetchildren.DatasetChildren")
 	}
@@ -21,7 +21,7 @@ func (a *API) DatasetParents() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	err := executeAll(a.Writer, a.Separator, stm, args...)
+	err := a.query(stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.datasetparents.DatasetParents")
 	}
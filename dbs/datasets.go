@@ -55,9 +55,10 @@ func (a *API) Datasets() error {
 		tmpl["Detail"] = true
 	}
 
-	// parse dataset argument
+	// parse dataset argument, list of plain dataset names is passed via token
+	// generator while filter expressions are handled by AddParam
 	datasets := getValues(a.Params, "dataset")
-	if len(datasets) > 1 {
+	if len(datasets) > 1 && !hasFilters(datasets) {
 		cond := fmt.Sprintf("D.DATASET in %s", TokenCondition())
 		// 100 is max for # of allowed datasets
 		token, binds := TokenGenerator(datasets, 100, "dataset_token")
@@ -66,7 +67,7 @@ func (a *API) Datasets() error {
 		for _, v := range binds {
 			args = append(args, v)
 		}
-	} else if len(datasets) == 1 {
+	} else if len(datasets) > 0 {
 		conds, args = AddParam("dataset", "D.DATASET", a.Params, conds, args)
 	}
 
@@ -270,9 +271,15 @@ func (a *API) Datasets() error {
 		vals = append(vals, new(sql.NullString))
 	}
 	stm = WhereClause(stm, conds)
+	cols, vals = a.columns(cols, vals)
+	db := a.readerDB()
+	if err := a.checkCost(a.statement(stm), args...); err != nil {
+		return Error(err, QueryErrorCode, "", "dbs.datasets.Datasets")
+	}
+	stm = a.statement(stm)
 
 	// use generic query API to fetch the results from DB
-	err = execute(a.Writer, a.Separator, stm, cols, vals, args...)
+	err = execute(db, a.Writer, a.Separator, stm, cols, vals, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.datasets.Datasets")
 	}
@@ -655,6 +662,7 @@ func (a *API) InsertDatasets() error {
 		log.Println("fail to commit transaction", err)
 		return Error(err, CommitErrorCode, "", "dbs.datasets.InsertDatasets")
 	}
+	InvalidateCache("DATASETS", "DATASET_OUTPUT_MOD_CONFIGS")
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
@@ -753,6 +761,7 @@ func (a *API) UpdateDatasets() error {
 		log.Println("unable to commit transaction", err)
 		return Error(err, CommitErrorCode, "", "dbs.datasets.UpdateDatasets")
 	}
+	InvalidateCache("DATASETS")
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
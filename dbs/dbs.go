@@ -29,20 +29,28 @@ import (
 // HTTP context, input HTTP GET paramers, separator for writer,
 // create by and api string values passed at run-time.
 type API struct {
-	Reader    io.Reader           // reader to read data payload
-	Writer    http.ResponseWriter // writer to write results back to client
-	Context   context.Context     // HTTP context
-	Params    Record              // HTTP input parameters
-	Separator string              // string separator for ndjson format
-	CreateBy  string              // create by value from run-time
-	Api       string              // api name
+	Reader     io.Reader           // reader to read data payload
+	Writer     http.ResponseWriter // writer to write results back to client
+	Context    context.Context     // HTTP context
+	Params     Record              // HTTP input parameters
+	Separator  string              // string separator for ndjson format
+	CreateBy   string              // create by value from run-time
+	Api        string              // api name
+	Fields     []string            // list of fields to select in API output
+	OrderBy    string              // field to order API output by
+	Descending bool                // use descending order of API output
+	Count      bool                // return only number of API records
+	Strong     bool                // read data from primary DB (strong consistency)
+	Guarded    bool                // API query is subject of query guardrails
+	Limit      int                 // max number of API records, e.g. of downgraded query
+	Format     string              // output format, e.g. json or dot
 }
 
 // String provides string representation of API struct
 func (a *API) String() string {
 	return fmt.Sprintf(
-		"API=%s params=%+v createBy=%s separator='%s'",
-		a.Api, a.Params, a.CreateBy, a.Separator)
+		"API=%s params=%+v fields=%v orderBy=%s desc=%v count=%v strong=%v limit=%d format=%s createBy=%s separator='%s'",
+		a.Api, a.Params, a.Fields, a.OrderBy, a.Descending, a.Count, a.Strong, a.Limit, a.Format, a.CreateBy, a.Separator)
 }
 
 // RecordValidator pointer to validator Validate method
@@ -205,6 +213,11 @@ func LoadSQL(owner string) Record {
 // So far we can ask for a data tier id of specific tier since this table
 // is very small and query execution will be really fast.
 func GetTestData() error {
+	return getTestData(DB)
+}
+
+// helper function to execute test query against given DB
+func getTestData(db *sql.DB) error {
 	tmpl := make(Record)
 	tmpl["Owner"] = DBOWNER
 	var args []interface{}
@@ -216,7 +229,7 @@ func GetTestData() error {
 	if utils.VERBOSE > 1 {
 		utils.PrintSQL(stm, args, "execute")
 	}
-	tx, err := DB.Begin()
+	tx, err := db.Begin()
 	if err != nil {
 		return Error(err, TransactionErrorCode, "", "dbs.GetTestData")
 	}
@@ -349,7 +362,7 @@ func CleanStatement(stm string) string {
 // then we literally stream data with our encoder (i.e. write records
 // to writer)
 //gocyclo:ignore
-func executeAll(w io.Writer, sep, stm string, args ...interface{}) error {
+func executeAll(db *sql.DB, w io.Writer, sep, stm string, args ...interface{}) error {
 	stm = CleanStatement(stm)
 	if DRYRUN {
 		utils.PrintSQL(stm, args, "")
@@ -363,13 +376,16 @@ func executeAll(w io.Writer, sep, stm string, args ...interface{}) error {
 		enc = json.NewEncoder(w)
 	}
 
+	// get cached prepared statement before transaction holds DB connection
+	stmt := preparedStmt(db, stm)
+
 	// execute transaction
-	tx, err := DB.Begin()
+	tx, err := db.Begin()
 	if err != nil {
 		return Error(err, TransactionErrorCode, "", "dbs.executeAll")
 	}
 	defer tx.Rollback()
-	rows, err := tx.Query(stm, args...)
+	rows, err := txQuery(tx, stmt, stm, args...)
 	if err != nil {
 		msg := fmt.Sprintf("unable to query statement: %v", stm)
 		log.Println(msg)
@@ -380,6 +396,27 @@ func executeAll(w io.Writer, sep, stm string, args ...interface{}) error {
 	// extract columns from Rows object and create values & valuesPtrs to retrieve results
 	columns, _ := rows.Columns()
 	var cols []string
+	// tabular writer, e.g. CSV, receives header row before the results
+	rw, tabular := w.(utils.RowWriter)
+	if tabular {
+		var header, kinds []string
+		for _, col := range columns {
+			header = append(header, strings.ToLower(col))
+		}
+		colTypes, _ := rows.ColumnTypes()
+		for i := range columns {
+			var kind string
+			if i < len(colTypes) {
+				kind = columnKind(colTypes[i])
+			}
+			kinds = append(kinds, kind)
+		}
+		if err := rw.WriteColumns(header, kinds); err != nil {
+			return Error(err, EncodeErrorCode, "", "dbs.executeAll")
+		}
+	}
+	// writer may keep track of last modification time of the records
+	mtw := utils.FindModTimeWriter(w)
 	count := len(columns)
 	values := make([]interface{}, count)
 	valuePtrs := make([]interface{}, count)
@@ -396,7 +433,7 @@ func executeAll(w io.Writer, sep, stm string, args ...interface{}) error {
 		if err != nil {
 			return Error(err, RowsScanErrorCode, "", "dbs.executeAll")
 		}
-		if rowCount != 0 && w != nil {
+		if rowCount != 0 && w != nil && !tabular {
 			// add separator line to our output
 			w.Write([]byte(sep))
 		}
@@ -432,7 +469,15 @@ func executeAll(w io.Writer, sep, stm string, args ...interface{}) error {
 				rec[cols[i]] = val
 			}
 		}
-		if w != nil {
+		if mtw != nil {
+			mtw.SetModTime(recordModTime(rec))
+		}
+		if tabular {
+			err = rw.WriteRow(recordValues(rec, cols))
+			if err != nil {
+				return Error(err, EncodeErrorCode, "", "dbs.executeAll")
+			}
+		} else if w != nil {
 			if rowCount == 0 {
 				if sep != "" {
 					writtenResults = true
@@ -451,15 +496,113 @@ func executeAll(w io.Writer, sep, stm string, args ...interface{}) error {
 		return Error(err, RowsScanErrorCode, "", "dbs.executeAll")
 	}
 	// make sure we write proper response if no result written
-	if sep != "" && !writtenResults {
+	if sep != "" && !writtenResults && !tabular {
 		w.Write([]byte("[]"))
 	}
 	return nil
 }
 
+// recordWriter writes JSON records as list (non empty separator) or as
+// ndjson stream (empty separator) using the same layout as executeAll
+type recordWriter struct {
+	w     io.Writer
+	sep   string
+	count int
+}
+
+// Write writes given record
+func (rw *recordWriter) Write(rec interface{}) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return Error(err, MarshalErrorCode, "", "dbs.recordWriter.Write")
+	}
+	if rw.count == 0 && rw.sep != "" {
+		rw.w.Write([]byte("[\n"))
+	} else if rw.count > 0 {
+		rw.w.Write([]byte(rw.sep))
+	}
+	rw.w.Write(data)
+	rw.w.Write([]byte("\n"))
+	rw.count++
+	return nil
+}
+
+// Close finishes the list of records
+func (rw *recordWriter) Close() {
+	if rw.sep == "" {
+		return
+	}
+	if rw.count == 0 {
+		rw.w.Write([]byte("[]"))
+	} else {
+		rw.w.Write([]byte("]\n"))
+	}
+}
+
+// helper function to get values of the record in order of given columns
+func recordValues(rec Record, cols []string) []interface{} {
+	vals := make([]interface{}, len(cols))
+	for i, col := range cols {
+		vals[i] = rec[col]
+	}
+	return vals
+}
+
+// helper function to get last modification date of the record, it returns
+// zero if record does not provide it
+func recordModTime(rec Record) int64 {
+	switch v := rec["last_modification_date"].(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case string:
+		ts, _ := strconv.ParseInt(v, 10, 64)
+		return ts
+	}
+	return 0
+}
+
+// helper function to get kind of the column from its database type, it
+// returns empty kind if it can't be determined
+func columnKind(ct *sql.ColumnType) string {
+	name := strings.ToUpper(ct.DatabaseTypeName())
+	switch {
+	case strings.Contains(name, "INT"):
+		return utils.IntColumn
+	case strings.Contains(name, "FLOAT"), strings.Contains(name, "DOUBLE"),
+		strings.Contains(name, "REAL"):
+		return utils.FloatColumn
+	case strings.Contains(name, "NUMBER"), strings.Contains(name, "DECIMAL"),
+		strings.Contains(name, "NUMERIC"):
+		if _, scale, ok := ct.DecimalSize(); ok && scale == 0 {
+			return utils.IntColumn
+		}
+		return ""
+	case strings.Contains(name, "CHAR"), strings.Contains(name, "TEXT"),
+		strings.Contains(name, "CLOB"):
+		return utils.StringColumn
+	}
+	return ""
+}
+
+// helper function to get kind of the column from type of its scan value
+func valueKind(v interface{}) string {
+	switch v.(type) {
+	case *sql.NullInt64, *int64:
+		return utils.IntColumn
+	case *sql.NullFloat64, *float64:
+		return utils.FloatColumn
+	case *sql.NullString, *string:
+		return utils.StringColumn
+	}
+	return ""
+}
+
 // similar to executeAll function but it takes explicit set of columns and values
 //gocyclo:ignore
 func execute(
+	db *sql.DB,
 	w io.Writer,
 	sep, stm string,
 	cols []string,
@@ -478,13 +621,16 @@ func execute(
 		enc = json.NewEncoder(w)
 	}
 
+	// get cached prepared statement before transaction holds DB connection
+	stmt := preparedStmt(db, stm)
+
 	// execute transaction
-	tx, err := DB.Begin()
+	tx, err := db.Begin()
 	if err != nil {
 		return Error(err, TransactionErrorCode, "", "dbs.execute")
 	}
 	defer tx.Rollback()
-	rows, err := tx.Query(stm, args...)
+	rows, err := txQuery(tx, stmt, stm, args...)
 	if err != nil {
 		msg := fmt.Sprintf("DB.Query, query='%s' args='%v'", stm, args)
 		log.Println(msg)
@@ -492,6 +638,21 @@ func execute(
 	}
 	defer rows.Close()
 
+	// tabular writer, e.g. CSV, receives header row before the results
+	rw, tabular := w.(utils.RowWriter)
+	if tabular {
+		var kinds []string
+		for _, v := range vals {
+			kinds = append(kinds, valueKind(v))
+		}
+		if err := rw.WriteColumns(cols, kinds); err != nil {
+			return Error(err, EncodeErrorCode, "", "dbs.execute")
+		}
+	}
+
+	// writer may keep track of last modification time of the records
+	mtw := utils.FindModTimeWriter(w)
+
 	// loop over rows
 	rowCount := 0
 	writtenResults := false
@@ -502,7 +663,7 @@ func execute(
 			log.Println(msg)
 			return Error(err, RowsScanErrorCode, "", "dbs.execute")
 		}
-		if rowCount != 0 && w != nil {
+		if rowCount != 0 && w != nil && !tabular {
 			// add separator line to our output
 			w.Write([]byte(sep))
 		}
@@ -534,7 +695,15 @@ func execute(
 				rec[cols[i]] = val
 			}
 		}
-		if w != nil {
+		if mtw != nil {
+			mtw.SetModTime(recordModTime(rec))
+		}
+		if tabular {
+			err = rw.WriteRow(recordValues(rec, cols))
+			if err != nil {
+				return Error(err, EncodeErrorCode, "", "dbs.execute")
+			}
+		} else if w != nil {
 			if rowCount == 0 {
 				if sep != "" {
 					writtenResults = true
@@ -553,7 +722,7 @@ func execute(
 		return Error(err, RowsScanErrorCode, "", "dbs.execute")
 	}
 	// make sure we write proper response if no result written
-	if sep != "" && !writtenResults {
+	if sep != "" && !writtenResults && !tabular {
 		w.Write([]byte("[]"))
 	}
 	return nil
@@ -878,7 +1047,11 @@ func runsClause(table string, runs []string) (string, string, []string) {
 	return token, where, args
 }
 
-// AddParam adds single parameter to SQL statement
+// AddParam adds single parameter to SQL statement, the parameter value may
+// be a filter expression (see filters.go) or list of values. Every value of
+// the list is parsed as filter expression: plain values are matched via IN
+// condition (or their patterns), while negations, comparisons and
+// case-insensitive values are added as separate conditions.
 func AddParam(
 	name, sqlName string,
 	params Record,
@@ -887,22 +1060,59 @@ func AddParam(
 
 	vals := getValues(params, name)
 	if len(vals) == 1 {
-		op, val := OperatorValue(vals[0])
-		if strings.Contains(val, "e+") || strings.Contains(val, "E+") {
-			val = utils.ConvertFloat(val)
-		}
-		if strings.Contains(val, "[") {
-			val = strings.Replace(val, "[", "", -1)
-			val = strings.Replace(val, "]", "", -1)
-			val = strings.Trim(val, " ")
-		}
-		cond := fmt.Sprintf(" %s %s %s", sqlName, op, placeholder(name))
+		f := ParseFilter(vals[0])
+		val := paramValue(f.Value)
+		cond := f.Condition(sqlName, placeholder(name))
 		conds = append(conds, cond)
 		args = append(args, val)
+	} else if len(vals) > 1 {
+		var binds, matches, filters []string
+		var inArgs, matchArgs, filterArgs []interface{}
+		for i, v := range vals {
+			f := ParseFilter(v)
+			val := paramValue(f.Value)
+			bind := placeholder(fmt.Sprintf("%s_%d", name, i))
+			if f.NoCase || (f.Operator != "=" && f.Operator != "like") {
+				filters = append(filters, f.Condition(sqlName, bind))
+				filterArgs = append(filterArgs, val)
+			} else if f.Operator == "like" {
+				matches = append(matches, f.Condition(sqlName, bind))
+				matchArgs = append(matchArgs, val)
+			} else {
+				binds = append(binds, bind)
+				inArgs = append(inArgs, val)
+			}
+		}
+		if len(binds) > 0 {
+			cond := fmt.Sprintf(" %s IN (%s)", sqlName, strings.Join(binds, ", "))
+			matches = append([]string{cond}, matches...)
+			matchArgs = append(inArgs, matchArgs...)
+		}
+		if len(matches) == 1 {
+			conds = append(conds, matches[0])
+		} else if len(matches) > 1 {
+			conds = append(conds, fmt.Sprintf(" (%s )", strings.Join(matches, " OR")))
+		}
+		args = append(args, matchArgs...)
+		conds = append(conds, filters...)
+		args = append(args, filterArgs...)
 	}
 	return conds, args
 }
 
+// helper function to normalize parameter value
+func paramValue(val string) string {
+	if strings.Contains(val, "e+") || strings.Contains(val, "E+") {
+		val = utils.ConvertFloat(val)
+	}
+	if strings.Contains(val, "[") {
+		val = strings.Replace(val, "[", "", -1)
+		val = strings.Replace(val, "]", "", -1)
+		val = strings.Trim(val, " ")
+	}
+	return val
+}
+
 // IncrementSequences API provide a way to get N unique IDs for given sequence name
 func IncrementSequences(tx *sql.Tx, seq string, n int) ([]int64, error) {
 	var out []int64
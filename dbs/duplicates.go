@@ -0,0 +1,69 @@
+package dbs
+
+// DBS duplicate files module
+//
+// Duplicates API reports files of a dataset whose content is registered more
+// than once under different names, i.e. files with the same checksum and
+// size. The checksum is either adler32 (default), check_sum or md5 and the
+// copies are looked up either within the dataset (default) or across all
+// datasets of DBS (scope=all). In the latter case distinct checksum and size
+// pairs of dataset files are joined with FILES table via (checksum,
+// FILE_SIZE) index. Files with unknown checksum, i.e. NULL or NOTSET value,
+// are ignored.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// checksumColumns maps checksum parameter values to FILES table columns
+var checksumColumns = map[string]string{
+	"adler32":   "ADLER32",
+	"check_sum": "CHECK_SUM",
+	"md5":       "MD5",
+}
+
+// Duplicates DBS API
+func (a *API) Duplicates() error {
+	dataset, _ := getSingleValue(a.Params, "dataset")
+	if dataset == "" || strings.ContainsAny(dataset, "*%") {
+		msg := "duplicates API requires dataset without wildcards"
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.duplicates.Duplicates")
+	}
+	checksum, _ := getSingleValue(a.Params, "checksum")
+	if checksum == "" {
+		checksum = "adler32"
+	}
+	column, ok := checksumColumns[checksum]
+	if !ok {
+		msg := fmt.Sprintf("invalid checksum %s, should be adler32, check_sum or md5", checksum)
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.duplicates.Duplicates")
+	}
+	scope, _ := getSingleValue(a.Params, "scope")
+	if scope != "" && scope != "dataset" && scope != "all" {
+		msg := fmt.Sprintf("invalid scope %s, should be dataset or all", scope)
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.duplicates.Duplicates")
+	}
+	tmpl := make(Record)
+	tmpl["Owner"] = DBOWNER
+	tmpl["Checksum"] = column
+	tmpl["AllDatasets"] = scope == "all"
+	if v, _ := getSingleValue(a.Params, "validFileOnly"); v == "1" {
+		tmpl["ValidFileOnly"] = true
+	}
+	stm, err := LoadTemplateSQL("duplicates", tmpl)
+	if err != nil {
+		return Error(err, LoadErrorCode, "", "dbs.duplicates.Duplicates")
+	}
+	// dataset is used by both duplicates sub-query and main query unless
+	// copies are looked up across all datasets
+	args := []interface{}{dataset}
+	if scope != "all" {
+		args = append(args, dataset)
+	}
+	err = a.query(stm, args...)
+	if err != nil {
+		return Error(err, QueryErrorCode, "", "dbs.duplicates.Duplicates")
+	}
+	return nil
+}
@@ -33,6 +33,9 @@ var NotImplementedApiErr = errors.New("not implemented api error")
 // InvalidRequestErr represents generic invalid request error
 var InvalidRequestErr = errors.New("invalid request error")
 
+// QueryGuardrailErr represents generic error of too expensive query
+var QueryGuardrailErr = errors.New("query guardrail error")
+
 // DBS Error codes provides static representation of DBS errors, they cover 1xx range
 const (
 	GenericErrorCode        = iota + 100 // generic DBS error
@@ -63,6 +66,7 @@ const (
 	MigrationErrorCode                   // 125 Migration error
 	RemoveErrorCode                      // 126 remove error
 	InvalidRequestErrorCode              // 127 invalid request error
+	QueryGuardrailErrorCode              // 128 query guardrail error
 	LastAvailableErrorCode               // last available DBS error code
 )
 
@@ -72,6 +76,8 @@ type DBSError struct {
 	Message  string `json:"message"`  // additional message describing the issue
 	Function string `json:"function"` // DBS function
 	Code     int    `json:"code"`     // DBS error code
+
+	Data interface{} `json:"data,omitempty"` // structured details of the error, e.g. validation report
 }
 
 // Error function implements details of DBS error message
@@ -143,6 +149,8 @@ func (e *DBSError) Explain() string {
 		return "Unable to remove record from DB"
 	case InvalidRequestErrorCode:
 		return "Invalid HTTP request"
+	case QueryGuardrailErrorCode:
+		return "DBS query is too expensive, e.g. API requires more selective parameters"
 	default:
 		return "Not defined"
 	}
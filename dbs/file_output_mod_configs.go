@@ -17,7 +17,7 @@ func (a *API) FileOutputModConfigs() error {
 	stm := getSQL("file_output_mod_configs")
 
 	// use generic query API to fetch the results from DB
-	err := executeAll(a.Writer, a.Separator, stm, args...)
+	err := executeAll(a.readerDB(), a.Writer, a.Separator, stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.file_output_mod_configs.FileOutputModConfigs")
 	}
@@ -0,0 +1,216 @@
+package dbs
+
+// DBS file ancestry module
+//
+// FileAncestry API walks FILE_PARENTS relation of given list of files up
+// (ancestors), down (descendants) or in both directions up to given depth.
+// Every generation is fetched by batched queries of FileAncestryBatchSize
+// files which use TokenGenerator for the list of files, i.e. number of
+// queries does not depend on number of files. The related files may be
+// restricted to given data tier, e.g. RAW files of NANOAOD files, while the
+// relation is walked through files of all data tiers.
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// FileAncestryBatchSize defines number of files looked up by single query
+var FileAncestryBatchSize = 1000
+
+// FileAncestor represents file related to given file by its ancestry
+type FileAncestor struct {
+	LogicalFileName        string `json:"logical_file_name"`
+	RelatedLogicalFileName string `json:"related_logical_file_name"`
+	DataTierName           string `json:"data_tier_name"`
+	Generation             int    `json:"generation"`
+}
+
+// fileRelation represents file relation fetched from DB
+type fileRelation struct {
+	Lfn      string
+	Related  string
+	DataTier string
+}
+
+// FileAncestry DBS API
+func (a *API) FileAncestry() error {
+	lfns := getValues(a.Params, "logical_file_name")
+	if len(lfns) == 0 {
+		msg := "fileancestry API requires logical_file_name parameter"
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.fileancestry.FileAncestry")
+	}
+	for _, lfn := range lfns {
+		if strings.ContainsAny(lfn, "*%") {
+			msg := fmt.Sprintf("fileancestry API does not support wildcards, lfn '%s'", lfn)
+			return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.fileancestry.FileAncestry")
+		}
+	}
+	direction := "up"
+	if val, err := getSingleValue(a.Params, "direction"); err == nil && val != "" {
+		direction = strings.ToLower(val)
+	}
+	if direction != "up" && direction != "down" && direction != "both" {
+		msg := fmt.Sprintf("invalid direction value '%s', should be up, down or both", direction)
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.fileancestry.FileAncestry")
+	}
+	depth := LineageMaxDepth
+	if val, err := getSingleValue(a.Params, "depth"); err == nil && val != "" {
+		depth, err = strconv.Atoi(val)
+		if err != nil || depth < 1 || depth > LineageMaxDepth {
+			msg := fmt.Sprintf("invalid depth value '%s', should be between 1 and %d", val, LineageMaxDepth)
+			return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.fileancestry.FileAncestry")
+		}
+	}
+	tier, _ := getSingleValue(a.Params, "data_tier_name")
+
+	records, err := fileAncestry(a.readerDB(), lfns, direction, depth, tier)
+	if err != nil {
+		return Error(err, QueryErrorCode, "", "dbs.fileancestry.FileAncestry")
+	}
+	if a.Separator == "" {
+		// ndjson output
+		enc := json.NewEncoder(a.Writer)
+		for _, rec := range records {
+			if err := enc.Encode(rec); err != nil {
+				return Error(err, EncodeErrorCode, "", "dbs.fileancestry.FileAncestry")
+			}
+		}
+		return nil
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return Error(err, MarshalErrorCode, "", "dbs.fileancestry.FileAncestry")
+	}
+	a.Writer.Write(data)
+	return nil
+}
+
+// helper function to find related files of given files
+func fileAncestry(db *sql.DB, lfns []string, direction string, depth int, tier string) ([]FileAncestor, error) {
+	records := []FileAncestor{}
+	tx, err := db.Begin()
+	if err != nil {
+		return records, Error(err, TransactionErrorCode, "", "dbs.fileancestry.fileAncestry")
+	}
+	defer tx.Rollback()
+
+	for _, up := range []bool{true, false} {
+		if (up && direction == "down") || (!up && direction == "up") {
+			continue
+		}
+		tmpl := make(Record)
+		tmpl["Owner"] = DBOWNER
+		tmpl["From"], tmpl["To"] = "THIS_FILE_ID", "PARENT_FILE_ID"
+		if !up {
+			tmpl["From"], tmpl["To"] = "PARENT_FILE_ID", "THIS_FILE_ID"
+		}
+		stm, err := LoadTemplateSQL("fileancestry", tmpl)
+		if err != nil {
+			return records, Error(err, LoadErrorCode, "", "dbs.fileancestry.fileAncestry")
+		}
+		step := 1
+		if up {
+			step = -1
+		}
+		// files reached by every given file and files of current generation
+		// along with given files they were reached from
+		reached := make(map[string]map[string]bool)
+		frontier := make(map[string][]string)
+		for _, lfn := range lfns {
+			if _, ok := reached[lfn]; !ok {
+				reached[lfn] = map[string]bool{lfn: true}
+				frontier[lfn] = []string{lfn}
+			}
+		}
+		for gen := step; len(frontier) > 0 && gen*step <= depth; gen += step {
+			var files []string
+			for lfn := range frontier {
+				files = append(files, lfn)
+			}
+			sort.Strings(files)
+			next := make(map[string][]string)
+			for start := 0; start < len(files); start += FileAncestryBatchSize {
+				end := start + FileAncestryBatchSize
+				if end > len(files) {
+					end = len(files)
+				}
+				rels, err := fileRelations(tx, stm, files[start:end])
+				if err != nil {
+					return records, err
+				}
+				for _, r := range rels {
+					for _, lfn := range frontier[r.Lfn] {
+						if reached[lfn][r.Related] {
+							continue
+						}
+						reached[lfn][r.Related] = true
+						next[r.Related] = append(next[r.Related], lfn)
+						if tier == "" || r.DataTier == tier {
+							rec := FileAncestor{
+								LogicalFileName:        lfn,
+								RelatedLogicalFileName: r.Related,
+								DataTierName:           r.DataTier,
+								Generation:             gen,
+							}
+							records = append(records, rec)
+						}
+					}
+				}
+			}
+			frontier = next
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].LogicalFileName != records[j].LogicalFileName {
+			return records[i].LogicalFileName < records[j].LogicalFileName
+		}
+		if records[i].Generation != records[j].Generation {
+			return records[i].Generation < records[j].Generation
+		}
+		return records[i].RelatedLogicalFileName < records[j].RelatedLogicalFileName
+	})
+	return records, nil
+}
+
+// helper function to fetch relations of given batch of files
+func fileRelations(tx *sql.Tx, stm string, lfns []string) ([]fileRelation, error) {
+	var args []interface{}
+	token, binds := TokenGenerator(lfns, 30, "lfn_token")
+	stm = fmt.Sprintf("%s %s", token, stm)
+	cond := fmt.Sprintf(" F.LOGICAL_FILE_NAME in %s", TokenCondition())
+	stm = WhereClause(stm, []string{cond})
+	for _, v := range binds {
+		args = append(args, v)
+	}
+	stm = CleanStatement(stm)
+	if utils.VERBOSE > 1 {
+		utils.PrintSQL(stm, args, "execute")
+	}
+	rows, err := tx.Query(stm, args...)
+	if err != nil {
+		msg := fmt.Sprintf("unable to query statement: %v", stm)
+		return nil, Error(err, QueryErrorCode, msg, "dbs.fileancestry.fileRelations")
+	}
+	defer rows.Close()
+	var relations []fileRelation
+	for rows.Next() {
+		var r fileRelation
+		var tier sql.NullString
+		if err := rows.Scan(&r.Lfn, &r.Related, &tier); err != nil {
+			return nil, Error(err, RowsScanErrorCode, "", "dbs.fileancestry.fileRelations")
+		}
+		r.DataTier = tier.String
+		relations = append(relations, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, Error(err, RowsScanErrorCode, "", "dbs.fileancestry.fileRelations")
+	}
+	return relations, nil
+}
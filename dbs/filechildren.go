@@ -33,6 +33,9 @@ func (a *API) FileChildren() error {
 	if len(lfns) == 1 {
 		conds, args = AddParam("logical_file_name", "F.LOGICAL_FILE_NAME", a.Params, conds, args)
 	} else {
+		if err := checkPlainValues("logical_file_name", lfns); err != nil {
+			return Error(err, ParametersErrorCode, "", "dbs.filechildren.FileChildren")
+		}
 		token, binds := TokenGenerator(lfns, 30, "lfn_token")
 		stm = fmt.Sprintf("%s %s", token, stm)
 		cond := fmt.Sprintf(" F.LOGICAL_FILE_NAME in %s", TokenCondition())
@@ -50,7 +53,7 @@ func (a *API) FileChildren() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	err = executeAll(a.Writer, a.Separator, stm, args...)
+	err = a.query(stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.filechildren.FileChildren")
 	}
@@ -17,7 +17,7 @@ func (a *API) FileDataTypes() error {
 	stm := getSQL("file_data_types")
 
 	// use generic query API to fetch the results from DB
-	err := executeAll(a.Writer, a.Separator, stm, args...)
+	err := executeAll(a.readerDB(), a.Writer, a.Separator, stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.filedatatypes.FileDataTypes")
 	}
@@ -102,5 +102,6 @@ func (a *API) InsertFileDataTypes() error {
 	if err != nil {
 		return Error(err, InsertErrorCode, "", "dbs.filedatatypes.InsertFileDataTypes")
 	}
+	InvalidateCache("FILE_DATA_TYPES")
 	return nil
 }
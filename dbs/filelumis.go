@@ -32,6 +32,9 @@ func (a *API) FileLumis() error {
 
 	lfns := getValues(a.Params, "logical_file_name")
 	if len(lfns) > 1 {
+		if err := checkPlainValues("logical_file_name", lfns); err != nil {
+			return Error(err, ParametersErrorCode, "", "dbs.filelumis.FileLumis")
+		}
 		token, binds := TokenGenerator(lfns, 100, "lfns_token") // 100 is max for # of allowed entries
 		tmpl["TokenGenerator"] = token
 		tmpl["Lfn"] = true
@@ -112,7 +115,7 @@ func (a *API) FileLumis() error {
 	}
 
 	// use generic query API to fetch the results from DB
-	err = executeAll(a.Writer, a.Separator, stm, args...)
+	err = a.query(stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.filelumis.FileLumis")
 	}
@@ -38,6 +38,9 @@ func (a *API) FileParents() error {
 	if len(lfns) == 1 {
 		conds, args = AddParam("logical_file_name", "F.LOGICAL_FILE_NAME", a.Params, conds, args)
 	} else if len(lfns) > 1 {
+		if err := checkPlainValues("logical_file_name", lfns); err != nil {
+			return Error(err, ParametersErrorCode, "", "dbs.fileparents.FileParents")
+		}
 		token, binds := TokenGenerator(lfns, 30, "lfn_token")
 		stm = fmt.Sprintf("%s %s", token, stm)
 		cond := fmt.Sprintf(" F.LOGICAL_FILE_NAME in %s", TokenCondition())
@@ -50,7 +53,7 @@ func (a *API) FileParents() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	err = executeAll(a.Writer, a.Separator, stm, args...)
+	err = a.query(stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.fileparents.FielParents")
 	}
@@ -267,6 +270,7 @@ func (a *API) InsertFileParents() error {
 		log.Println("fail to commit transaction", err)
 		return Error(err, CommitErrorCode, "", "dbs.fileparents.InsertFileParents")
 	}
+	InvalidateCache("FILE_PARENTS")
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
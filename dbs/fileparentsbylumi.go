@@ -1,8 +1,13 @@
 package dbs
 
 import (
+	"database/sql"
 	"fmt"
+	"log"
+	"sort"
 	"strings"
+
+	"github.com/dmwm/dbs2go/utils"
 )
 
 // FileParentsByLumi DBS API
@@ -52,14 +57,178 @@ func (a *API) FileParentsByLumi() error {
 	}
 
 	// use generic query API to fetch the results from DB
-	err = executeAll(a.Writer, a.Separator, stm, args...)
+	err = executeAll(a.readerDB(), a.Writer, a.Separator, stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.fileparentsbylumi.FileParentsByLumi")
 	}
 	return nil
 }
 
-// InsertFileParentsByLumi DBS API
+// FileParentLink represents file parentage inferred from lumi overlap
+type FileParentLink struct {
+	LogicalFileName       string `json:"logical_file_name"`
+	ParentLogicalFileName string `json:"parent_logical_file_name"`
+	fileID                int64
+	parentFileID          int64
+}
+
+// FileParentsByLumiReport represents report of inferred file parentage,
+// unmatched lumis are lumis of files which are not found in parent datasets
+// and skipped files are files which already have parents
+type FileParentsByLumiReport struct {
+	BlockName      string              `json:"block_name"`
+	LinkCount      int                 `json:"link_count"`
+	Links          []FileParentLink    `json:"links"`
+	UnmatchedLumis map[string]LumiMask `json:"unmatched_lumis"`
+	SkippedFiles   []string            `json:"skipped_files"`
+}
+
+// InsertFileParentsByLumi DBS API infers parentage of files of given block
+// from run/lumi overlap with valid files of parent datasets of the block
+// dataset and inserts it in single transaction. Files which already have
+// parents, e.g. provided by file_parent_list of bulkblocks API, are skipped.
+// It is served by /inferfileparents end-point of DBS writer.
 func (a *API) InsertFileParentsByLumi() error {
+	blockNames := getValues(a.Params, "block_name")
+	if len(blockNames) != 1 || strings.ContainsAny(blockNames[0], "*%") {
+		msg := "insert file parents by lumi requires single block_name without wildcards"
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.fileparentsbylumi.InsertFileParentsByLumi")
+	}
+	blk := blockNames[0]
+	dataset := strings.Split(blk, "#")[0]
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Error(err, TransactionErrorCode, "", "dbs.fileparentsbylumi.InsertFileParentsByLumi")
+	}
+	defer tx.Rollback()
+	if _, err := GetID(tx, "BLOCKS", "block_id", "block_name", blk); err != nil {
+		msg := fmt.Sprintf("unable to find block %s", blk)
+		return Error(err, GetIDErrorCode, msg, "dbs.fileparentsbylumi.InsertFileParentsByLumi")
+	}
+	report, err := insertInferredFileParents(tx, dataset, blk)
+	if err != nil {
+		return Error(err, InsertErrorCode, "", "dbs.fileparentsbylumi.InsertFileParentsByLumi")
+	}
+	if err := tx.Commit(); err != nil {
+		return Error(err, CommitErrorCode, "", "dbs.fileparentsbylumi.InsertFileParentsByLumi")
+	}
+	InvalidateCache("FILE_PARENTS")
+	rw := &recordWriter{w: a.Writer, sep: a.Separator}
+	if err := rw.Write(report); err != nil {
+		return err
+	}
+	rw.Close()
 	return nil
 }
+
+// helper function to infer parents of files of given block and insert them
+// within given transaction, it is used by InsertFileParentsByLumi and by
+// bulkblocks APIs when file parent list is not provided
+func insertInferredFileParents(tx *sql.Tx, dataset, blk string) (FileParentsByLumiReport, error) {
+	report, err := inferFileParents(tx, dataset, blk)
+	if err != nil {
+		return report, err
+	}
+	for _, link := range report.Links {
+		r := FileParents{THIS_FILE_ID: link.fileID, PARENT_FILE_ID: link.parentFileID}
+		if err := r.Insert(tx); err != nil {
+			msg := fmt.Sprintf("unable to insert parent %s of %s", link.ParentLogicalFileName, link.LogicalFileName)
+			return report, Error(err, InsertErrorCode, msg, "dbs.fileparentsbylumi.insertInferredFileParents")
+		}
+	}
+	if utils.VERBOSE > 0 {
+		log.Printf("block %s: inferred %d file parents, %d files with unmatched lumis, %d skipped files",
+			blk, report.LinkCount, len(report.UnmatchedLumis), len(report.SkippedFiles))
+	}
+	return report, nil
+}
+
+// helper function to infer parents of files of given block from lumis of
+// files of parent datasets
+func inferFileParents(tx *sql.Tx, dataset, blk string) (FileParentsByLumiReport, error) {
+	report := FileParentsByLumiReport{
+		BlockName:      blk,
+		Links:          []FileParentLink{},
+		UnmatchedLumis: make(map[string]LumiMask),
+		SkippedFiles:   []string{},
+	}
+	tmpl := make(Record)
+	tmpl["Owner"] = DBOWNER
+	stm, err := LoadTemplateSQL("infer_fileparents", tmpl)
+	if err != nil {
+		return report, Error(err, LoadErrorCode, "", "dbs.fileparentsbylumi.inferFileParents")
+	}
+	stm = CleanStatement(stm)
+	if utils.VERBOSE > 1 {
+		utils.PrintSQL(stm, []interface{}{dataset, blk}, "execute")
+	}
+	rows, err := tx.Query(stm, dataset, blk)
+	if err != nil {
+		msg := fmt.Sprintf("unable to query statement: %v", stm)
+		return report, Error(err, QueryErrorCode, msg, "dbs.fileparentsbylumi.inferFileParents")
+	}
+	defer rows.Close()
+
+	// rows are ordered by file names, i.e. parents of every file are known
+	// once all its lumis are read
+	var lfn string
+	var fileID, hasParents int64
+	var parents map[string]int64
+	var unmatched lumiMaskBuilder
+	var nunmatched int
+	flush := func() {
+		if lfn == "" {
+			return
+		}
+		if hasParents == 1 {
+			report.SkippedFiles = append(report.SkippedFiles, lfn)
+			return
+		}
+		var plfns []string
+		for plfn := range parents {
+			plfns = append(plfns, plfn)
+		}
+		sort.Strings(plfns)
+		for _, plfn := range plfns {
+			link := FileParentLink{
+				LogicalFileName:       lfn,
+				ParentLogicalFileName: plfn,
+				fileID:                fileID,
+				parentFileID:          parents[plfn],
+			}
+			report.Links = append(report.Links, link)
+		}
+		if nunmatched > 0 {
+			report.UnmatchedLumis[lfn], _, _ = unmatched.Mask()
+		}
+	}
+	for rows.Next() {
+		var clfn string
+		var cid, run, lumi, flag int64
+		var pid sql.NullInt64
+		var plfn sql.NullString
+		if err := rows.Scan(&cid, &clfn, &run, &lumi, &pid, &plfn, &flag); err != nil {
+			return report, Error(err, RowsScanErrorCode, "", "dbs.fileparentsbylumi.inferFileParents")
+		}
+		if clfn != lfn {
+			flush()
+			lfn, fileID, hasParents = clfn, cid, flag
+			parents = make(map[string]int64)
+			unmatched = lumiMaskBuilder{}
+			nunmatched = 0
+		}
+		if pid.Valid {
+			parents[plfn.String] = pid.Int64
+		} else {
+			unmatched.Add(run, lumi, 0)
+			nunmatched++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return report, Error(err, RowsScanErrorCode, "", "dbs.fileparentsbylumi.inferFileParents")
+	}
+	flush()
+	report.LinkCount = len(report.Links)
+	return report, nil
+}
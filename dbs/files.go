@@ -104,6 +104,11 @@ func (a *API) Files() error {
 		tmpl["Addition"] = true
 	}
 	conds, args = AddParam("origin_site_name", "B.ORIGIN_SITE_NAME", a.Params, conds, args)
+	conds, args = AddParam("file_size", "F.FILE_SIZE", a.Params, conds, args)
+	conds, args = AddParam("event_count", "F.EVENT_COUNT", a.Params, conds, args)
+	conds, args = AddParam("check_sum", "F.CHECK_SUM", a.Params, conds, args)
+	conds, args = AddParam("adler32", "F.ADLER32", a.Params, conds, args)
+	conds, args = AddParam("md5", "F.MD5", a.Params, conds, args)
 
 	// load our SQL statement
 	stm, err := LoadTemplateSQL("files", tmpl)
@@ -114,6 +119,9 @@ func (a *API) Files() error {
 	// add lfns conditions
 	lfns := getValues(a.Params, "logical_file_name")
 	if len(lfns) > 1 {
+		if err := checkPlainValues("logical_file_name", lfns); err != nil {
+			return Error(err, ParametersErrorCode, "", "dbs.files.Files")
+		}
 		lfngen = true
 		lfnList = true
 		token, binds := TokenGenerator(lfns, 30, "lfn_token")
@@ -221,7 +229,7 @@ func (a *API) Files() error {
 	}
 
 	// use generic query API to fetch the results from DB
-	err = executeAll(a.Writer, a.Separator, stm, args...)
+	err = a.query(stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.files.Files")
 	}
@@ -589,6 +597,7 @@ func (a *API) InsertFiles() error {
 			return Error(err, CommitErrorCode, "", "dbs.files.InsertFiles")
 		}
 	}
+	InvalidateCache("FILES", "FILE_LUMIS", "FILE_PARENTS", "BLOCKS")
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
@@ -691,6 +700,7 @@ func (a *API) UpdateFiles() error {
 		log.Println("unable to commit transaction", err)
 		return Error(err, CommitErrorCode, "", "dbs.files.UpdateFiles")
 	}
+	InvalidateCache("FILES")
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
@@ -0,0 +1,153 @@
+package dbs
+
+// DBS files by lumi mask module
+//
+// FilesByMask API selects files of a dataset which overlap with given lumi
+// mask, e.g. certification JSON. The mask may contain thousands of runs and
+// lumi ranges and therefore its ranges are loaded into temporary table
+// (private temporary table on ORACLE, see temptable module) which is joined
+// with FILE_LUMIS table instead of building IN list of all lumi sections.
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// FilesByMaskRequest represents input of FilesByMask API
+type FilesByMaskRequest struct {
+	Dataset       string   `json:"dataset"`
+	LumiMask      LumiMask `json:"lumi_mask"`
+	Lumis         bool     `json:"lumis"`
+	ValidFileOnly int      `json:"validFileOnly"`
+}
+
+// FileMaskRecord represents file which overlaps with lumi mask
+type FileMaskRecord struct {
+	LogicalFileName string   `json:"logical_file_name"`
+	LumiCount       int64    `json:"lumi_count"`
+	EventCount      int64    `json:"event_count"`
+	LumiMask        LumiMask `json:"lumi_mask,omitempty"`
+}
+
+// FilesByMask DBS API
+func (a *API) FilesByMask() error {
+	data, err := io.ReadAll(a.Reader)
+	if err != nil {
+		return Error(err, ReaderErrorCode, "", "dbs.filesbymask.FilesByMask")
+	}
+	var rec FilesByMaskRequest
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return Error(err, UnmarshalErrorCode, "", "dbs.filesbymask.FilesByMask")
+	}
+	if rec.Dataset == "" || strings.ContainsAny(rec.Dataset, "*%") {
+		msg := "filesbymask API requires dataset without wildcards"
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.filesbymask.FilesByMask")
+	}
+	if len(rec.LumiMask) == 0 {
+		msg := "filesbymask API requires non empty lumi_mask"
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.filesbymask.FilesByMask")
+	}
+	ranges, err := rec.LumiMask.Ranges()
+	if err != nil {
+		return Error(err, ParametersErrorCode, "", "dbs.filesbymask.FilesByMask")
+	}
+	if err := filesByMask(a.readerDB(), a.Writer, a.Separator, rec, ranges); err != nil {
+		return Error(err, QueryErrorCode, "", "dbs.filesbymask.FilesByMask")
+	}
+	return nil
+}
+
+// helper function to load lumi mask into temp table and write files which
+// overlap with it
+func filesByMask(db *sql.DB, w io.Writer, sep string, rec FilesByMaskRequest, ranges []LumiRange) error {
+	tmpl := make(Record)
+	tmpl["Owner"] = DBOWNER
+	tmpl["TempTable"] = fmt.Sprintf("TEMP_LUMI_MASK_%d", time.Now().UnixMicro())
+	if DBOWNER != "sqlite" {
+		tmpl["TempTable"] = fmt.Sprintf("ORA$PTT_LUMI_MASK_%d", time.Now().UnixMicro())
+	}
+	tmpl["ValidFileOnly"] = rec.ValidFileOnly == 1
+	tx, err := beginTempTable(db, "temp_lumi_mask", tmpl)
+	if err != nil {
+		return err
+	}
+	// temp table is dropped at the end of transaction
+	defer tx.Rollback()
+
+	var vals [][]interface{}
+	for _, r := range ranges {
+		vals = append(vals, []interface{}{r.Run, r.First, r.Last})
+	}
+	cols := []string{"RUN_NUM", "FIRST_LUMI", "LAST_LUMI"}
+	if err := insertTempTable(tx, tmpl["TempTable"].(string), cols, vals); err != nil {
+		return err
+	}
+
+	stm, err := LoadTemplateSQL("filesbymask", tmpl)
+	if err != nil {
+		return Error(err, LoadErrorCode, "", "dbs.filesbymask.filesByMask")
+	}
+	stm = CleanStatement(stm)
+	if utils.VERBOSE > 1 {
+		utils.PrintSQL(stm, []interface{}{rec.Dataset}, "execute")
+	}
+	rows, err := tx.Query(stm, rec.Dataset)
+	if err != nil {
+		msg := fmt.Sprintf("unable to query statement: %v", stm)
+		return Error(err, QueryErrorCode, msg, "dbs.filesbymask.filesByMask")
+	}
+	defer rows.Close()
+
+	// rows are ordered by file names, i.e. every file record is written
+	// once all its lumis are read
+	rw := &recordWriter{w: w, sep: sep}
+	var frec *FileMaskRecord
+	var builder lumiMaskBuilder
+	write := func() error {
+		if frec == nil {
+			return nil
+		}
+		if rec.Lumis {
+			frec.LumiMask, _, _ = builder.Mask()
+		}
+		return rw.Write(frec)
+	}
+	for rows.Next() {
+		var lfn string
+		var run, lumi int64
+		var events sql.NullInt64
+		if err := rows.Scan(&lfn, &run, &lumi, &events); err != nil {
+			return Error(err, RowsScanErrorCode, "", "dbs.filesbymask.filesByMask")
+		}
+		if frec == nil || frec.LogicalFileName != lfn {
+			if err := write(); err != nil {
+				return err
+			}
+			frec = &FileMaskRecord{LogicalFileName: lfn}
+			builder = lumiMaskBuilder{}
+		}
+		frec.LumiCount++
+		frec.EventCount += events.Int64
+		if rec.Lumis {
+			builder.Add(run, lumi, 0)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return Error(err, RowsScanErrorCode, "", "dbs.filesbymask.filesByMask")
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	rw.Close()
+	if utils.VERBOSE > 0 {
+		log.Printf("filesbymask: %d lumi ranges, %d files", len(ranges), rw.count)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package dbs
+
+// DBS filters module
+//
+// Values of GET parameters of reader APIs may carry the following filter
+// expressions:
+// - value     equality, e.g. dataset=/a/b/c
+// - val*      pattern matching (LIKE), e.g. dataset=/a/*/RAW
+// - !=value   negation of equality or pattern matching, e.g. data_tier_name=!=RAW
+// - ~value    case-insensitive equality or pattern matching, e.g. dataset=~/zmm*/*/*
+// - !=~value  negation of case-insensitive equality or pattern matching
+// - >value, >=value, <value, <=value
+//             comparison of numeric parameters (sizes, dates, event counts),
+//             e.g. file_size=>=1000000
+// - [v1,v2]   list of values (IN) for any string parameter
+// Repeated parameter values are parsed one by one, plain values and patterns
+// are alternatives while other filters restrict the match. Lists of GET
+// parameters are limited by FilterMaxValues.
+// Filters are compiled into bind parameterised SQL conditions by AddParam
+// function, while their values are validated against lexicon patterns by
+// Validate function.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// FilterMaxValues defines max number of values in filter list
+var FilterMaxValues = 1000
+
+// DBS numeric parameters which support comparison operators
+var numericParameters = []string{
+	"cdate",
+	"ldate",
+	"block_size",
+	"file_count",
+	"file_size",
+	"event_count",
+}
+
+// list of comparison operators ordered by their length
+var comparisonOperators = []string{">=", "<=", ">", "<"}
+
+// Filter represents filter expression of API parameter value
+type Filter struct {
+	Operator string // SQL operator: =, <>, like, not like, >, >=, < or <=
+	Value    string // filter value with SQL wildcards
+	NoCase   bool   // case-insensitive matching
+}
+
+// Condition returns SQL condition of the filter for given column and bind
+// placeholder
+func (f Filter) Condition(sqlName, bind string) string {
+	if f.NoCase {
+		return fmt.Sprintf(" UPPER(%s) %s UPPER(%s)", sqlName, f.Operator, bind)
+	}
+	return fmt.Sprintf(" %s %s %s", sqlName, f.Operator, bind)
+}
+
+// helper function to split filter expression into its operator prefix and value
+func splitFilter(arg string) (string, string) {
+	for _, op := range comparisonOperators {
+		if strings.HasPrefix(arg, op) {
+			return op, strings.TrimSpace(arg[len(op):])
+		}
+	}
+	var prefix string
+	if strings.HasPrefix(arg, "!=") {
+		prefix = "!="
+		arg = arg[2:]
+	}
+	if strings.HasPrefix(arg, "~") {
+		prefix += "~"
+		arg = arg[1:]
+	}
+	return prefix, arg
+}
+
+// ParseFilter parses filter expression of given parameter value
+func ParseFilter(arg string) Filter {
+	prefix, val := splitFilter(arg)
+	if utils.InList(prefix, comparisonOperators) {
+		return Filter{Operator: prefix, Value: val}
+	}
+	op, val := OperatorValue(val)
+	if strings.HasPrefix(prefix, "!=") {
+		if op == "like" {
+			op = "not like"
+		} else {
+			op = "<>"
+		}
+	}
+	return Filter{Operator: op, Value: val, NoCase: strings.HasSuffix(prefix, "~")}
+}
+
+// helper function to check if any of given values is filter expression or
+// pattern, i.e. values can't be used as plain list of values
+func hasFilters(vals []string) bool {
+	for _, v := range vals {
+		if prefix, val := splitFilter(v); prefix != "" || strings.Contains(val, "*") {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function to check that list of values, which is passed to SQL
+// via token generator, does not contain filter expressions
+func checkPlainValues(key string, vals []string) error {
+	if hasFilters(vals) {
+		msg := fmt.Sprintf("filter expressions are not supported in list of %s values", key)
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.filters.checkPlainValues")
+	}
+	return nil
+}
+
+// helper function to build IN condition for given list of values
+func inCondition(name, sqlName string, vals []string) (string, []interface{}) {
+	var binds []string
+	var args []interface{}
+	for i, v := range vals {
+		binds = append(binds, placeholder(fmt.Sprintf("%s_%d", name, i)))
+		args = append(args, v)
+	}
+	cond := fmt.Sprintf(" %s IN (%s)", sqlName, strings.Join(binds, ", "))
+	return cond, args
+}
+
+// helper function to check that number of values of given string parameter
+// does not exceed FilterMaxValues
+func checkValuesLimit(key string, nvals int) error {
+	if nvals > FilterMaxValues && utils.InList(key, strParameters) {
+		msg := fmt.Sprintf("number of %s values exceeds %d", key, FilterMaxValues)
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.filters.checkValuesLimit")
+	}
+	return nil
+}
+
+// helper function to check filter expression of given GET parameter, it
+// returns list of plain values which should be validated against lexicon
+func filterValues(key, arg string) ([]string, error) {
+	if strings.HasPrefix(arg, "[") && strings.HasSuffix(arg, "]") {
+		// only string parameters are treated as list of values, other
+		// parameters, e.g. run_num or lumi_list, have their own list format
+		if !utils.InList(key, strParameters) {
+			return []string{arg}, nil
+		}
+		vals := lfnList(arg)
+		if err := checkValuesLimit(key, len(vals)); err != nil {
+			return nil, err
+		}
+		for _, v := range vals {
+			if prefix, _ := splitFilter(v); prefix != "" {
+				msg := fmt.Sprintf("filter operator %s is not allowed in list of %s values", prefix, key)
+				return nil, Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.filters.filterValues")
+			}
+		}
+		return vals, nil
+	}
+	prefix, val := splitFilter(arg)
+	if utils.InList(prefix, comparisonOperators) {
+		if !utils.InList(key, numericParameters) {
+			msg := fmt.Sprintf("comparison operator %s is not supported by %s parameter", prefix, key)
+			return nil, Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.filters.filterValues")
+		}
+		if _, err := strconv.ParseFloat(val, 64); err != nil {
+			msg := fmt.Sprintf("%s parameter requires numeric value for %s comparison", key, prefix)
+			return nil, Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.filters.filterValues")
+		}
+	}
+	return []string{val}, nil
+}
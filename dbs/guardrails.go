@@ -0,0 +1,199 @@
+package dbs
+
+// DBS query guardrails module
+//
+// Reader APIs which have selective parameters defined in API parameters
+// file may scan large part of DBS tables when they are called without them,
+// e.g. /files?dataset=/*/*/*. The guardrails estimate cost of such queries
+// before their execution:
+// - at least one selective parameter should be provided with selective
+//   value, i.e. value without negation or case-insensitive filter whose
+//   wildcard follows literal prefix: path-like values (datasets, blocks,
+//   files) should specify leading path elements before the wildcard, e.g.
+//   /ZMM/*/RAW, other values should have at least wildcardMinPrefix
+//   characters before the wildcard
+// - optionally the cost of query plan is obtained from DB (EXPLAIN PLAN on
+//   ORACLE and EXPLAIN QUERY PLAN on SQLite) and compared to GuardrailMaxCost
+// Expensive queries are either rejected with QueryGuardrailErrorCode or
+// downgraded, i.e. their output is limited to GuardrailMaxRows records and
+// GuardrailHeader is set in HTTP response.
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// GuardrailHeader represents HTTP header which reports downgraded queries
+const GuardrailHeader = "X-Dbs-Guardrail"
+
+// GuardrailAction defines action for expensive queries, i.e. reject or
+// downgrade, empty value disables query guardrails
+var GuardrailAction string
+
+// GuardrailMaxRows defines max number of records of downgraded queries
+var GuardrailMaxRows int
+
+// GuardrailMaxCost defines max cost of query plan, on ORACLE it is optimizer
+// cost of the plan and on SQLite it is number of full table scans, zero value
+// disables query plan check
+var GuardrailMaxCost float64
+
+// number of leading path elements which should precede wildcard in values
+// of path-like parameters
+var wildcardPathDepth = map[string]int{
+	"dataset":           1,
+	"parent_dataset":    1,
+	"block_name":        1,
+	"logical_file_name": 3,
+}
+
+// min length of literal prefix which should precede wildcard in values of
+// other parameters
+var wildcardMinPrefix = 3
+
+// helper function to check if given value of parameter is selective
+func selectiveValue(key, arg string) bool {
+	if strings.HasPrefix(arg, "[") && strings.HasSuffix(arg, "]") && utils.InList(key, strParameters) {
+		for _, v := range lfnList(arg) {
+			if !selectiveValue(key, v) {
+				return false
+			}
+		}
+		return true
+	}
+	// negation or case-insensitive matching can't use DB indexes
+	prefix, val := splitFilter(arg)
+	if prefix != "" {
+		return false
+	}
+	idx := strings.IndexAny(val, "*%")
+	if idx < 0 {
+		return val != ""
+	}
+	if depth, ok := wildcardPathDepth[key]; ok {
+		// literal prefix should contain trailing slash of last leading element
+		return strings.Count(val[:idx], "/") > depth
+	}
+	return idx >= wildcardMinPrefix
+}
+
+// CheckGuardrails checks if API is called with selective parameters, the
+// expensive queries are rejected or downgraded according to GuardrailAction
+func (a *API) CheckGuardrails() error {
+	if GuardrailAction == "" {
+		return nil
+	}
+	selective, err := GetApiSelective(a.Api)
+	if err != nil {
+		return Error(err, LoadErrorCode, "", "dbs.guardrails.CheckGuardrails")
+	}
+	if len(selective) == 0 {
+		return nil
+	}
+	a.Guarded = true
+	for _, key := range selective {
+		vals := getValues(a.Params, key)
+		if len(vals) == 0 {
+			continue
+		}
+		ok := true
+		for _, v := range vals {
+			if !selectiveValue(key, v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return nil
+		}
+	}
+	msg := fmt.Sprintf("'%s' API requires selective value of one of %v parameters", a.Api, selective)
+	return a.guard(msg, "dbs.guardrails.CheckGuardrails")
+}
+
+// helper function to apply guardrail action to expensive query, the count
+// queries can't be limited and therefore they are always rejected
+func (a *API) guard(msg, function string) error {
+	if GuardrailAction == "downgrade" && !a.Count {
+		log.Printf("downgrade %s API query to %d records: %s", a.Api, GuardrailMaxRows, msg)
+		a.Limit = GuardrailMaxRows
+		if a.Writer != nil {
+			a.Writer.Header().Set(GuardrailHeader, fmt.Sprintf("downgraded; limit=%d", a.Limit))
+		}
+		return nil
+	}
+	return Error(QueryGuardrailErr, QueryGuardrailErrorCode, msg, function)
+}
+
+// helper function to check query plan cost of guarded API query
+func (a *API) checkCost(stm string, args ...interface{}) error {
+	if !a.Guarded || a.Limit > 0 || GuardrailMaxCost <= 0 || DRYRUN {
+		return nil
+	}
+	// EXPLAIN PLAN writes into PLAN_TABLE which is not possible on read-only
+	// replicas, therefore the plan is always obtained from primary DB
+	cost, err := QueryCost(DB, stm, args...)
+	if err != nil {
+		// query plan cost is optional estimate which should not block the query
+		log.Println("unable to get query plan cost", err)
+		return nil
+	}
+	if cost > GuardrailMaxCost {
+		msg := fmt.Sprintf("'%s' API query cost %v exceeds %v", a.Api, cost, GuardrailMaxCost)
+		return a.guard(msg, "dbs.guardrails.checkCost")
+	}
+	return nil
+}
+
+// QueryCost returns cost of query plan of given SQL statement, on ORACLE it
+// is optimizer cost of the plan and on SQLite it is number of full table scans.
+// The DB should be writable since ORACLE stores the plan in PLAN_TABLE.
+func QueryCost(db *sql.DB, stm string, args ...interface{}) (float64, error) {
+	stm = CleanStatement(stm)
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, Error(err, TransactionErrorCode, "", "dbs.guardrails.QueryCost")
+	}
+	// query plan records are not kept in DB
+	defer tx.Rollback()
+
+	if DBOWNER == "sqlite" {
+		rows, err := tx.Query("EXPLAIN QUERY PLAN "+stm, args...)
+		if err != nil {
+			return 0, Error(err, QueryErrorCode, "", "dbs.guardrails.QueryCost")
+		}
+		defer rows.Close()
+		var cost float64
+		for rows.Next() {
+			var id, parent, notused int64
+			var detail string
+			if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+				return 0, Error(err, RowsScanErrorCode, "", "dbs.guardrails.QueryCost")
+			}
+			if strings.HasPrefix(detail, "SCAN ") && !strings.HasPrefix(detail, "SCAN CONSTANT") {
+				cost++
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return 0, Error(err, RowsScanErrorCode, "", "dbs.guardrails.QueryCost")
+		}
+		return cost, nil
+	}
+
+	sid := fmt.Sprintf("dbs%d", time.Now().UnixNano())
+	plan := fmt.Sprintf("EXPLAIN PLAN SET STATEMENT_ID = '%s' FOR %s", sid, stm)
+	if _, err := tx.Exec(plan, args...); err != nil {
+		return 0, Error(err, QueryErrorCode, "", "dbs.guardrails.QueryCost")
+	}
+	var cost sql.NullFloat64
+	err = tx.QueryRow("SELECT COST FROM PLAN_TABLE WHERE STATEMENT_ID = :sid AND ID = 0", sid).Scan(&cost)
+	if err != nil {
+		return 0, Error(err, QueryErrorCode, "", "dbs.guardrails.QueryCost")
+	}
+	return cost.Float64, nil
+}
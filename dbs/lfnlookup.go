@@ -0,0 +1,184 @@
+package dbs
+
+// DBS LFN lookup module
+//
+// LfnLookup API checks large number of files against DBS, e.g. storage dump
+// of a site. The input is stream of JSON values (JSON or NDJSON, optionally
+// gzipped) which is read in batches of LfnLookupBatchSize files. Every batch
+// is looked up by single query which uses TokenGenerator for the list of
+// files and its records are written before the next batch is read, i.e.
+// neither input nor output of the API is kept in memory.
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// LfnLookupBatchSize defines number of files looked up by single query
+var LfnLookupBatchSize = 1000
+
+// LfnRecord represents outcome of file lookup, attributes of the file are
+// only provided for existing files
+type LfnRecord struct {
+	LogicalFileName string `json:"logical_file_name"`
+	Exists          bool   `json:"exists"`
+	IsFileValid     *int64 `json:"is_file_valid,omitempty"`
+	BlockName       string `json:"block_name,omitempty"`
+	Dataset         string `json:"dataset,omitempty"`
+	FileSize        int64  `json:"file_size,omitempty"`
+	CheckSum        string `json:"check_sum,omitempty"`
+	Adler32         string `json:"adler32,omitempty"`
+	MD5             string `json:"md5,omitempty"`
+}
+
+// LfnLookup DBS API
+func (a *API) LfnLookup() error {
+	tmpl := make(Record)
+	tmpl["Owner"] = DBOWNER
+	stm, err := LoadTemplateSQL("lfnlookup", tmpl)
+	if err != nil {
+		return Error(err, LoadErrorCode, "", "dbs.lfnlookup.LfnLookup")
+	}
+	db := a.readerDB()
+	dec := json.NewDecoder(a.Reader)
+	rw := &recordWriter{w: a.Writer, sep: a.Separator}
+	var pending []string
+	var nfiles, nfound int
+	for {
+		var batch []string
+		batch, pending, err = readLfns(dec, pending, LfnLookupBatchSize)
+		if err != nil {
+			return Error(err, DecodeErrorCode, "unable to read list of files", "dbs.lfnlookup.LfnLookup")
+		}
+		if len(batch) == 0 {
+			break
+		}
+		files, err := lookupLfns(db, stm, batch)
+		if err != nil {
+			return Error(err, QueryErrorCode, "", "dbs.lfnlookup.LfnLookup")
+		}
+		for _, lfn := range batch {
+			rec, ok := files[lfn]
+			if ok {
+				nfound++
+			} else {
+				rec = LfnRecord{LogicalFileName: lfn}
+			}
+			if err := rw.Write(rec); err != nil {
+				return err
+			}
+		}
+		nfiles += len(batch)
+	}
+	rw.Close()
+	if utils.VERBOSE > 0 {
+		log.Printf("lfnlookup: %d files, %d found in DBS", nfiles, nfound)
+	}
+	return nil
+}
+
+// helper function to read next batch of files from JSON stream, every JSON
+// value of the stream is either file name, list of file names or object
+// with logical_file_name attribute. It returns the batch along with files
+// of the last read value which do not fit into the batch.
+func readLfns(dec *json.Decoder, pending []string, size int) ([]string, []string, error) {
+	var batch []string
+	for len(batch) < size {
+		if len(pending) == 0 {
+			var val interface{}
+			if err := dec.Decode(&val); err == io.EOF {
+				break
+			} else if err != nil {
+				return batch, pending, err
+			}
+			lfns, err := lfnValues(val)
+			if err != nil {
+				return batch, pending, err
+			}
+			pending = lfns
+			continue
+		}
+		n := size - len(batch)
+		if n > len(pending) {
+			n = len(pending)
+		}
+		batch = append(batch, pending[:n]...)
+		pending = pending[n:]
+	}
+	return batch, pending, nil
+}
+
+// helper function to get file names from JSON value
+func lfnValues(val interface{}) ([]string, error) {
+	switch v := val.(type) {
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		return []string{v}, nil
+	case []interface{}:
+		var lfns []string
+		for _, item := range v {
+			vals, err := lfnValues(item)
+			if err != nil {
+				return nil, err
+			}
+			lfns = append(lfns, vals...)
+		}
+		return lfns, nil
+	case map[string]interface{}:
+		if lfn, ok := v["logical_file_name"]; ok {
+			return lfnValues(lfn)
+		}
+	}
+	msg := fmt.Sprintf("invalid file value %v, should be file name or object with logical_file_name", val)
+	return nil, errors.New(msg)
+}
+
+// helper function to look up given batch of files
+func lookupLfns(db *sql.DB, stm string, lfns []string) (map[string]LfnRecord, error) {
+	var args []interface{}
+	token, binds := TokenGenerator(lfns, 30, "lfn_token")
+	stm = fmt.Sprintf("%s %s", token, stm)
+	cond := fmt.Sprintf(" F.LOGICAL_FILE_NAME in %s", TokenCondition())
+	stm = WhereClause(stm, []string{cond})
+	for _, v := range binds {
+		args = append(args, v)
+	}
+	stm = CleanStatement(stm)
+	if utils.VERBOSE > 1 {
+		utils.PrintSQL(stm, args, "execute")
+	}
+	rows, err := db.Query(stm, args...)
+	if err != nil {
+		msg := fmt.Sprintf("unable to query statement: %v", stm)
+		return nil, Error(err, QueryErrorCode, msg, "dbs.lfnlookup.lookupLfns")
+	}
+	defer rows.Close()
+	files := make(map[string]LfnRecord)
+	for rows.Next() {
+		var valid, size sql.NullInt64
+		var cksum, adler, md5 sql.NullString
+		rec := LfnRecord{Exists: true}
+		err := rows.Scan(&rec.LogicalFileName, &valid, &rec.BlockName, &rec.Dataset, &size, &cksum, &adler, &md5)
+		if err != nil {
+			return nil, Error(err, RowsScanErrorCode, "", "dbs.lfnlookup.lookupLfns")
+		}
+		rec.IsFileValid = &valid.Int64
+		rec.FileSize = size.Int64
+		rec.CheckSum = cksum.String
+		rec.Adler32 = adler.String
+		rec.MD5 = md5.String
+		files[rec.LogicalFileName] = rec
+	}
+	if err := rows.Err(); err != nil {
+		return nil, Error(err, RowsScanErrorCode, "", "dbs.lfnlookup.lookupLfns")
+	}
+	return files, nil
+}
@@ -0,0 +1,301 @@
+package dbs
+
+// DBS dataset lineage module
+//
+// Lineage API walks DATASET_PARENTS relation starting from given dataset up
+// (parents), down (children) or in both directions up to given depth. The
+// relation is walked either by single recursive SQL query (recursive
+// LineageMethod), which is supported by ORACLE and SQLite back-ends, or
+// level by level (iterative LineageMethod), the latter is also used if
+// recursive query fails. Diamonds, i.e. datasets reachable via different
+// paths, are de-duplicated and every dataset is reported once along with
+// its generation relative to given dataset (negative for ancestors and
+// positive for descendants). The lineage is written as JSON record with
+// nodes and edges of the graph or in Graphviz DOT format.
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// LineageMethod defines method to walk dataset lineage: recursive or iterative
+var LineageMethod string
+
+// LineageMaxDepth defines max depth of dataset lineage and file ancestry
+var LineageMaxDepth = 20
+
+// LineageNode represents dataset of lineage graph
+type LineageNode struct {
+	Dataset    string `json:"dataset"`
+	DatasetID  int64  `json:"dataset_id"`
+	Generation int    `json:"generation"`
+}
+
+// LineageEdge represents parentage relation of lineage graph
+type LineageEdge struct {
+	ParentDataset string `json:"parent_dataset"`
+	ChildDataset  string `json:"child_dataset"`
+}
+
+// LineageGraph represents lineage graph of dataset
+type LineageGraph struct {
+	Dataset   string        `json:"dataset"`
+	Direction string        `json:"direction"`
+	Depth     int           `json:"depth"`
+	Nodes     []LineageNode `json:"nodes"`
+	Edges     []LineageEdge `json:"edges"`
+}
+
+// lineageRelation represents parentage relation fetched from DB
+type lineageRelation struct {
+	Parent   string
+	ParentID int64
+	Child    string
+	ChildID  int64
+}
+
+// Lineage DBS API
+func (a *API) Lineage() error {
+	dataset, _ := getSingleValue(a.Params, "dataset")
+	if dataset == "" || strings.ContainsAny(dataset, "*%") {
+		msg := "lineage API requires dataset parameter without wildcards"
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.lineage.Lineage")
+	}
+	direction := "up"
+	if val, err := getSingleValue(a.Params, "direction"); err == nil {
+		direction = strings.ToLower(val)
+	}
+	if direction != "up" && direction != "down" && direction != "both" {
+		msg := fmt.Sprintf("invalid direction value '%s', should be up, down or both", direction)
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.lineage.Lineage")
+	}
+	depth := LineageMaxDepth
+	if val, err := getSingleValue(a.Params, "depth"); err == nil {
+		depth, err = strconv.Atoi(val)
+		if err != nil || depth < 1 || depth > LineageMaxDepth {
+			msg := fmt.Sprintf("invalid depth value '%s', should be between 1 and %d", val, LineageMaxDepth)
+			return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.lineage.Lineage")
+		}
+	}
+
+	graph, err := datasetLineage(a.readerDB(), dataset, direction, depth)
+	if err != nil {
+		return Error(err, QueryErrorCode, "", "dbs.lineage.Lineage")
+	}
+	if a.Format == "dot" {
+		a.Writer.Write([]byte(graph.Dot()))
+		return nil
+	}
+	data, err := json.Marshal([]LineageGraph{graph})
+	if err != nil {
+		return Error(err, MarshalErrorCode, "", "dbs.lineage.Lineage")
+	}
+	a.Writer.Write(data)
+	return nil
+}
+
+// Dot returns representation of lineage graph in Graphviz DOT format
+func (g *LineageGraph) Dot() string {
+	var out strings.Builder
+	out.WriteString("digraph lineage {\n")
+	out.WriteString("  rankdir=LR;\n")
+	out.WriteString("  node [shape=box];\n")
+	for _, n := range g.Nodes {
+		if n.Generation == 0 {
+			out.WriteString(fmt.Sprintf("  %s [style=bold];\n", strconv.Quote(n.Dataset)))
+		} else {
+			out.WriteString(fmt.Sprintf("  %s;\n", strconv.Quote(n.Dataset)))
+		}
+	}
+	for _, e := range g.Edges {
+		out.WriteString(fmt.Sprintf("  %s -> %s;\n", strconv.Quote(e.ParentDataset), strconv.Quote(e.ChildDataset)))
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// helper function to build lineage graph of given dataset
+func datasetLineage(db *sql.DB, dataset, direction string, depth int) (LineageGraph, error) {
+	graph := LineageGraph{Dataset: dataset, Direction: direction, Depth: depth}
+	tx, err := db.Begin()
+	if err != nil {
+		return graph, Error(err, TransactionErrorCode, "", "dbs.lineage.datasetLineage")
+	}
+	defer tx.Rollback()
+	did, err := GetID(tx, "DATASETS", "dataset_id", "dataset", dataset)
+	if err != nil {
+		msg := fmt.Sprintf("unable to find dataset %s", dataset)
+		return graph, Error(err, GetIDErrorCode, msg, "dbs.lineage.datasetLineage")
+	}
+
+	generations := map[int64]int{did: 0}
+	names := map[int64]string{did: dataset}
+	edges := make(map[LineageEdge]bool)
+	for _, up := range []bool{true, false} {
+		if (up && direction == "down") || (!up && direction == "up") {
+			continue
+		}
+		var relations []lineageRelation
+		if LineageMethod != "iterative" {
+			relations, err = recursiveLineage(tx, dataset, up, depth)
+			if err != nil {
+				log.Println("unable to get lineage via recursive query, fall back to iterative one", err)
+			}
+		}
+		if LineageMethod == "iterative" || err != nil {
+			relations, err = iterativeLineage(tx, did, up, depth)
+			if err != nil {
+				return graph, err
+			}
+		}
+		// adjacency list of relations in walk direction
+		next := make(map[int64][]int64)
+		for _, r := range relations {
+			names[r.ParentID] = r.Parent
+			names[r.ChildID] = r.Child
+			edges[LineageEdge{ParentDataset: r.Parent, ChildDataset: r.Child}] = true
+			if up {
+				next[r.ChildID] = append(next[r.ChildID], r.ParentID)
+			} else {
+				next[r.ParentID] = append(next[r.ParentID], r.ChildID)
+			}
+		}
+		// assign generations in breadth-first order, i.e. by shortest path
+		step := 1
+		if up {
+			step = -1
+		}
+		frontier := []int64{did}
+		for gen := step; len(frontier) > 0; gen += step {
+			var nodes []int64
+			for _, id := range frontier {
+				for _, nid := range next[id] {
+					if _, ok := generations[nid]; !ok {
+						generations[nid] = gen
+						nodes = append(nodes, nid)
+					}
+				}
+			}
+			frontier = nodes
+		}
+	}
+
+	for id, gen := range generations {
+		graph.Nodes = append(graph.Nodes, LineageNode{Dataset: names[id], DatasetID: id, Generation: gen})
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		if graph.Nodes[i].Generation != graph.Nodes[j].Generation {
+			return graph.Nodes[i].Generation < graph.Nodes[j].Generation
+		}
+		return graph.Nodes[i].Dataset < graph.Nodes[j].Dataset
+	})
+	for e := range edges {
+		graph.Edges = append(graph.Edges, e)
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].ParentDataset != graph.Edges[j].ParentDataset {
+			return graph.Edges[i].ParentDataset < graph.Edges[j].ParentDataset
+		}
+		return graph.Edges[i].ChildDataset < graph.Edges[j].ChildDataset
+	})
+	return graph, nil
+}
+
+// helper function to get columns which link relations in walk direction
+func lineageColumns(up bool) (string, string) {
+	if up {
+		return "THIS_DATASET_ID", "PARENT_DATASET_ID"
+	}
+	return "PARENT_DATASET_ID", "THIS_DATASET_ID"
+}
+
+// helper function to fetch lineage relations of given dataset via
+// recursive SQL query
+func recursiveLineage(tx *sql.Tx, dataset string, up bool, depth int) ([]lineageRelation, error) {
+	tmpl := make(Record)
+	tmpl["Owner"] = DBOWNER
+	tmpl["From"], tmpl["To"] = lineageColumns(up)
+	stm, err := LoadTemplateSQL("lineage", tmpl)
+	if err != nil {
+		return nil, Error(err, LoadErrorCode, "", "dbs.lineage.recursiveLineage")
+	}
+	return lineageRelations(tx, stm, dataset, depth)
+}
+
+// helper function to fetch lineage relations of given dataset level by level
+func iterativeLineage(tx *sql.Tx, did int64, up bool, depth int) ([]lineageRelation, error) {
+	tmpl := make(Record)
+	tmpl["Owner"] = DBOWNER
+	stm, err := LoadTemplateSQL("lineage_step", tmpl)
+	if err != nil {
+		return nil, Error(err, LoadErrorCode, "", "dbs.lineage.iterativeLineage")
+	}
+	from, _ := lineageColumns(up)
+	var relations []lineageRelation
+	visited := map[int64]bool{did: true}
+	frontier := []int64{did}
+	for level := 0; level < depth && len(frontier) > 0; level++ {
+		var nodes []int64
+		for start := 0; start < len(frontier); start += FilterMaxValues {
+			end := start + FilterMaxValues
+			if end > len(frontier) {
+				end = len(frontier)
+			}
+			var vals []string
+			for _, id := range frontier[start:end] {
+				vals = append(vals, fmt.Sprintf("%d", id))
+			}
+			cond, args := inCondition("dataset_id", "DP."+from, vals)
+			rels, err := lineageRelations(tx, WhereClause(stm, []string{cond}), args...)
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range rels {
+				nid := r.ParentID
+				if !up {
+					nid = r.ChildID
+				}
+				if !visited[nid] {
+					visited[nid] = true
+					nodes = append(nodes, nid)
+				}
+			}
+			relations = append(relations, rels...)
+		}
+		frontier = nodes
+	}
+	return relations, nil
+}
+
+// helper function to execute lineage query and fetch its relations
+func lineageRelations(tx *sql.Tx, stm string, args ...interface{}) ([]lineageRelation, error) {
+	stm = CleanStatement(stm)
+	if utils.VERBOSE > 1 {
+		utils.PrintSQL(stm, args, "execute")
+	}
+	rows, err := tx.Query(stm, args...)
+	if err != nil {
+		msg := fmt.Sprintf("unable to query statement: %v", stm)
+		return nil, Error(err, QueryErrorCode, msg, "dbs.lineage.lineageRelations")
+	}
+	defer rows.Close()
+	var relations []lineageRelation
+	for rows.Next() {
+		var r lineageRelation
+		if err := rows.Scan(&r.Parent, &r.ParentID, &r.Child, &r.ChildID); err != nil {
+			return nil, Error(err, RowsScanErrorCode, "", "dbs.lineage.lineageRelations")
+		}
+		relations = append(relations, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, Error(err, RowsScanErrorCode, "", "dbs.lineage.lineageRelations")
+	}
+	return relations, nil
+}
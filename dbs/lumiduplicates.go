@@ -0,0 +1,145 @@
+package dbs
+
+// DBS duplicate lumis module
+//
+// LumiDuplicates API reports run/lumi pairs which appear in more than one
+// valid file of a dataset, i.e. lumis whose events are counted twice, along
+// with files and blocks which own them. The same check is applied by
+// bulkblocks APIs to lumis of injected files when BulkBlocksLumiCheck is set.
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// BulkBlocksLumiCheck defines how bulkblocks APIs treat lumis of injected
+// files which already exist in valid files of the same dataset: "warn" logs
+// them, "reject" aborts the injection and empty value disables the check
+var BulkBlocksLumiCheck string
+
+// DuplicateLumiErr represents error of lumis which already exist in dataset
+var DuplicateLumiErr = errors.New("duplicate lumi error")
+
+// max number of duplicate lumis reported by bulkblocks lumi check
+const maxReportedLumis = 10
+
+// LumiDuplicates DBS API
+func (a *API) LumiDuplicates() error {
+	dataset, _ := getSingleValue(a.Params, "dataset")
+	if dataset == "" || strings.ContainsAny(dataset, "*%") {
+		msg := "lumiduplicates API requires dataset without wildcards"
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.lumiduplicates.LumiDuplicates")
+	}
+	tmpl := make(Record)
+	tmpl["Owner"] = DBOWNER
+	stm, err := LoadTemplateSQL("lumiduplicates", tmpl)
+	if err != nil {
+		return Error(err, LoadErrorCode, "", "dbs.lumiduplicates.LumiDuplicates")
+	}
+	// dataset is used by both duplicates sub-query and main query
+	err = a.query(stm, dataset, dataset)
+	if err != nil {
+		return Error(err, QueryErrorCode, "", "dbs.lumiduplicates.LumiDuplicates")
+	}
+	return nil
+}
+
+// helper function to check if lumis of valid files injected by bulkblocks
+// APIs already exist in other valid files of given dataset. The injected
+// run/lumi pairs are loaded into temporary table and only colliding lumis
+// are read from DB. Files with the same name are skipped since their lumis
+// belong to injected files.
+func checkBulkBlocksLumis(tx *sql.Tx, dataset string, files []File, isFileValid int64, hash string) error {
+	if BulkBlocksLumiCheck == "" {
+		return nil
+	}
+	lumis := make(map[[2]int64]string)
+	injected := make(map[string]bool)
+	var vals [][]interface{}
+	for _, f := range files {
+		injected[f.LogicalFileName] = true
+		if isFileValid != 1 && f.IsFileValid != 1 {
+			continue
+		}
+		for _, l := range f.FileLumiList {
+			key := [2]int64{l.RunNumber, l.LumiSectionNumber}
+			if _, ok := lumis[key]; ok {
+				continue
+			}
+			lumis[key] = f.LogicalFileName
+			vals = append(vals, []interface{}{l.RunNumber, l.LumiSectionNumber, f.LogicalFileName})
+		}
+	}
+	if len(vals) == 0 {
+		return nil
+	}
+
+	tmpl := make(Record)
+	tmpl["Owner"] = DBOWNER
+	tmpl["TempTable"] = fmt.Sprintf("TEMP_BULKBLOCKS_LUMIS_%d", time.Now().UnixMicro())
+	if DBOWNER != "sqlite" {
+		tmpl["TempTable"] = fmt.Sprintf("ORA$PTT_BULKBLOCKS_LUMIS_%d", time.Now().UnixMicro())
+	}
+	table := tmpl["TempTable"].(string)
+	if err := createTempTable(tx, "temp_bulkblocks_lumis", tmpl); err != nil {
+		return err
+	}
+	cols := []string{"RUN_NUM", "LUMI_SECTION_NUM", "LOGICAL_FILE_NAME"}
+	if err := insertTempTable(tx, table, cols, vals); err != nil {
+		return err
+	}
+	stm, err := LoadTemplateSQL("bulkblocks_lumis", tmpl)
+	if err != nil {
+		return Error(err, LoadErrorCode, "", "dbs.lumiduplicates.checkBulkBlocksLumis")
+	}
+	stm = CleanStatement(stm)
+	if utils.VERBOSE > 1 {
+		utils.PrintSQL(stm, []interface{}{dataset}, "execute")
+	}
+	rows, err := tx.Query(stm, dataset)
+	if err != nil {
+		msg := fmt.Sprintf("unable to query statement: %v", stm)
+		return Error(err, QueryErrorCode, msg, "dbs.lumiduplicates.checkBulkBlocksLumis")
+	}
+	defer rows.Close()
+	var dups []string
+	for rows.Next() {
+		var run, lumi int64
+		var ilfn, lfn string
+		if err := rows.Scan(&run, &lumi, &ilfn, &lfn); err != nil {
+			return Error(err, RowsScanErrorCode, "", "dbs.lumiduplicates.checkBulkBlocksLumis")
+		}
+		if injected[lfn] {
+			continue
+		}
+		dups = append(dups, fmt.Sprintf("run %d lumi %d of %s exists in %s", run, lumi, ilfn, lfn))
+	}
+	if err := rows.Err(); err != nil {
+		return Error(err, RowsScanErrorCode, "", "dbs.lumiduplicates.checkBulkBlocksLumis")
+	}
+	rows.Close()
+	if err := dropTempTable(tx, table); err != nil {
+		return err
+	}
+	if len(dups) == 0 {
+		return nil
+	}
+	sort.Strings(dups)
+	msg := fmt.Sprintf("%d lumis already exist in valid files of dataset %s", len(dups), dataset)
+	if len(dups) > maxReportedLumis {
+		dups = append(dups[:maxReportedLumis], "...")
+	}
+	msg = fmt.Sprintf("%s: %s", msg, strings.Join(dups, ", "))
+	if BulkBlocksLumiCheck == "warn" {
+		log.Println(hash, "WARNING", msg)
+		return nil
+	}
+	return Error(DuplicateLumiErr, ValidateErrorCode, msg, "dbs.lumiduplicates.checkBulkBlocksLumis")
+}
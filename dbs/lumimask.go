@@ -0,0 +1,277 @@
+package dbs
+
+// DBS lumi mask module
+//
+// Lumi mask represents run/lumi coverage in the JSON format used by CMS
+// certification (golden JSON), i.e. map of run numbers to list of lumi
+// section ranges, e.g. {"1": [[1, 10], [20, 30]], "2": [[1, 5]]}.
+// LumiMask API provides run/lumi coverage of a dataset, block or list of
+// files in this compact form instead of one record per lumi section.
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// LumiMask represents run/lumi mask in CMS certification JSON format
+type LumiMask map[string][][]int64
+
+// LumiMaskRecord represents run/lumi coverage of DBS entity
+type LumiMaskRecord struct {
+	LumiMask    LumiMask           `json:"lumi_mask"`
+	EventCounts map[string][]int64 `json:"event_counts,omitempty"`
+	RunCount    int                `json:"run_count"`
+	LumiCount   int64              `json:"lumi_count"`
+	EventCount  int64              `json:"event_count"`
+}
+
+// LumiRange represents range of lumi sections of a run
+type LumiRange struct {
+	Run   int64
+	First int64
+	Last  int64
+}
+
+// Ranges returns sorted list of lumi ranges of the mask, overlapping and
+// adjacent ranges of the same run are merged
+func (m LumiMask) Ranges() ([]LumiRange, error) {
+	var ranges []LumiRange
+	for run, pairs := range m {
+		rnum, err := strconv.ParseInt(run, 10, 64)
+		if err != nil || rnum <= 0 {
+			msg := fmt.Sprintf("invalid run number '%s' in lumi mask", run)
+			return nil, Error(errors.New(msg), ParametersErrorCode, msg, "dbs.lumimask.Ranges")
+		}
+		for _, pair := range pairs {
+			if len(pair) != 2 || pair[0] <= 0 || pair[0] > pair[1] {
+				msg := fmt.Sprintf("invalid lumi range %v of run %s in lumi mask", pair, run)
+				return nil, Error(errors.New(msg), ParametersErrorCode, msg, "dbs.lumimask.Ranges")
+			}
+			ranges = append(ranges, LumiRange{Run: rnum, First: pair[0], Last: pair[1]})
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].Run != ranges[j].Run {
+			return ranges[i].Run < ranges[j].Run
+		}
+		return ranges[i].First < ranges[j].First
+	})
+	var out []LumiRange
+	for _, r := range ranges {
+		n := len(out)
+		if n > 0 && out[n-1].Run == r.Run && r.First <= out[n-1].Last+1 {
+			if r.Last > out[n-1].Last {
+				out[n-1].Last = r.Last
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// lumiMaskBuilder builds compact lumi mask from run/lumi pairs, the pairs
+// ordered by run and lumi numbers are merged into ranges as they are added
+type lumiMaskBuilder struct {
+	ranges map[int64][]lumiCount
+}
+
+// lumiCount represents range of lumi sections with their number of events
+type lumiCount struct {
+	First  int64
+	Last   int64
+	Events int64
+}
+
+// Add adds lumi section of a run along with its number of events
+func (b *lumiMaskBuilder) Add(run, lumi, events int64) {
+	if b.ranges == nil {
+		b.ranges = make(map[int64][]lumiCount)
+	}
+	rs := b.ranges[run]
+	if n := len(rs); n > 0 && lumi >= rs[n-1].First && lumi <= rs[n-1].Last+1 {
+		if lumi > rs[n-1].Last {
+			rs[n-1].Last = lumi
+		}
+		rs[n-1].Events += events
+		return
+	}
+	b.ranges[run] = append(rs, lumiCount{First: lumi, Last: lumi, Events: events})
+}
+
+// Mask returns compact lumi mask, i.e. consecutive lumi sections of a run
+// are represented by single range, along with number of events of every
+// range and total number of lumi sections
+func (b *lumiMaskBuilder) Mask() (LumiMask, map[string][]int64, int64) {
+	mask := make(LumiMask)
+	events := make(map[string][]int64)
+	var nlumis int64
+	for run, rs := range b.ranges {
+		sort.Slice(rs, func(i, j int) bool { return rs[i].First < rs[j].First })
+		var merged []lumiCount
+		for _, r := range rs {
+			n := len(merged)
+			if n > 0 && r.First <= merged[n-1].Last+1 {
+				if r.Last > merged[n-1].Last {
+					merged[n-1].Last = r.Last
+				}
+				merged[n-1].Events += r.Events
+				continue
+			}
+			merged = append(merged, r)
+		}
+		key := fmt.Sprintf("%d", run)
+		for _, r := range merged {
+			mask[key] = append(mask[key], []int64{r.First, r.Last})
+			events[key] = append(events[key], r.Events)
+			nlumis += r.Last - r.First + 1
+		}
+	}
+	return mask, events, nlumis
+}
+
+// LumiMask DBS API
+func (a *API) LumiMask() error {
+	var args []interface{}
+	var conds []string
+	tmpl := make(Record)
+	tmpl["Owner"] = DBOWNER
+
+	datasets := getValues(a.Params, "dataset")
+	blocks := getValues(a.Params, "block_name")
+	lfns := getValues(a.Params, "logical_file_name")
+	nparams := 0
+	for _, vals := range [][]string{datasets, blocks, lfns} {
+		if len(vals) > 0 {
+			nparams++
+		}
+		for _, v := range vals {
+			if strings.ContainsAny(v, "*%") {
+				msg := fmt.Sprintf("lumimask API does not support wildcards, value '%s'", v)
+				return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.lumimask.LumiMask")
+			}
+		}
+	}
+	if nparams != 1 || len(datasets) > 1 || len(blocks) > 1 {
+		msg := "lumimask API requires either single dataset, single block_name or list of logical_file_name"
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.lumimask.LumiMask")
+	}
+	var token string
+	if len(datasets) == 1 {
+		tmpl["Dataset"] = true
+		conds = append(conds, fmt.Sprintf("D.DATASET = %s", placeholder("dataset")))
+		args = append(args, datasets[0])
+	} else if len(blocks) == 1 {
+		tmpl["BlockName"] = true
+		conds = append(conds, fmt.Sprintf("B.BLOCK_NAME = %s", placeholder("block_name")))
+		args = append(args, blocks[0])
+	} else if len(lfns) == 1 {
+		conds = append(conds, fmt.Sprintf("F.LOGICAL_FILE_NAME = %s", placeholder("logical_file_name")))
+		args = append(args, lfns[0])
+	} else {
+		var binds []string
+		token, binds = TokenGenerator(lfns, 100, "lfns_token")
+		conds = append(conds, fmt.Sprintf(" F.LOGICAL_FILE_NAME in %s", TokenCondition()))
+		for _, v := range binds {
+			args = append(args, v)
+		}
+	}
+
+	runs := getValues(a.Params, "run_num")
+	t, c, na, err := RunsConditions(runs, "FL")
+	if err != nil {
+		return Error(err, ParametersErrorCode, "", "dbs.lumimask.LumiMask")
+	}
+	if t != "" {
+		if token != "" {
+			msg := "lumimask API supports single list of lfns or run numbers"
+			return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.lumimask.LumiMask")
+		}
+		token = t
+		// run token binds should precede other binds
+		args = append(na, args...)
+	} else {
+		args = append(args, na...)
+	}
+	conds = append(conds, c...)
+
+	if v, _ := getSingleValue(a.Params, "validFileOnly"); v == "1" {
+		tmpl["ValidFileOnly"] = true
+		conds = append(conds, "F.IS_FILE_VALID = 1")
+		conds = append(conds, "DT.DATASET_ACCESS_TYPE in ('VALID', 'PRODUCTION')")
+	}
+	events, _ := getSingleValue(a.Params, "events")
+
+	stm, err := LoadTemplateSQL("lumimask", tmpl)
+	if err != nil {
+		return Error(err, LoadErrorCode, "", "dbs.lumimask.LumiMask")
+	}
+	if token != "" {
+		stm = fmt.Sprintf("%s %s", token, stm)
+	}
+	stm = WhereClause(stm, conds)
+	// ordered lumis are merged into ranges as they are read
+	stm = fmt.Sprintf("%s ORDER BY FL.RUN_NUM, FL.LUMI_SECTION_NUM", stm)
+	stm = CleanStatement(stm)
+	if DRYRUN {
+		utils.PrintSQL(stm, args, "")
+		return nil
+	}
+	if utils.VERBOSE > 1 {
+		utils.PrintSQL(stm, args, "execute")
+	}
+
+	db := a.readerDB()
+	// get cached prepared statement before transaction holds DB connection
+	stmt := preparedStmt(db, stm)
+	tx, err := db.Begin()
+	if err != nil {
+		return Error(err, TransactionErrorCode, "", "dbs.lumimask.LumiMask")
+	}
+	defer tx.Rollback()
+	rows, err := txQuery(tx, stmt, stm, args...)
+	if err != nil {
+		msg := fmt.Sprintf("unable to query statement: %v", stm)
+		return Error(err, QueryErrorCode, msg, "dbs.lumimask.LumiMask")
+	}
+	defer rows.Close()
+	var builder lumiMaskBuilder
+	var rec LumiMaskRecord
+	for rows.Next() {
+		var run, lumi int64
+		var nevents sql.NullInt64
+		if err := rows.Scan(&run, &lumi, &nevents); err != nil {
+			return Error(err, RowsScanErrorCode, "", "dbs.lumimask.LumiMask")
+		}
+		builder.Add(run, lumi, nevents.Int64)
+		rec.EventCount += nevents.Int64
+	}
+	if err := rows.Err(); err != nil {
+		return Error(err, RowsScanErrorCode, "", "dbs.lumimask.LumiMask")
+	}
+	var counts map[string][]int64
+	rec.LumiMask, counts, rec.LumiCount = builder.Mask()
+	rec.RunCount = len(rec.LumiMask)
+	if events == "1" {
+		rec.EventCounts = counts
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return Error(err, MarshalErrorCode, "", "dbs.lumimask.LumiMask")
+	}
+	if a.Separator != "" {
+		data = append(append([]byte("[\n"), data...), []byte("\n]\n")...)
+	} else {
+		data = append(data, []byte("\n")...)
+	}
+	a.Writer.Write(data)
+	return nil
+}
@@ -365,7 +365,7 @@ func GetParentBlocks(rurl, block string, order int) ([]MigrationBlock, error) {
 		if utils.VERBOSE > 1 {
 			log.Println("unable to get list of blocks at remote url", rurl, err)
 		}
-		return out, Error(err, HttpRequestErrorCode, "", "dbs.migrate.GetParentsBlock")
+		return out, Error(err, HttpRequestErrorCode, "", "dbs.migrate.GetParentBlocks")
 	}
 	// add block parents to final list
 	for _, blk := range srcblocks {
@@ -396,7 +396,9 @@ func GetParentBlocks(rurl, block string, order int) ([]MigrationBlock, error) {
 		}
 		return out, nil
 	}
-	// collect results from goroutines
+	// collect results from goroutines, the failure of remote call leads to
+	// incomplete list of parents and therefore we report it back
+	var perr error
 	exit := false
 	for {
 		select {
@@ -405,6 +407,9 @@ func GetParentBlocks(rurl, block string, order int) ([]MigrationBlock, error) {
 				if utils.VERBOSE > 1 {
 					log.Printf("unable to fetch blocks for url=%s block=%s error=%v", rurl, r.Block, r.Error)
 				}
+				if perr == nil {
+					perr = r.Error
+				}
 			} else {
 				for _, blk := range r.Blocks {
 					parentBlocks = append(parentBlocks, MigrationBlock{Block: blk, Order: order - 1})
@@ -422,6 +427,10 @@ func GetParentBlocks(rurl, block string, order int) ([]MigrationBlock, error) {
 		}
 	}
 
+	if perr != nil {
+		return out, Error(perr, HttpRequestErrorCode, "", "dbs.migrate.GetParentBlocks")
+	}
+
 	// loop over parent blocks and get its parents
 	for _, pblk := range parentBlocks {
 		out = append(out, pblk)
@@ -432,7 +441,7 @@ func GetParentBlocks(rurl, block string, order int) ([]MigrationBlock, error) {
 			if utils.VERBOSE > 1 {
 				log.Printf("fail to get url=%s block=%v error=%v", rurl, pblk, err)
 			}
-			continue
+			return out, Error(err, HttpRequestErrorCode, "", "dbs.migrate.GetParentBlocks")
 		}
 		for _, b := range results {
 			out = append(out, b)
@@ -563,6 +572,20 @@ func GetParentDatasetBlocks(rurl, dataset string, order int) ([]MigrationBlock,
 
 // helper function to check if migration input is already queued
 func alreadyQueued(input string) error {
+	mid, err := queuedMigrationRequest(input)
+	if err != nil {
+		return err
+	}
+	if mid != 0 {
+		msg := fmt.Sprintf("migration request %s is already exist in DB with id=%d", input, mid)
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// helper function to find id of migration request of given input, it
+// returns zero id if input is not queued
+func queuedMigrationRequest(input string) (int64, error) {
 	stm := getSQL("check_migration_request")
 	var args []interface{}
 	args = append(args, input)
@@ -573,15 +596,11 @@ func alreadyQueued(input string) error {
 	err := DB.QueryRow(stm, args...).Scan(&mid)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil
+			return 0, nil
 		}
-		return err
+		return 0, err
 	}
-	if mid != 0 {
-		msg := fmt.Sprintf("migration request %s is already exist in DB with id=%d", input, mid)
-		return errors.New(msg)
-	}
-	return nil
+	return mid, nil
 }
 
 // DatasetShortRecord represents short dataset record
@@ -652,6 +671,10 @@ func (a *API) SubmitMigration() error {
 		log.Println("unable to read from reader", err)
 		return Error(err, ReaderErrorCode, "", "dbs.migrate.SubmitMigration")
 	}
+	// batch migration request with list of migration inputs
+	if rurl, inputs, ok := batchInputs(data); ok {
+		return a.submitMigrationBatch(rurl, inputs)
+	}
 	tstamp := time.Now().Unix()
 	rec := MigrationRequest{
 		MIGRATION_STATUS:       QUEUED,
@@ -753,10 +776,8 @@ func StartMigrationRequest(rec MigrationRequest) {
 // helper function to start migration request and return list of migration ids
 //gocyclo:ignore
 func startMigrationRequest(req MigrationRequest) ([]MigrationReport, error) {
-	var err error
 	status := int64(PENDING)
-	msg := "Migration request is started"
-	var reports []MigrationReport
+	var msg string
 
 	input := req.MIGRATION_INPUT
 	mstr := fmt.Sprintf("Migration request for %+v", input)
@@ -817,6 +838,21 @@ func startMigrationRequest(req MigrationRequest) ([]MigrationReport, error) {
 		}
 	}
 
+	return registerMigrationBlocks(req, migBlocks)
+}
+
+// helper function to register migration blocks of given migration request,
+// i.e. insert MigrationRequest and MigrationBlocks records for every block
+// and update status of original migration request
+//gocyclo:ignore
+func registerMigrationBlocks(req MigrationRequest, migBlocks []string) ([]MigrationReport, error) {
+	var err error
+	var reports []MigrationReport
+	status := int64(PENDING)
+	msg := "Migration request is started"
+	input := req.MIGRATION_INPUT
+	mstr := fmt.Sprintf("Migration request for %+v", input)
+
 	// if no migration blocks found to process return immediately
 	if len(migBlocks) == 0 {
 		status = int64(EXIST_IN_DB)
@@ -1092,6 +1128,12 @@ func (a *API) ProcessMigration() {
 		log.Printf("unable to unmarshal Record, error %v", err)
 		return
 	}
+	// file parentage of migrated block is taken from source DBS as is
+	data, err = blockDumpPayload(data, brec)
+	if err != nil {
+		log.Printf("unable to marshal BulkBlocks, error %v", err)
+		return
+	}
 	reader := bytes.NewReader(data)
 	writer := utils.StdoutWriter("")
 
@@ -1121,8 +1163,13 @@ func (a *API) ProcessMigration() {
 		status = FAILED
 		updateMigrationStatus(mrec, FAILED)
 	} else {
-		status = COMPLETED
-		updateMigrationStatus(mrec, COMPLETED)
+		// verify migrated block against source DBS if it is required
+		vstatus, report := verifiedMigrationStatus(mid, brec)
+		status = int64(vstatus)
+		updateMigrationStatus(mrec, vstatus)
+		if report != "" {
+			log.Printf("migration request %v verification report: %s", mid, report)
+		}
 	}
 	log.Printf("updated migration request %v with status %v", mid, status)
 }
@@ -1187,7 +1234,8 @@ func (a *API) ProcessMigrationCtx(timeout int) error {
 	mrec := records[0]
 
 	// execute slow operation in background
-	go a.processMigration(ch, &status, mrec)
+	var report string
+	go a.processMigration(ch, &status, &report, mrec)
 
 	// the slow operation will either finish or timeout
 	select {
@@ -1196,6 +1244,9 @@ func (a *API) ProcessMigrationCtx(timeout int) error {
 		err = errors.New(msg)
 	case <-ch:
 		msg = fmt.Sprintf("migration request completed with status %v", status)
+		if report != "" {
+			msg = fmt.Sprintf("%s, %s", msg, report)
+		}
 		log.Println(msg)
 	}
 	reports := []MigrationReport{migrationReport(mrec, msg, status, err)}
@@ -1216,9 +1267,20 @@ func (a *API) ProcessMigrationCtx(timeout int) error {
 	return nil
 }
 
+// blockDumpPayload returns bulkblocks payload of migrated block, options of
+// bulkblocks APIs which alter injected data, e.g. file parents inference,
+// are never applied to migrated blocks
+func blockDumpPayload(data []byte, brec BulkBlocks) ([]byte, error) {
+	if !brec.InferFileParents {
+		return data, nil
+	}
+	brec.InferFileParents = false
+	return json.Marshal(brec)
+}
+
 // processMigration will process given migration report
-// and inject data to source DBS
-func (a *API) processMigration(ch chan<- bool, status *int64, mrec MigrationRequest) {
+// and inject data to source DBS, the report holds verification report of migrated block
+func (a *API) processMigration(ch chan<- bool, status *int64, report *string, mrec MigrationRequest) {
 	// report on channel that we are done with this workflow
 	defer func() {
 		ch <- true
@@ -1278,6 +1340,12 @@ func (a *API) processMigration(ch chan<- bool, status *int64, mrec MigrationRequ
 		log.Printf("unable to unmarshal Record, error %v", err)
 		return
 	}
+	// file parentage of migrated block is taken from source DBS as is
+	data, err = blockDumpPayload(data, brec)
+	if err != nil {
+		log.Printf("unable to marshal BulkBlocks, error %v", err)
+		return
+	}
 	reader := bytes.NewReader(data)
 	writer := utils.StdoutWriter("")
 
@@ -1307,8 +1375,11 @@ func (a *API) processMigration(ch chan<- bool, status *int64, mrec MigrationRequ
 		*status = FAILED
 		updateMigrationStatus(mrec, FAILED)
 	} else {
-		*status = COMPLETED
-		updateMigrationStatus(mrec, COMPLETED)
+		// verify migrated block against source DBS if it is required
+		vstatus, msg := verifiedMigrationStatus(mid, brec)
+		*status = int64(vstatus)
+		*report = msg
+		updateMigrationStatus(mrec, vstatus)
 	}
 	log.Printf("updated migration request %v with status %v", mid, *status)
 }
@@ -1559,6 +1630,10 @@ func (a *API) StatusMigration() error {
 	if _, e := getSingleValue(a.Params, "create_by"); e == nil {
 		conds, args = AddParam("create_by", "MR.CREATE_BY", a.Params, conds, args)
 	}
+	if _, e := getSingleValue(a.Params, "migration_batch_id"); e == nil {
+		tmpl["Batch"] = true
+		conds, args = AddParam("migration_batch_id", "MBT.MIGRATION_BATCH_ID", a.Params, conds, args)
+	}
 
 	// get SQL statement from static area
 	stm, err := LoadTemplateSQL("migration_requests", tmpl)
@@ -1572,7 +1647,7 @@ func (a *API) StatusMigration() error {
 	}
 
 	// use generic query API to fetch the results from DB
-	err = executeAll(a.Writer, a.Separator, stm, args...)
+	err = executeAll(DB, a.Writer, a.Separator, stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.migrate.StatusMigration")
 	}
@@ -1586,7 +1661,7 @@ func (a *API) TotalMigration() error {
 	stm := getSQL("migration_total_count")
 
 	// use generic query API to fetch the results from DB
-	err := executeAll(a.Writer, a.Separator, stm, args...)
+	err := executeAll(DB, a.Writer, a.Separator, stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.migrate.TotalMigration")
 	}
@@ -1639,40 +1714,167 @@ func (a *API) CancelMigration() error {
 
 // CleanupMigrationRequests clean-ups migration requests in DB
 func (a *API) CleanupMigrationRequests(offset int64) error {
+	records, err := cleanupMigrationRequests(offset, false)
+	if err != nil {
+		return Error(err, RemoveErrorCode, "", "dbs.migrate.CleanupMigrationRequests")
+	}
+	log.Printf("cleanup removed %d migration requests", len(records))
+	return nil
+}
+
+// MigrationCleanupRequest represents migration cleanup request object
+type MigrationCleanupRequest struct {
+	DryRun bool `json:"dry_run"`
+}
+
+// CleanupMigration DBS API reports or removes migration requests according
+// to retention policy. In dry run mode it only reports migration requests
+// which would be purged. The GET API always runs in dry run mode while POST
+// API removes migration requests unless dry_run is set in its payload.
+func (a *API) CleanupMigration(dryRun bool) error {
+	if val, err := getSingleValue(a.Params, "dry_run"); err == nil && dryRun && val != "true" {
+		msg := "cleanup via GET only supports dry_run=true, use POST to remove migration requests"
+		return Error(InvalidRequestErr, InvalidRequestErrorCode, msg, "dbs.migrate.CleanupMigration")
+	}
+	if !dryRun && a.Reader != nil {
+		data, err := io.ReadAll(a.Reader)
+		if err != nil {
+			return Error(err, ReaderErrorCode, "", "dbs.migrate.CleanupMigration")
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			rec := MigrationCleanupRequest{}
+			if err := json.Unmarshal(data, &rec); err != nil {
+				return Error(err, UnmarshalErrorCode, "", "dbs.migrate.CleanupMigration")
+			}
+			dryRun = rec.DryRun
+		}
+	}
+	records, err := cleanupMigrationRequests(MigrationCleanupOffset, dryRun)
+	if err != nil {
+		return Error(err, RemoveErrorCode, "", "dbs.migrate.CleanupMigration")
+	}
+	if records == nil {
+		records = []MigrationRequest{}
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return Error(err, MarshalErrorCode, "", "dbs.migrate.CleanupMigration")
+	}
+	a.Writer.Write(data)
+	return nil
+}
+
+// helper function to load cleanup migration requests statement
+// for given offset and retention policy. Only completed, exist in DB and
+// terminally failed requests are removed, the offset is used as retention
+// of statuses without explicit retention.
+func cleanupStatement(offset, now int64, sel bool) (string, error) {
+	retention := func(status int) int64 {
+		if val := MigrationCleanupRetention[status]; val > 0 {
+			return now - val
+		}
+		return now - offset
+	}
 	tmplData := make(Record)
 	tmplData["Owner"] = DBOWNER
-	tmplData["Value"] = time.Now().Unix() - offset
-	tmplData["FailDate"] = time.Now().Unix() - 2*7*60*60 // 2 weeks
+	tmplData["Select"] = sel
+	tmplData["Retries"] = MigrationRetries
+	tmplData["CompletedDate"] = retention(COMPLETED)
+	tmplData["ExistDate"] = retention(EXIST_IN_DB)
+	tmplData["FailDate"] = retention(TERM_FAILED)
 	stm, err := LoadTemplateSQL("cleanup_migration_requests", tmplData)
 	if err != nil {
 		log.Println("unable to load cleanup_migration_requests template", err)
-		return Error(err, LoadErrorCode, "", "dbs.migrate.CleanupMigrationRequests")
+		return "", Error(err, LoadErrorCode, "", "dbs.migrate.cleanupStatement")
+	}
+	return CleanStatement(stm), nil
+}
+
+// helper function to archive migration requests into JSONL file
+func archiveMigrationRequests(fname string, records []MigrationRequest) error {
+	file, err := os.OpenFile(fname, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return Error(err, WriterErrorCode, "", "dbs.migrate.archiveMigrationRequests")
+	}
+	defer file.Close()
+	for _, rec := range records {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return Error(err, MarshalErrorCode, "", "dbs.migrate.archiveMigrationRequests")
+		}
+		data = append(data, '\n')
+		if _, err := file.Write(data); err != nil {
+			return Error(err, WriterErrorCode, "", "dbs.migrate.archiveMigrationRequests")
+		}
+	}
+	return file.Sync()
+}
+
+// helper function to cleanup migration requests. It returns list of
+// removed migration requests (or requests which would be removed in dry run
+// mode). If MigrationCleanupArchive is set the removed requests are stored
+// in JSONL archive file before deletion.
+func cleanupMigrationRequests(offset int64, dryRun bool) ([]MigrationRequest, error) {
+	var records []MigrationRequest
+	now := time.Now().Unix()
+	stm, err := cleanupStatement(offset, now, true)
+	if err != nil {
+		return records, err
 	}
 
 	// start transaction
 	tx, err := DB.Begin()
 	if err != nil {
 		log.Println("unable to get DB transaction", err)
-		return Error(err, TransactionErrorCode, "", "dbs.migrate.CleanupMigrationRequests")
+		return records, Error(err, TransactionErrorCode, "", "dbs.migrate.cleanupMigrationRequests")
 	}
 	defer tx.Rollback()
-	stm = CleanStatement(stm)
 	if utils.VERBOSE > 0 {
 		var args []interface{}
 		utils.PrintSQL(stm, args, "execute")
 	}
+	rows, err := tx.Query(stm)
+	if err != nil {
+		log.Printf("unable to execute %s, error %v", stm, err)
+		return records, Error(err, QueryErrorCode, "", "dbs.migrate.cleanupMigrationRequests")
+	}
+	records, err = scanMigrationRequests(rows)
+	rows.Close()
+	if err != nil {
+		return records, Error(err, RowsScanErrorCode, "", "dbs.migrate.cleanupMigrationRequests")
+	}
+	if dryRun || len(records) == 0 {
+		return records, nil
+	}
 
+	// archive migration requests before deleting them
+	if MigrationCleanupArchive != "" {
+		err = archiveMigrationRequests(MigrationCleanupArchive, records)
+		if err != nil {
+			log.Println("unable to archive migration requests", err)
+			return records, Error(err, WriterErrorCode, "", "dbs.migrate.cleanupMigrationRequests")
+		}
+	}
+
+	stm, err = cleanupStatement(offset, now, false)
+	if err != nil {
+		return records, err
+	}
+	if utils.VERBOSE > 0 {
+		var args []interface{}
+		utils.PrintSQL(stm, args, "execute")
+	}
 	_, err = tx.Exec(stm)
 	if err != nil {
 		log.Printf("unable to execute %s, error %v", stm, err)
-		return Error(err, RemoveErrorCode, "", "dbs.migrate.CleanupMigrationRequests")
+		return records, Error(err, RemoveErrorCode, "", "dbs.migrate.cleanupMigrationRequests")
 	}
 
 	// commit transaction
 	err = tx.Commit()
 	if err != nil {
 		log.Println("unable to commit transaction", err)
-		return Error(err, CommitErrorCode, "", "dbs.migrate.CleanupMigrationRequests")
+		return records, Error(err, CommitErrorCode, "", "dbs.migrate.cleanupMigrationRequests")
 	}
-	return nil
+	return records, nil
 }
@@ -0,0 +1,435 @@
+package dbs
+
+// DBS migration batch module
+//
+// Batch migration request contains list of migration inputs (datasets or
+// blocks) from the same remote DBS instance. All inputs are registered as
+// regular migration requests which are grouped under single umbrella
+// (batch) id. The parent block closure of all inputs is computed once, i.e.
+// remote parents of every block or dataset are fetched only once, and blocks
+// are deduplicated across inputs.
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// MigrationBatchRequest represents payload of batch migration request.
+// The migration inputs can be provided either via migration_inputs list
+// or via migration_input attribute as JSON list.
+type MigrationBatchRequest struct {
+	MIGRATION_URL    string          `json:"migration_url"`
+	MIGRATION_INPUT  json.RawMessage `json:"migration_input"`
+	MIGRATION_INPUTS []string        `json:"migration_inputs"`
+}
+
+// MigrationBatchReport represents report of batch migration request
+type MigrationBatchReport struct {
+	MigrationBatchID    int64             `json:"migration_batch_id"`
+	MigrationRequestIDs []int64           `json:"migration_request_ids"`
+	Reports             []MigrationReport `json:"reports"`
+}
+
+// helper function to extract list of migration inputs from batch migration
+// request payload, it returns false if payload represents single migration input
+func batchInputs(data []byte) (string, []string, bool) {
+	var rec MigrationBatchRequest
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return "", nil, false
+	}
+	inputs := rec.MIGRATION_INPUTS
+	if len(rec.MIGRATION_INPUT) > 0 && rec.MIGRATION_INPUT[0] == '[' {
+		var vals []string
+		if err := json.Unmarshal(rec.MIGRATION_INPUT, &vals); err != nil {
+			return "", nil, false
+		}
+		inputs = append(inputs, vals...)
+	}
+	if len(inputs) == 0 {
+		return "", nil, false
+	}
+	// remove duplicate inputs and keep their order
+	var out []string
+	for _, input := range inputs {
+		input = strings.TrimSpace(input)
+		if input != "" && !utils.InList(input, out) {
+			out = append(out, input)
+		}
+	}
+	return rec.MIGRATION_URL, out, true
+}
+
+// helper function to insert batch record for given migration request
+func insertMigrationBatch(tx *sql.Tx, bid int64, rec MigrationRequest) error {
+	stm := getSQL("insert_migration_batches")
+	if utils.VERBOSE > 0 {
+		args := []interface{}{bid, rec.MIGRATION_REQUEST_ID, rec.CREATION_DATE, rec.CREATE_BY}
+		utils.PrintSQL(stm, args, "execute")
+	}
+	_, err := tx.Exec(stm, bid, rec.MIGRATION_REQUEST_ID, rec.CREATION_DATE, rec.CREATE_BY)
+	if err != nil {
+		return Error(err, InsertErrorCode, "", "dbs.migration_batch.insertMigrationBatch")
+	}
+	return nil
+}
+
+// helper function to get new migration batch id
+func migrationBatchID(tx *sql.Tx) (int64, error) {
+	var bid int64
+	var err error
+	if DBOWNER == "sqlite" {
+		bid, err = LastInsertID(tx, "MIGRATION_BATCHES", "migration_batch_id")
+		bid += 1
+	} else {
+		bid, err = IncrementSequence(tx, "SEQ_MBT")
+	}
+	if err != nil {
+		return bid, Error(err, LastInsertErrorCode, "", "dbs.migration_batch.migrationBatchID")
+	}
+	return bid, nil
+}
+
+// submitMigrationBatch submits batch migration request for given url and
+// list of inputs. Inputs which are already queued or not valid for migration
+// are reported and skipped.
+func (a *API) submitMigrationBatch(rurl string, inputs []string) error {
+	tstamp := time.Now().Unix()
+	var reports []MigrationReport
+	var requests []MigrationRequest
+	var rids []int64
+
+	// check migration inputs before we start transaction since
+	// validation requires remote DBS calls
+	var candidates []MigrationRequest
+	for _, input := range inputs {
+		rec := MigrationRequest{
+			MIGRATION_URL:          rurl,
+			MIGRATION_INPUT:        input,
+			MIGRATION_STATUS:       QUEUED,
+			CREATE_BY:              a.CreateBy,
+			CREATION_DATE:          tstamp,
+			LAST_MODIFIED_BY:       a.CreateBy,
+			LAST_MODIFICATION_DATE: tstamp,
+		}
+		mstr := fmt.Sprintf("Migration request %s", input)
+		if err := alreadyQueued(input); err != nil {
+			msg := fmt.Sprintf("%s already queued", mstr)
+			reports = append(reports, migrationReport(rec, msg, FAILED, err))
+			continue
+		}
+		if err := validInput(rurl, input); err != nil {
+			msg := fmt.Sprintf("%s not allowed for migration", mstr)
+			reports = append(reports, migrationReport(rec, msg, FAILED, err))
+			continue
+		}
+		candidates = append(candidates, rec)
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Error(err, TransactionErrorCode, "", "dbs.migration_batch.submitMigrationBatch")
+	}
+	defer tx.Rollback()
+	bid, err := migrationBatchID(tx)
+	if err != nil {
+		return Error(err, LastInsertErrorCode, "", "dbs.migration_batch.submitMigrationBatch")
+	}
+	log.Printf("submit migration batch %d with %d inputs", bid, len(inputs))
+
+	for _, rec := range candidates {
+		input := rec.MIGRATION_INPUT
+		mstr := fmt.Sprintf("Migration request %s", input)
+		err = rec.Insert(tx)
+		if err != nil {
+			return Error(err, InsertErrorCode, mstr, "dbs.migration_batch.submitMigrationBatch")
+		}
+		// insert silently skips existing migration input, therefore we look-up its id
+		rid, err := GetID(tx, "MIGRATION_REQUESTS", "MIGRATION_REQUEST_ID", "MIGRATION_INPUT", input)
+		if err != nil {
+			return Error(err, GetIDErrorCode, mstr, "dbs.migration_batch.submitMigrationBatch")
+		}
+		rec.MIGRATION_REQUEST_ID = rid
+		err = insertMigrationBatch(tx, bid, rec)
+		if err != nil {
+			return Error(err, InsertErrorCode, mstr, "dbs.migration_batch.submitMigrationBatch")
+		}
+		updateMigrationStatusMetrics(rec, QUEUED)
+		requests = append(requests, rec)
+		rids = append(rids, rid)
+		reports = append(reports, migrationReport(rec, "Migration request is started", QUEUED, nil))
+	}
+	err = tx.Commit()
+	if err != nil {
+		return Error(err, CommitErrorCode, "", "dbs.migration_batch.submitMigrationBatch")
+	}
+
+	// start migration requests of our batch
+	if len(requests) > 0 {
+		go StartMigrationBatch(bid, requests)
+	}
+
+	report := MigrationBatchReport{
+		MigrationBatchID:    bid,
+		MigrationRequestIDs: rids,
+		Reports:             reports,
+	}
+	if report.MigrationRequestIDs == nil {
+		report.MigrationRequestIDs = []int64{}
+	}
+	data, err := json.Marshal([]MigrationBatchReport{report})
+	if err != nil {
+		return Error(err, MarshalErrorCode, "", "dbs.migration_batch.submitMigrationBatch")
+	}
+	a.Writer.Write(data)
+	return nil
+}
+
+// StartMigrationBatch starts asynchronously migration requests of given
+// batch via goroutine with timeout context
+func StartMigrationBatch(bid int64, requests []MigrationRequest) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		time.Duration(MigrationAsyncTimeout)*time.Second)
+	defer cancel()
+	ch := make(chan string, 1)
+	go func(ctx context.Context, ch chan string) {
+		reports, err := startMigrationBatch(requests)
+		if err != nil {
+			ch <- fmt.Sprintf("fail to start migration batch %d, error %v", bid, err)
+		} else {
+			ch <- fmt.Sprintf("finished migration batch %d with %d migration requests", bid, len(reports))
+		}
+	}(ctx, ch)
+	select {
+	case <-ctx.Done():
+		log.Printf("Migration batch %d with context is cancelled %v", bid, ctx.Err())
+	case response := <-ch:
+		log.Println(response)
+	}
+}
+
+// max number of concurrent remote calls used to build parent closure
+const migrationClosureWorkers = 20
+
+// migrationClosure represents parent closure of inputs of migration batch at
+// remote DBS. Parents of every block or dataset and blocks of every dataset
+// are fetched only once and shared by all inputs of the batch.
+type migrationClosure struct {
+	rurl    string
+	parents map[string][]string // parent blocks or datasets of visited inputs
+	blocks  map[string][]string // blocks of visited datasets
+	failed  map[string]error    // inputs whose remote look-up failed
+}
+
+// helper function to fetch given remote data for list of blocks or datasets
+// concurrently, it returns results and errors in order of given inputs
+func fetchConcurrently(inputs []string, fetch func(string) ([]string, error)) ([][]string, []error) {
+	results := make([][]string, len(inputs))
+	errs := make([]error, len(inputs))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, migrationClosureWorkers)
+	for idx, input := range inputs {
+		wg.Add(1)
+		go func(i int, v string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i], errs[i] = fetch(v)
+		}(idx, input)
+	}
+	wg.Wait()
+	return results, errs
+}
+
+// helper function to fetch parents of given block or dataset, RAW data do
+// not have parents
+func remoteParents(rurl, input string) ([]string, error) {
+	dataset := strings.Split(input, "#")[0]
+	if strings.HasSuffix(dataset, "/RAW") {
+		return []string{}, nil
+	}
+	return GetParents(rurl, input)
+}
+
+// helper function to walk parents of given inputs level by level with
+// shared set of visited blocks and datasets
+func (c *migrationClosure) walk(inputs []string) {
+	level := inputs
+	for len(level) > 0 {
+		var nodes []string
+		for _, n := range utils.OrderedSet(level) {
+			if _, ok := c.parents[n]; ok {
+				continue
+			}
+			if _, ok := c.failed[n]; ok {
+				continue
+			}
+			nodes = append(nodes, n)
+		}
+		results, errs := fetchConcurrently(nodes, func(n string) ([]string, error) {
+			return remoteParents(c.rurl, n)
+		})
+		level = nil
+		for i, n := range nodes {
+			if errs[i] != nil {
+				c.failed[n] = errs[i]
+				continue
+			}
+			c.parents[n] = results[i]
+			level = append(level, results[i]...)
+		}
+	}
+
+	// fetch blocks of all visited datasets
+	var datasets []string
+	for n := range c.parents {
+		if !strings.Contains(n, "#") {
+			datasets = append(datasets, n)
+		}
+	}
+	sort.Strings(datasets)
+	results, errs := fetchConcurrently(datasets, func(n string) ([]string, error) {
+		return GetBlocks(c.rurl, n)
+	})
+	for i, n := range datasets {
+		if errs[i] != nil {
+			c.failed[n] = errs[i]
+			continue
+		}
+		c.blocks[n] = results[i]
+	}
+}
+
+// helper function to get blocks of parent closure of given input in order of
+// processing, i.e. first parents then children
+func (c *migrationClosure) closure(input string) ([]string, error) {
+	// depth of every ancestor is its longest distance from the input
+	depth := make(map[string]int)
+	var failed error
+	var visit func(n string, d int)
+	visit = func(n string, d int) {
+		if err, ok := c.failed[n]; ok && failed == nil {
+			failed = Error(err, HttpRequestErrorCode, "unable to get parents of "+n, "dbs.migration_batch.closure")
+		}
+		if v, ok := depth[n]; (ok && v >= d) || d > len(c.parents) {
+			return
+		}
+		depth[n] = d
+		for _, p := range c.parents[n] {
+			visit(p, d+1)
+		}
+	}
+	visit(input, 0)
+	if failed != nil {
+		return nil, failed
+	}
+	var nodes []string
+	for n := range depth {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if depth[nodes[i]] != depth[nodes[j]] {
+			return depth[nodes[i]] > depth[nodes[j]]
+		}
+		return nodes[i] < nodes[j]
+	})
+	var blocks []string
+	for _, n := range nodes {
+		if strings.Contains(n, "#") {
+			blocks = append(blocks, n)
+		} else {
+			blocks = append(blocks, c.blocks[n]...)
+		}
+	}
+	return utils.OrderedSet(blocks), nil
+}
+
+// helper function to start migration requests of a batch. The parent block
+// closure of all inputs is computed once, i.e. every remote block or dataset
+// is visited only once, and every block is assigned only to the first
+// migration request which requires it.
+func startMigrationBatch(requests []MigrationRequest) ([]MigrationReport, error) {
+	var reports []MigrationReport
+	localhost := fmt.Sprintf("%s%s", utils.Localhost, utils.BASE)
+
+	// walk parents of all inputs at destination DBS instance, inputs of the
+	// batch come from the same remote DBS
+	time0 := time.Now()
+	var inputs []string
+	for _, req := range requests {
+		inputs = append(inputs, req.MIGRATION_INPUT)
+	}
+	var rurl string
+	if len(requests) > 0 {
+		rurl = requests[0].MIGRATION_URL
+	}
+	mc := &migrationClosure{
+		rurl:    rurl,
+		parents: make(map[string][]string),
+		blocks:  make(map[string][]string),
+		failed:  make(map[string]error),
+	}
+	mc.walk(inputs)
+	closures := make(map[string][]string)
+	var valid []MigrationRequest
+	var allBlocks []string
+	for _, req := range requests {
+		input := req.MIGRATION_INPUT
+		blocks, err := mc.closure(input)
+		if err != nil {
+			msg := fmt.Sprintf("unable to get parent blocks for %s", input)
+			log.Println(msg, err)
+			reports = append(reports, migrationReport(req, msg, PENDING, err))
+			continue
+		}
+		closures[input] = blocks
+		allBlocks = append(allBlocks, blocks...)
+		valid = append(valid, req)
+	}
+	allBlocks = utils.Set(allBlocks)
+	if utils.VERBOSE > 0 {
+		log.Printf("Migration batch blocks from destination, total %d, visited %d blocks and datasets, elapsed time %v", len(allBlocks), len(mc.parents), time.Since(time0))
+	}
+
+	// get blocks at source DBS instance for all inputs at once
+	time0 = time.Now()
+	srcBlocks := make(map[string]bool)
+	for _, blk := range prepareMigrationListAtSource(localhost, allBlocks) {
+		srcBlocks[blk] = true
+	}
+	if utils.VERBOSE > 0 {
+		log.Printf("Migration batch blocks from source %s, total %d, elapsed time %v", localhost, len(srcBlocks), time.Since(time0))
+	}
+
+	// assign blocks to migration requests and register them
+	assigned := make(map[string]bool)
+	for _, req := range valid {
+		input := req.MIGRATION_INPUT
+		var migBlocks []string
+		for _, blk := range closures[input] {
+			if !srcBlocks[blk] && !assigned[blk] {
+				migBlocks = append(migBlocks, blk)
+				assigned[blk] = true
+			}
+		}
+		// add dataset itself to the list of migration
+		if !strings.Contains(input, "#") && !utils.InList(input, migBlocks) {
+			migBlocks = append(migBlocks, input)
+		}
+		rep, err := registerMigrationBlocks(req, migBlocks)
+		reports = append(reports, rep...)
+		if err != nil {
+			log.Printf("unable to register migration blocks for %s, error %v", input, err)
+		}
+	}
+	return reports, nil
+}
@@ -197,6 +197,12 @@ func MigrationRequests(mid int64) ([]MigrationRequest, error) {
 		return records, Error(err, QueryErrorCode, msg, "dbs.migration_requests.MigrationRequests")
 	}
 	defer rows.Close()
+	return scanMigrationRequests(rows)
+}
+
+// helper function to scan rows of migration requests table
+func scanMigrationRequests(rows *sql.Rows) ([]MigrationRequest, error) {
+	var records []MigrationRequest
 	for rows.Next() {
 		var mid, migRetryCount, migCreationDate, migLastModificationDate, migStatus int64
 		var migURL, migInput, migCreateBy, migLastModifiedBy string
@@ -214,7 +220,7 @@ func MigrationRequests(mid int64) ([]MigrationRequest, error) {
 			&migRetryCount,
 		)
 		if err != nil {
-			return records, Error(err, RowsScanErrorCode, "", "dbs.migration_requests.MigrationRequests")
+			return records, Error(err, RowsScanErrorCode, "", "dbs.migration_requests.scanMigrationRequests")
 		}
 		rec := MigrationRequest{
 			MIGRATION_REQUEST_ID:   mid,
@@ -230,8 +236,8 @@ func MigrationRequests(mid int64) ([]MigrationRequest, error) {
 		}
 		records = append(records, rec)
 	}
-	if err = rows.Err(); err != nil {
-		return records, Error(err, RowsScanErrorCode, "", "dbs.migration_requests.MigrationRequests")
+	if err := rows.Err(); err != nil {
+		return records, Error(err, RowsScanErrorCode, "", "dbs.migration_requests.scanMigrationRequests")
 	}
 	return records, nil
 }
@@ -24,6 +24,15 @@ var MigrationCleanupInterval int
 // MigrationCleanupOffset defines offset in seconds to delete migration requests
 var MigrationCleanupOffset int64
 
+// MigrationCleanupRetention defines retention (in seconds) of migration requests per migration status
+var MigrationCleanupRetention map[int]int64
+
+// MigrationCleanupArchive defines JSONL file to archive removed migration requests
+var MigrationCleanupArchive string
+
+// MigrationSubscriptionInterval defines interval to evaluate migration subscriptions
+var MigrationSubscriptionInterval int
+
 // MigrationRetries specifies total number of migration retries
 var MigrationRetries int64
 
@@ -42,6 +51,7 @@ func MigrationServer(interval, timeout int, ch <-chan bool) {
 	}
 
 	lastCall := time.Now()
+	var lastSubscriptionCall time.Time
 	for {
 		select {
 		case v := <-ch:
@@ -54,6 +64,13 @@ func MigrationServer(interval, timeout int, ch <-chan bool) {
 			if time.Since(lastCall).Seconds() < float64(interval) {
 				continue
 			}
+			// evaluate migration subscriptions and create new migration requests
+			if time.Since(lastSubscriptionCall).Seconds() >= float64(MigrationSubscriptionInterval) {
+				lastSubscriptionCall = time.Now()
+				if err := ProcessSubscriptions(); err != nil {
+					log.Println("fail to process migration subscriptions", err)
+				}
+			}
 			if utils.VERBOSE > 0 {
 				log.Println("call MigrationRequests")
 			}
@@ -0,0 +1,555 @@
+package dbs
+
+// DBS migration subscriptions module
+//
+// Migration subscription represents continuous mirroring of datasets from
+// remote DBS instance. The DBSMigration daemon periodically evaluates all
+// active subscriptions, i.e. it looks-up datasets matching subscription
+// pattern in remote DBS, fetches their blocks created since last check via
+// remote /blocks?min_ldate= API and creates and starts regular migration
+// requests for blocks which are not yet known to migration server.
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// subscription status codes
+const (
+	SUBSCRIPTION_ACTIVE = iota
+	SUBSCRIPTION_PAUSED
+)
+
+// TotalSubscriptionRequests counts total number of migration requests created by subscriptions
+var TotalSubscriptionRequests uint64
+
+// MigrationSubscription represent MigrationSubscriptions table
+type MigrationSubscription struct {
+	SUBSCRIPTION_ID        int64  `json:"subscription_id"`
+	MIGRATION_URL          string `json:"migration_url" validate:"required"`
+	DATASET_PATTERN        string `json:"dataset_pattern" validate:"required"`
+	DATA_TIER_NAME         string `json:"data_tier_name"`
+	DATASET_ACCESS_TYPE    string `json:"dataset_access_type"`
+	SUBSCRIPTION_STATUS    int64  `json:"subscription_status" validate:"gte=0,lte=1"`
+	LAST_CHECK_DATE        int64  `json:"last_check_date"`
+	CREATE_BY              string `json:"create_by" validate:"required"`
+	CREATION_DATE          int64  `json:"creation_date" validate:"required,number,gt=0"`
+	LAST_MODIFIED_BY       string `json:"last_modified_by" validate:"required"`
+	LAST_MODIFICATION_DATE int64  `json:"last_modification_date" validate:"required,number,gt=0"`
+}
+
+// MigrationSubscriptionRequest represents subscription request used by pause/resume APIs
+type MigrationSubscriptionRequest struct {
+	SUBSCRIPTION_ID int64 `json:"subscription_id"`
+}
+
+// Insert implementation of MigrationSubscription
+func (r *MigrationSubscription) Insert(tx *sql.Tx) error {
+	var tid int64
+	var err error
+	if r.SUBSCRIPTION_ID == 0 {
+		if DBOWNER == "sqlite" {
+			tid, err = LastInsertID(tx, "MIGRATION_SUBSCRIPTIONS", "subscription_id")
+			r.SUBSCRIPTION_ID = tid + 1
+		} else {
+			tid, err = IncrementSequence(tx, "SEQ_MS")
+			r.SUBSCRIPTION_ID = tid
+		}
+		if err != nil {
+			return Error(err, LastInsertErrorCode, "", "dbs.migration_subscriptions.Insert")
+		}
+	}
+	// set defaults and validate the record
+	r.SetDefaults()
+	err = r.Validate()
+	if err != nil {
+		log.Println("unable to validate record", err)
+		return Error(err, ValidateErrorCode, "", "dbs.migration_subscriptions.Insert")
+	}
+	// get SQL statement from static area
+	stm := getSQL("insert_migration_subscriptions")
+	if utils.VERBOSE > 0 {
+		log.Printf("Insert MigrationSubscription\n%s\n%+v", stm, r)
+	}
+	_, err = tx.Exec(stm,
+		r.SUBSCRIPTION_ID,
+		r.MIGRATION_URL,
+		r.DATASET_PATTERN,
+		r.DATA_TIER_NAME,
+		r.DATASET_ACCESS_TYPE,
+		r.SUBSCRIPTION_STATUS,
+		r.LAST_CHECK_DATE,
+		r.CREATION_DATE,
+		r.CREATE_BY,
+		r.LAST_MODIFICATION_DATE,
+		r.LAST_MODIFIED_BY)
+	if err != nil {
+		if utils.VERBOSE > 0 {
+			log.Println("unable to insert MigrationSubscription", err)
+		}
+		return Error(err, InsertErrorCode, "", "dbs.migration_subscriptions.Insert")
+	}
+	return nil
+}
+
+// Validate implementation of MigrationSubscription
+func (r *MigrationSubscription) Validate() error {
+	if err := RecordValidator.Struct(*r); err != nil {
+		log.Println("validation error", err)
+		return DecodeValidatorError(r, err)
+	}
+	if !strings.HasPrefix(r.DATASET_PATTERN, "/") {
+		msg := fmt.Sprintf("invalid dataset pattern %s", r.DATASET_PATTERN)
+		return Error(InvalidParamErr, PatternErrorCode, msg, "dbs.migration_subscriptions.Validate")
+	}
+	return nil
+}
+
+// SetDefaults implements set defaults for MigrationSubscription
+func (r *MigrationSubscription) SetDefaults() {
+	if r.DATASET_ACCESS_TYPE == "" {
+		r.DATASET_ACCESS_TYPE = "VALID"
+	}
+}
+
+// Decode implementation for MigrationSubscription
+func (r *MigrationSubscription) Decode(reader io.Reader) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		log.Println("fail to read data", err)
+		return Error(err, ReaderErrorCode, "", "dbs.migration_subscriptions.Decode")
+	}
+	err = json.Unmarshal(data, &r)
+	if err != nil {
+		log.Println("fail to decode data", err)
+		return Error(err, UnmarshalErrorCode, "", "dbs.migration_subscriptions.Decode")
+	}
+	return nil
+}
+
+// SubmitSubscription DBS API creates new migration subscription
+func (a *API) SubmitSubscription() error {
+	var rec MigrationSubscription
+	err := rec.Decode(a.Reader)
+	if err != nil {
+		return Error(err, UnmarshalErrorCode, "", "dbs.migration_subscriptions.SubmitSubscription")
+	}
+	// new subscription is always active and owned by requester
+	tstamp := time.Now().Unix()
+	rec.SUBSCRIPTION_ID = 0
+	rec.SUBSCRIPTION_STATUS = SUBSCRIPTION_ACTIVE
+	rec.LAST_CHECK_DATE = 0
+	rec.CREATE_BY = a.CreateBy
+	rec.CREATION_DATE = tstamp
+	rec.LAST_MODIFIED_BY = a.CreateBy
+	rec.LAST_MODIFICATION_DATE = tstamp
+	log.Printf("submit migration subscription %+v", rec)
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Error(err, TransactionErrorCode, "", "dbs.migration_subscriptions.SubmitSubscription")
+	}
+	defer tx.Rollback()
+	err = rec.Insert(tx)
+	if err != nil {
+		return Error(err, InsertErrorCode, "", "dbs.migration_subscriptions.SubmitSubscription")
+	}
+	err = tx.Commit()
+	if err != nil {
+		return Error(err, CommitErrorCode, "", "dbs.migration_subscriptions.SubmitSubscription")
+	}
+
+	data, err := json.Marshal([]MigrationSubscription{rec})
+	if err != nil {
+		return Error(err, MarshalErrorCode, "", "dbs.migration_subscriptions.SubmitSubscription")
+	}
+	a.Writer.Write(data)
+	return nil
+}
+
+// StatusSubscription DBS API provides information about migration subscriptions
+func (a *API) StatusSubscription() error {
+	var args []interface{}
+	var conds []string
+	tmpl := make(Record)
+	tmpl["Owner"] = DBOWNER
+
+	if _, e := getSingleValue(a.Params, "subscription_id"); e == nil {
+		conds, args = AddParam("subscription_id", "MS.SUBSCRIPTION_ID", a.Params, conds, args)
+	}
+	if _, e := getSingleValue(a.Params, "subscription_status"); e == nil {
+		conds, args = AddParam("subscription_status", "MS.SUBSCRIPTION_STATUS", a.Params, conds, args)
+	}
+	if _, e := getSingleValue(a.Params, "migration_url"); e == nil {
+		conds, args = AddParam("migration_url", "MS.MIGRATION_URL", a.Params, conds, args)
+	}
+	if _, e := getSingleValue(a.Params, "dataset_pattern"); e == nil {
+		conds, args = AddParam("dataset_pattern", "MS.DATASET_PATTERN", a.Params, conds, args)
+	}
+	if _, e := getSingleValue(a.Params, "create_by"); e == nil {
+		conds, args = AddParam("create_by", "MS.CREATE_BY", a.Params, conds, args)
+	}
+
+	// get SQL statement from static area
+	stm, err := LoadTemplateSQL("migration_subscriptions", tmpl)
+	if err != nil {
+		return Error(err, LoadErrorCode, "", "dbs.migration_subscriptions.StatusSubscription")
+	}
+	stm = WhereClause(stm, conds)
+
+	// use generic query API to fetch the results from DB
+	err = executeAll(DB, a.Writer, a.Separator, stm, args...)
+	if err != nil {
+		return Error(err, QueryErrorCode, "", "dbs.migration_subscriptions.StatusSubscription")
+	}
+	return nil
+}
+
+// PauseSubscription DBS API pauses given migration subscription
+func (a *API) PauseSubscription() error {
+	return a.updateSubscriptionStatus(SUBSCRIPTION_PAUSED)
+}
+
+// ResumeSubscription DBS API resumes given migration subscription
+func (a *API) ResumeSubscription() error {
+	return a.updateSubscriptionStatus(SUBSCRIPTION_ACTIVE)
+}
+
+// helper function to update status of migration subscription
+func (a *API) updateSubscriptionStatus(status int64) error {
+	data, err := io.ReadAll(a.Reader)
+	if err != nil {
+		return Error(err, ReaderErrorCode, "", "dbs.migration_subscriptions.updateSubscriptionStatus")
+	}
+	var r MigrationSubscriptionRequest
+	err = json.Unmarshal(data, &r)
+	if err != nil {
+		return Error(err, UnmarshalErrorCode, "", "dbs.migration_subscriptions.updateSubscriptionStatus")
+	}
+	if r.SUBSCRIPTION_ID == 0 {
+		msg := "subscription_id is required"
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.migration_subscriptions.updateSubscriptionStatus")
+	}
+	stm := getSQL("update_migration_subscription_status")
+	stm = CleanStatement(stm)
+	tstamp := time.Now().Unix()
+	var args []interface{}
+	args = append(args, status)
+	args = append(args, a.CreateBy)
+	args = append(args, tstamp)
+	args = append(args, r.SUBSCRIPTION_ID)
+	if utils.VERBOSE > 0 {
+		utils.PrintSQL(stm, args, "execute")
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return Error(err, TransactionErrorCode, "", "dbs.migration_subscriptions.updateSubscriptionStatus")
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(stm, args...)
+	if err != nil {
+		return Error(err, UpdateErrorCode, "", "dbs.migration_subscriptions.updateSubscriptionStatus")
+	}
+	if n, e := res.RowsAffected(); e == nil && n == 0 {
+		msg := fmt.Sprintf("subscription %d does not exist", r.SUBSCRIPTION_ID)
+		return Error(RecordErr, UpdateErrorCode, msg, "dbs.migration_subscriptions.updateSubscriptionStatus")
+	}
+	err = tx.Commit()
+	if err != nil {
+		return Error(err, CommitErrorCode, "", "dbs.migration_subscriptions.updateSubscriptionStatus")
+	}
+	return nil
+}
+
+// MigrationSubscriptions fetches active migration subscriptions from migration DB
+func MigrationSubscriptions() ([]MigrationSubscription, error) {
+	var records []MigrationSubscription
+	tmpl := make(Record)
+	tmpl["Owner"] = DBOWNER
+	stm, err := LoadTemplateSQL("migration_subscriptions", tmpl)
+	if err != nil {
+		return records, Error(err, LoadErrorCode, "", "dbs.migration_subscriptions.MigrationSubscriptions")
+	}
+	cond := fmt.Sprintf(" MS.SUBSCRIPTION_STATUS = %s", placeholder("subscription_status"))
+	stm = WhereClause(stm, []string{cond})
+	args := []interface{}{SUBSCRIPTION_ACTIVE}
+
+	if MigrationDB == nil {
+		msg := "Migration DB access is closed"
+		return records, Error(DatabaseErr, DatabaseErrorCode, msg, "dbs.migration_subscriptions.MigrationSubscriptions")
+	}
+	stm = CleanStatement(stm)
+	if utils.VERBOSE > 1 {
+		utils.PrintSQL(stm, args, "execute")
+	}
+	rows, err := MigrationDB.Query(stm, args...)
+	if err != nil {
+		msg := fmt.Sprintf("fail to execute %s", stm)
+		return records, Error(err, QueryErrorCode, msg, "dbs.migration_subscriptions.MigrationSubscriptions")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r MigrationSubscription
+		var tier, atype sql.NullString
+		var lastCheck sql.NullInt64
+		err := rows.Scan(
+			&r.SUBSCRIPTION_ID,
+			&r.MIGRATION_URL,
+			&r.DATASET_PATTERN,
+			&tier,
+			&atype,
+			&r.SUBSCRIPTION_STATUS,
+			&lastCheck,
+			&r.CREATE_BY,
+			&r.CREATION_DATE,
+			&r.LAST_MODIFIED_BY,
+			&r.LAST_MODIFICATION_DATE,
+		)
+		if err != nil {
+			return records, Error(err, RowsScanErrorCode, "", "dbs.migration_subscriptions.MigrationSubscriptions")
+		}
+		r.DATA_TIER_NAME = tier.String
+		r.DATASET_ACCESS_TYPE = atype.String
+		r.LAST_CHECK_DATE = lastCheck.Int64
+		records = append(records, r)
+	}
+	if err = rows.Err(); err != nil {
+		return records, Error(err, RowsScanErrorCode, "", "dbs.migration_subscriptions.MigrationSubscriptions")
+	}
+	return records, nil
+}
+
+// helper function to fetch list of remote datasets matching given subscription
+func subscriptionDatasets(sub MigrationSubscription) ([]string, error) {
+	var out []string
+	params := url.Values{}
+	params.Set("dataset", sub.DATASET_PATTERN)
+	if sub.DATASET_ACCESS_TYPE != "" {
+		params.Set("dataset_access_type", sub.DATASET_ACCESS_TYPE)
+	}
+	if sub.DATA_TIER_NAME != "" {
+		params.Set("data_tier_name", sub.DATA_TIER_NAME)
+	}
+	rurl := fmt.Sprintf("%s/datasets?%s", sub.MIGRATION_URL, params.Encode())
+	data, err := getData(rurl)
+	if err != nil {
+		return out, Error(err, HttpRequestErrorCode, "", "dbs.migration_subscriptions.subscriptionDatasets")
+	}
+	var records []DatasetShortRecord
+	err = json.Unmarshal(data, &records)
+	if err != nil {
+		return out, Error(err, UnmarshalErrorCode, "", "dbs.migration_subscriptions.subscriptionDatasets")
+	}
+	for _, r := range records {
+		out = append(out, r.Dataset)
+	}
+	return out, nil
+}
+
+// helper function to fetch list of remote blocks of given dataset
+// created since given time stamp
+func subscriptionBlocks(rurl, dataset string, since int64) ([]Blocks, error) {
+	var records []Blocks
+	params := url.Values{}
+	params.Set("dataset", dataset)
+	params.Set("detail", "true")
+	if since > 0 {
+		params.Set("min_ldate", fmt.Sprintf("%d", since))
+	}
+	rurl = fmt.Sprintf("%s/blocks?%s", rurl, params.Encode())
+	data, err := getData(rurl)
+	if err != nil {
+		return records, Error(err, HttpRequestErrorCode, "", "dbs.migration_subscriptions.subscriptionBlocks")
+	}
+	err = json.Unmarshal(data, &records)
+	if err != nil {
+		return records, Error(err, UnmarshalErrorCode, "", "dbs.migration_subscriptions.subscriptionBlocks")
+	}
+	return records, nil
+}
+
+// helper function to update last check date of migration subscription
+func updateSubscriptionCheckDate(sid, tstamp int64) error {
+	stm := getSQL("update_migration_subscription_check")
+	stm = CleanStatement(stm)
+	tx, err := DB.Begin()
+	if err != nil {
+		return Error(err, TransactionErrorCode, "", "dbs.migration_subscriptions.updateSubscriptionCheckDate")
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(stm, tstamp, sid)
+	if err != nil {
+		return Error(err, UpdateErrorCode, "", "dbs.migration_subscriptions.updateSubscriptionCheckDate")
+	}
+	err = tx.Commit()
+	if err != nil {
+		return Error(err, CommitErrorCode, "", "dbs.migration_subscriptions.updateSubscriptionCheckDate")
+	}
+	return nil
+}
+
+// helper function to find id and status of migration request of given
+// input, unlike queuedMigrationRequest it finds requests which do not have
+// migration blocks yet, it returns zero id if input is not submitted
+func submittedMigrationRequest(input string) (int64, int64, error) {
+	stm := getSQL("submitted_migration_request")
+	var args []interface{}
+	args = append(args, input)
+	if utils.VERBOSE > 0 {
+		utils.PrintSQL(stm, args, "execute")
+	}
+	var mid, status int64
+	err := DB.QueryRow(stm, args...).Scan(&mid, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	return mid, status, nil
+}
+
+// helper function to create and start migration request for given block
+// of subscription, it returns false if block is already submitted. The
+// request which was created by previous evaluation of subscription but
+// failed to start, i.e. it is still queued, is started again.
+func subscriptionMigrationRequest(sub MigrationSubscription, block string) (bool, error) {
+	mid, status, err := submittedMigrationRequest(block)
+	if err != nil {
+		return false, Error(err, QueryErrorCode, "", "dbs.migration_subscriptions.subscriptionMigrationRequest")
+	}
+	if mid != 0 && status != QUEUED {
+		return false, nil
+	}
+	tstamp := time.Now().Unix()
+	rec := MigrationRequest{
+		MIGRATION_REQUEST_ID:   mid,
+		MIGRATION_URL:          sub.MIGRATION_URL,
+		MIGRATION_INPUT:        block,
+		MIGRATION_STATUS:       QUEUED,
+		CREATE_BY:              sub.CREATE_BY,
+		CREATION_DATE:          tstamp,
+		LAST_MODIFIED_BY:       sub.CREATE_BY,
+		LAST_MODIFICATION_DATE: tstamp,
+	}
+	if mid == 0 {
+		tx, err := DB.Begin()
+		if err != nil {
+			return false, Error(err, TransactionErrorCode, "", "dbs.migration_subscriptions.subscriptionMigrationRequest")
+		}
+		defer tx.Rollback()
+		err = rec.Insert(tx)
+		if err != nil {
+			return false, Error(err, InsertErrorCode, "", "dbs.migration_subscriptions.subscriptionMigrationRequest")
+		}
+		err = tx.Commit()
+		if err != nil {
+			return false, Error(err, CommitErrorCode, "", "dbs.migration_subscriptions.subscriptionMigrationRequest")
+		}
+		updateMigrationStatusMetrics(rec, QUEUED)
+		atomic.AddUint64(&TotalSubscriptionRequests, 1)
+	}
+	// start migration request synchronously, i.e. its migration blocks are
+	// registered before check date of subscription is advanced
+	_, err = startMigrationRequest(rec)
+	if err != nil {
+		return false, Error(err, MigrationErrorCode, "", "dbs.migration_subscriptions.subscriptionMigrationRequest")
+	}
+	return true, nil
+}
+
+// ProcessSubscription evaluates given migration subscription against remote
+// DBS and creates migration requests for new blocks. It returns number of
+// created migration requests.
+func ProcessSubscription(sub MigrationSubscription) (int, error) {
+	var count int
+	var failed []string
+	// check date is taken from creation dates of remote blocks, i.e. it
+	// does not depend on clocks of local and remote servers
+	lastCheck := sub.LAST_CHECK_DATE
+	var failedDate int64
+	datasets, err := subscriptionDatasets(sub)
+	if err != nil {
+		return count, err
+	}
+	for _, dataset := range datasets {
+		blocks, err := subscriptionBlocks(sub.MIGRATION_URL, dataset, sub.LAST_CHECK_DATE)
+		if err != nil {
+			return count, err
+		}
+		for _, blk := range blocks {
+			created, err := subscriptionMigrationRequest(sub, blk.BLOCK_NAME)
+			if err != nil {
+				log.Printf("fail to submit block %s of subscription %d, %v", blk.BLOCK_NAME, sub.SUBSCRIPTION_ID, err)
+				failed = append(failed, blk.BLOCK_NAME)
+				if failedDate == 0 || blk.CREATION_DATE < failedDate {
+					failedDate = blk.CREATION_DATE
+				}
+				continue
+			}
+			if blk.CREATION_DATE > lastCheck {
+				lastCheck = blk.CREATION_DATE
+			}
+			if created {
+				count += 1
+			} else if utils.VERBOSE > 1 {
+				log.Printf("skip block %s of subscription %d, it is already submitted", blk.BLOCK_NAME, sub.SUBSCRIPTION_ID)
+			}
+		}
+	}
+	// remote DBS looks-up blocks created after check date, therefore we only
+	// advance check date up to the first failed block and next evaluation
+	// retries failed blocks while already submitted blocks are skipped
+	if failedDate > 0 && failedDate-1 < lastCheck {
+		lastCheck = failedDate - 1
+	}
+	if lastCheck > sub.LAST_CHECK_DATE {
+		err = updateSubscriptionCheckDate(sub.SUBSCRIPTION_ID, lastCheck)
+		if err != nil {
+			return count, err
+		}
+	}
+	if len(failed) > 0 {
+		msg := fmt.Sprintf("fail to submit %d blocks of subscription %d", len(failed), sub.SUBSCRIPTION_ID)
+		return count, Error(errors.New(msg), MigrationErrorCode, "", "dbs.migration_subscriptions.ProcessSubscription")
+	}
+	return count, nil
+}
+
+// ProcessSubscriptions evaluates all active migration subscriptions
+func ProcessSubscriptions() error {
+	records, err := MigrationSubscriptions()
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for _, sub := range records {
+		time0 := time.Now()
+		count, err := ProcessSubscription(sub)
+		if err != nil {
+			log.Printf("fail to process subscription %+v, error %v", sub, err)
+			failed = append(failed, fmt.Sprintf("%d", sub.SUBSCRIPTION_ID))
+			continue
+		}
+		if utils.VERBOSE > 0 || count > 0 {
+			log.Printf("subscription %d created %d migration requests in %v",
+				sub.SUBSCRIPTION_ID, count, time.Since(time0))
+		}
+	}
+	if len(failed) > 0 {
+		msg := fmt.Sprintf("failed subscriptions: %s", strings.Join(failed, ","))
+		return Error(errors.New(msg), MigrationErrorCode, "", "dbs.migration_subscriptions.ProcessSubscriptions")
+	}
+	return nil
+}
@@ -0,0 +1,371 @@
+package dbs
+
+// DBS migration verification module
+//
+// After migrated block is inserted into local DBS we can verify it against
+// the source DBS instance. Both block dumps are converted into canonical
+// representation (sorted files, lumis and parentage) which is hashed and
+// compared. The comparison report contains list of differences which is
+// used to mark migration request as FAILED and it is stored along with the
+// migration request in MIGRATION_REPORTS table.
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// MigrationVerify controls if migrated blocks should be verified against source DBS
+var MigrationVerify bool
+
+// MigrationVerificationReport represents report of migrated block verification
+type MigrationVerificationReport struct {
+	Block      string   `json:"block_name"`
+	LocalHash  string   `json:"local_hash"`
+	RemoteHash string   `json:"remote_hash"`
+	Match      bool     `json:"match"`
+	Diffs      []string `json:"diffs"`
+}
+
+// String provides string representation of verification report
+func (r *MigrationVerificationReport) String() string {
+	if r.Match {
+		return fmt.Sprintf("block %s verified, hash %s", r.Block, r.LocalHash)
+	}
+	return fmt.Sprintf(
+		"block %s verification failed, local hash %s remote hash %s, differences: %s",
+		r.Block, r.LocalHash, r.RemoteHash, strings.Join(r.Diffs, "; "))
+}
+
+// canonicalFile represents file attributes used for block comparison
+type canonicalFile struct {
+	LogicalFileName string   `json:"logical_file_name"`
+	CheckSum        string   `json:"check_sum"`
+	Adler32         string   `json:"adler32"`
+	MD5             string   `json:"md5"`
+	FileSize        int64    `json:"file_size"`
+	EventCount      int64    `json:"event_count"`
+	Lumis           []string `json:"lumis"`
+}
+
+// canonicalBlock represents block attributes used for block comparison
+type canonicalBlock struct {
+	Block          string          `json:"block_name"`
+	Files          []canonicalFile `json:"files"`
+	FileParents    []string        `json:"file_parents"`
+	BlockParents   []string        `json:"block_parents"`
+	DatasetParents []string        `json:"dataset_parents"`
+}
+
+// helper function to build canonical block representation of BulkBlocks record
+func canonicalBlockDump(rec BulkBlocks) canonicalBlock {
+	cblk := canonicalBlock{Block: rec.Block.BlockName}
+	for _, f := range rec.Files {
+		lumis := []string{}
+		for _, l := range f.FileLumiList {
+			lumis = append(lumis, fmt.Sprintf("%d:%d:%d", l.RunNumber, l.LumiSectionNumber, l.EventCount))
+		}
+		sort.Strings(lumis)
+		cblk.Files = append(cblk.Files, canonicalFile{
+			LogicalFileName: f.LogicalFileName,
+			CheckSum:        f.CheckSum,
+			Adler32:         f.Adler32,
+			MD5:             f.MD5,
+			FileSize:        f.FileSize,
+			EventCount:      f.EventCount,
+			Lumis:           lumis,
+		})
+	}
+	sort.Slice(cblk.Files, func(i, j int) bool {
+		return cblk.Files[i].LogicalFileName < cblk.Files[j].LogicalFileName
+	})
+	cblk.FileParents = []string{}
+	for _, r := range rec.FileParentList {
+		lfn := r.ThisLogicalFileName
+		if lfn == "" {
+			lfn = r.LogicalFileName
+		}
+		cblk.FileParents = append(cblk.FileParents, fmt.Sprintf("%s -> %s", lfn, r.ParentLogicalFileName))
+	}
+	cblk.FileParents = utils.OrderedSet(cblk.FileParents)
+	cblk.BlockParents = []string{}
+	for _, r := range rec.BlockParentList {
+		cblk.BlockParents = append(cblk.BlockParents, r.ParentBlockName)
+	}
+	cblk.BlockParents = utils.OrderedSet(cblk.BlockParents)
+	cblk.DatasetParents = []string{}
+	for _, d := range rec.DatasetParentList {
+		cblk.DatasetParents = append(cblk.DatasetParents, d)
+	}
+	for _, r := range rec.DsParentList {
+		cblk.DatasetParents = append(cblk.DatasetParents, r.ParentDataset)
+	}
+	cblk.DatasetParents = utils.OrderedSet(cblk.DatasetParents)
+	return cblk
+}
+
+// BlockDumpHash provides canonical hash of given BulkBlocks record.
+// The hash only depends on files, checksums, lumis and parentage of the block
+// and does not depend on order of records or creation/modification dates.
+func BlockDumpHash(rec BulkBlocks) (string, error) {
+	data, err := json.Marshal(canonicalBlockDump(rec))
+	if err != nil {
+		return "", Error(err, MarshalErrorCode, "", "dbs.migration_verify.BlockDumpHash")
+	}
+	return utils.GetHash(data), nil
+}
+
+// helper function to report differences between two ordered lists
+func listDiffs(name string, local, remote []string) []string {
+	var diffs []string
+	for _, v := range remote {
+		if !utils.InList(v, local) {
+			diffs = append(diffs, fmt.Sprintf("missing %s %s", name, v))
+		}
+	}
+	for _, v := range local {
+		if !utils.InList(v, remote) {
+			diffs = append(diffs, fmt.Sprintf("extra %s %s", name, v))
+		}
+	}
+	return diffs
+}
+
+// CompareBlockDumps compares local and remote BulkBlocks records and
+// returns verification report
+func CompareBlockDumps(local, remote BulkBlocks) (MigrationVerificationReport, error) {
+	report := MigrationVerificationReport{Block: remote.Block.BlockName}
+	lhash, err := BlockDumpHash(local)
+	if err != nil {
+		return report, err
+	}
+	rhash, err := BlockDumpHash(remote)
+	if err != nil {
+		return report, err
+	}
+	report.LocalHash = lhash
+	report.RemoteHash = rhash
+	report.Match = lhash == rhash
+	if report.Match {
+		return report, nil
+	}
+
+	// find out differences between local and remote blocks
+	lblk := canonicalBlockDump(local)
+	rblk := canonicalBlockDump(remote)
+	if lblk.Block != rblk.Block {
+		report.Diffs = append(report.Diffs,
+			fmt.Sprintf("block name mismatch local=%s remote=%s", lblk.Block, rblk.Block))
+	}
+	lfiles := make(map[string]canonicalFile)
+	for _, f := range lblk.Files {
+		lfiles[f.LogicalFileName] = f
+	}
+	rfiles := make(map[string]canonicalFile)
+	for _, f := range rblk.Files {
+		rfiles[f.LogicalFileName] = f
+	}
+	for _, rf := range rblk.Files {
+		lf, ok := lfiles[rf.LogicalFileName]
+		if !ok {
+			report.Diffs = append(report.Diffs, fmt.Sprintf("missing file %s", rf.LogicalFileName))
+			continue
+		}
+		if lf.CheckSum != rf.CheckSum || lf.Adler32 != rf.Adler32 || lf.MD5 != rf.MD5 {
+			report.Diffs = append(report.Diffs,
+				fmt.Sprintf("checksum mismatch for file %s", rf.LogicalFileName))
+		}
+		if lf.FileSize != rf.FileSize || lf.EventCount != rf.EventCount {
+			report.Diffs = append(report.Diffs,
+				fmt.Sprintf("size or event count mismatch for file %s", rf.LogicalFileName))
+		}
+		if !utils.Equal(lf.Lumis, rf.Lumis) {
+			report.Diffs = append(report.Diffs,
+				fmt.Sprintf("lumi mismatch for file %s, local %d lumis, remote %d lumis",
+					rf.LogicalFileName, len(lf.Lumis), len(rf.Lumis)))
+		}
+	}
+	for _, lf := range lblk.Files {
+		if _, ok := rfiles[lf.LogicalFileName]; !ok {
+			report.Diffs = append(report.Diffs, fmt.Sprintf("extra file %s", lf.LogicalFileName))
+		}
+	}
+	report.Diffs = append(report.Diffs, listDiffs("file parent", lblk.FileParents, rblk.FileParents)...)
+	report.Diffs = append(report.Diffs, listDiffs("block parent", lblk.BlockParents, rblk.BlockParents)...)
+	report.Diffs = append(report.Diffs, listDiffs("dataset parent", lblk.DatasetParents, rblk.DatasetParents)...)
+	return report, nil
+}
+
+// helper function to get local block dump record for given block name
+func localBlockDump(block string) (BulkBlocks, error) {
+	var rec BulkBlocks
+	params := make(Record)
+	params["block_name"] = block
+	writer := utils.NewBufferWriter()
+	api := &API{
+		Params: params,
+		Writer: writer,
+		Api:    "blockdump",
+	}
+	err := api.BlockDump()
+	if err != nil {
+		return rec, Error(err, QueryErrorCode, "", "dbs.migration_verify.localBlockDump")
+	}
+	err = json.Unmarshal(writer.Bytes(), &rec)
+	if err != nil {
+		return rec, Error(err, UnmarshalErrorCode, "", "dbs.migration_verify.localBlockDump")
+	}
+	return rec, nil
+}
+
+// helper function to get remote block dump record for given url and block name
+func remoteBlockDump(rurl, block string) (BulkBlocks, error) {
+	var rec BulkBlocks
+	rurl = fmt.Sprintf("%s/blockdump?block_name=%s", rurl, url.QueryEscape(block))
+	data, err := getData(rurl)
+	if err != nil {
+		return rec, Error(err, HttpRequestErrorCode, "", "dbs.migration_verify.remoteBlockDump")
+	}
+	err = json.Unmarshal(data, &rec)
+	if err != nil {
+		return rec, Error(err, UnmarshalErrorCode, "", "dbs.migration_verify.remoteBlockDump")
+	}
+	return rec, nil
+}
+
+// verifyBlockDump compares given remote block dump with block dump
+// of local DBS instance
+func verifyBlockDump(remote BulkBlocks) (MigrationVerificationReport, error) {
+	block := remote.Block.BlockName
+	local, err := localBlockDump(block)
+	if err != nil {
+		report := MigrationVerificationReport{Block: block}
+		return report, Error(err, MigrationErrorCode, "", "dbs.migration_verify.verifyBlockDump")
+	}
+	report, err := CompareBlockDumps(local, remote)
+	if err != nil {
+		return report, Error(err, MigrationErrorCode, "", "dbs.migration_verify.verifyBlockDump")
+	}
+	if utils.VERBOSE > 0 {
+		log.Println(report.String())
+	}
+	return report, nil
+}
+
+// helper function to find migration url of given block from migration requests
+func migrationURL(block string) (string, error) {
+	var rurl string
+	stm := fmt.Sprintf(
+		"SELECT MR.MIGRATION_URL FROM %s.MIGRATION_REQUESTS MR WHERE MR.MIGRATION_INPUT = %s",
+		DBOWNER, placeholder("migration_input"))
+	if DBOWNER == "sqlite" {
+		stm = "SELECT MR.MIGRATION_URL FROM MIGRATION_REQUESTS MR WHERE MR.MIGRATION_INPUT = ?"
+	}
+	if utils.VERBOSE > 1 {
+		utils.PrintSQL(stm, []interface{}{block}, "execute")
+	}
+	err := DB.QueryRow(stm, block).Scan(&rurl)
+	if err != nil {
+		msg := fmt.Sprintf("unable to find migration url for %s", block)
+		return rurl, Error(err, QueryErrorCode, msg, "dbs.migration_verify.migrationURL")
+	}
+	return rurl, nil
+}
+
+// VerifyMigration DBS API compares local block with the block from
+// source DBS instance. It accepts block_name parameter, the source DBS is
+// taken from migration request of given block, i.e. the API does not fetch
+// data from arbitrary URLs.
+func (a *API) VerifyMigration() error {
+	block, err := getSingleValue(a.Params, "block_name")
+	if err != nil {
+		return Error(err, ParametersErrorCode, "", "dbs.migration_verify.VerifyMigration")
+	}
+	if err := checkBlockHash(block); err != nil {
+		return Error(err, ParametersErrorCode, "", "dbs.migration_verify.VerifyMigration")
+	}
+	rurl, err := migrationURL(block)
+	if err != nil {
+		return Error(err, MigrationErrorCode, "", "dbs.migration_verify.VerifyMigration")
+	}
+	remote, err := remoteBlockDump(rurl, block)
+	if err != nil {
+		return Error(err, MigrationErrorCode, "", "dbs.migration_verify.VerifyMigration")
+	}
+	report, err := verifyBlockDump(remote)
+	if err != nil {
+		return Error(err, MigrationErrorCode, "", "dbs.migration_verify.VerifyMigration")
+	}
+	data, err := json.Marshal([]MigrationVerificationReport{report})
+	if err != nil {
+		return Error(err, MarshalErrorCode, "", "dbs.migration_verify.VerifyMigration")
+	}
+	a.Writer.Write(data)
+	return nil
+}
+
+// helper function to verify migrated block and return final migration status
+// along with verification report message. The report is stored in
+// MIGRATION_REPORTS table along with migration request. Since the block is
+// already injected, failed verification terminates migration request, i.e.
+// it is not retried by migration server.
+func verifiedMigrationStatus(mid int64, remote BulkBlocks) (int, string) {
+	if !MigrationVerify {
+		return COMPLETED, ""
+	}
+	status := COMPLETED
+	var msg string
+	report, err := verifyBlockDump(remote)
+	if err != nil {
+		msg = fmt.Sprintf("migration request %d verification error %v", mid, err)
+		log.Println(msg)
+		status = TERM_FAILED
+	} else {
+		msg = report.String()
+		if !report.Match {
+			log.Printf("migration request %d %s", mid, msg)
+			status = TERM_FAILED
+		}
+	}
+	if err := insertMigrationReport(mid, msg); err != nil {
+		log.Printf("unable to store report of migration request %d, error %v", mid, err)
+	}
+	return status, msg
+}
+
+// helper function to store verification report of migration request
+func insertMigrationReport(mid int64, report string) error {
+	// MIGRATION_REPORT column holds up to 4000 characters, the report is
+	// truncated on UTF-8 character boundary
+	if len(report) > 4000 {
+		idx := 3996
+		for idx > 0 && !utf8.RuneStart(report[idx]) {
+			idx--
+		}
+		report = report[:idx] + " ..."
+	}
+	tx, err := DB.Begin()
+	if err != nil {
+		return Error(err, TransactionErrorCode, "", "dbs.migration_verify.insertMigrationReport")
+	}
+	defer tx.Rollback()
+	stm := getSQL("insert_migration_reports")
+	args := []interface{}{mid, report, time.Now().Unix()}
+	if utils.VERBOSE > 0 {
+		utils.PrintSQL(stm, args, "execute")
+	}
+	if _, err := tx.Exec(stm, args...); err != nil {
+		return Error(err, InsertErrorCode, "", "dbs.migration_verify.insertMigrationReport")
+	}
+	if err := tx.Commit(); err != nil {
+		return Error(err, CommitErrorCode, "", "dbs.migration_verify.insertMigrationReport")
+	}
+	return nil
+}
@@ -0,0 +1,375 @@
+package dbs
+
+// DBS query options module
+//
+// Reader APIs may be called with the following query options:
+// - fields parameter defines the list of columns to return, e.g.
+//   /files?dataset=/a/b/c&fields=logical_file_name,file_size
+// - order_by parameter defines the column to sort results by, e.g.
+//   /files?dataset=/a/b/c&order_by=file_size:desc
+// - count parameter requests only number of matching records, e.g.
+//   /files?dataset=/a/b/c&count=true
+// - consistency parameter defines if data should be read from primary DB
+//   (strong) or may be read from DB replicas (eventual, default), e.g.
+//   /files?dataset=/a/b/c&consistency=strong
+// The requested fields and sortable columns are validated against API
+// fields and order_by lists defined in API parameters file and applied to
+// SQL statement of the API.
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// ParseOptions parses query options of the API, i.e. fields, order_by,
+// count and consistency parameters, and removes them from API parameters
+func (a *API) ParseOptions() error {
+	if err := a.parseFields(); err != nil {
+		return err
+	}
+	if err := a.parseOrderBy(); err != nil {
+		return err
+	}
+	if err := a.parseCount(); err != nil {
+		return err
+	}
+	return a.parseConsistency()
+}
+
+// briefFields defines output fields of APIs which are provided without
+// detail parameter
+var briefFields = map[string][]string{
+	"datasets": {"dataset"},
+	"blocks":   {"block_name"},
+	"files":    {"logical_file_name"},
+}
+
+// helper function to enable detail parameter of the API if it is supported
+// and some of given fields are provided only by detailed API output
+func (a *API) enableDetail(fields ...string) {
+	params, ok := ApiParamMap[a.Api]
+	if !ok || !utils.InList("detail", params) {
+		return
+	}
+	for _, f := range fields {
+		if !utils.InList(f, briefFields[a.Api]) {
+			a.Params["detail"] = []string{"true"}
+			return
+		}
+	}
+}
+
+// helper function to parse fields parameter of the API, it validates
+// requested fields against API fields and sets API Fields attribute. The
+// detail parameter is enabled if requested fields are part of detailed API
+// output only.
+func (a *API) parseFields() error {
+	var fields []string
+	for _, val := range getValues(a.Params, "fields") {
+		for _, f := range strings.Split(val, ",") {
+			f = strings.ToLower(strings.TrimSpace(f))
+			if f != "" && !utils.InList(f, fields) {
+				fields = append(fields, f)
+			}
+		}
+	}
+	delete(a.Params, "fields")
+	if len(fields) == 0 {
+		return nil
+	}
+	_, apiFields, err := GetApiParameters(a.Api)
+	if err != nil {
+		return Error(err, LoadErrorCode, "", "dbs.options.parseFields")
+	}
+	for _, f := range fields {
+		if !utils.InList(f, apiFields) {
+			msg := fmt.Sprintf("field '%s' is not provided by '%s' API, allowed fields: %v", f, a.Api, apiFields)
+			return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.options.parseFields")
+		}
+	}
+	a.Fields = fields
+	a.enableDetail(fields...)
+	return nil
+}
+
+// helper function to parse order_by parameter of the API, the parameter has
+// <field>[:asc|:desc] form and field should be sortable by the API. The
+// detail parameter is enabled if field is part of detailed API output only.
+func (a *API) parseOrderBy() error {
+	values := getValues(a.Params, "order_by")
+	delete(a.Params, "order_by")
+	if len(values) == 0 {
+		return nil
+	}
+	if len(values) > 1 {
+		msg := "order_by parameter accepts single field"
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.options.parseOrderBy")
+	}
+	arr := strings.Split(strings.ToLower(strings.TrimSpace(values[0])), ":")
+	field := strings.TrimSpace(arr[0])
+	if len(arr) > 2 || (len(arr) == 2 && arr[1] != "asc" && arr[1] != "desc") {
+		msg := fmt.Sprintf("invalid order_by value '%s', should be <field>[:asc|:desc]", values[0])
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.options.parseOrderBy")
+	}
+	sortable, err := GetApiOrderBy(a.Api)
+	if err != nil {
+		return Error(err, LoadErrorCode, "", "dbs.options.parseOrderBy")
+	}
+	if !utils.InList(field, sortable) {
+		msg := fmt.Sprintf("'%s' API can not be ordered by '%s', allowed fields: %v", a.Api, field, sortable)
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.options.parseOrderBy")
+	}
+	a.OrderBy = field
+	a.Descending = len(arr) == 2 && arr[1] == "desc"
+	a.enableDetail(field)
+	return nil
+}
+
+// helper function to parse count parameter of the API
+func (a *API) parseCount() error {
+	values := getValues(a.Params, "count")
+	delete(a.Params, "count")
+	if len(values) == 0 {
+		return nil
+	}
+	switch strings.ToLower(values[0]) {
+	case "true", "1":
+		a.Count = true
+	case "false", "0":
+		a.Count = false
+	default:
+		msg := fmt.Sprintf("invalid count value '%s', should be true or false", values[0])
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.options.parseCount")
+	}
+	return nil
+}
+
+// helper function to parse consistency parameter of the API
+func (a *API) parseConsistency() error {
+	values := getValues(a.Params, "consistency")
+	delete(a.Params, "consistency")
+	if len(values) == 0 {
+		return nil
+	}
+	switch strings.ToLower(values[0]) {
+	case "strong":
+		a.Strong = true
+	case "eventual":
+		a.Strong = false
+	default:
+		msg := fmt.Sprintf("invalid consistency value '%s', should be strong or eventual", values[0])
+		return Error(InvalidParamErr, ParametersErrorCode, msg, "dbs.options.parseConsistency")
+	}
+	return nil
+}
+
+// helper function to apply API query options to given SQL statement.
+// The requested fields are selected directly from API statement if its
+// select list can be projected, otherwise and for sorted (order_by),
+// counted (count) or limited (downgraded query) output the API statement is
+// used as inline view. Such form is supported by both ORACLE and SQLite
+// back-ends.
+func (a *API) statement(stm string) string {
+	if len(a.Fields) == 0 && a.OrderBy == "" && !a.Count && a.Limit == 0 {
+		return stm
+	}
+	stm = CleanStatement(stm)
+	if a.Count {
+		return fmt.Sprintf("SELECT COUNT(*) AS COUNT FROM (\n%s\n) CNT", stm)
+	}
+	cols := "PRJ.*"
+	if len(a.Fields) > 0 {
+		// sorting column should be part of projected statement
+		if a.OrderBy == "" || utils.InList(a.OrderBy, a.Fields) {
+			if pstm, ok := projectStatement(stm, a.Fields); ok {
+				if a.OrderBy == "" && a.Limit == 0 {
+					return pstm
+				}
+				stm = pstm
+			} else {
+				cols = projectColumns(a.Fields)
+			}
+		} else {
+			cols = projectColumns(a.Fields)
+		}
+	}
+	stm = fmt.Sprintf("SELECT %s FROM (\n%s\n) PRJ", cols, stm)
+	if a.OrderBy != "" {
+		// ORACLE and SQLite put NULL values differently, therefore we
+		// explicitly put them last
+		order := "ASC"
+		if a.Descending {
+			order = "DESC"
+		}
+		stm = fmt.Sprintf("%s\nORDER BY PRJ.%s %s NULLS LAST", stm, strings.ToUpper(a.OrderBy), order)
+	}
+	if a.Limit > 0 {
+		// ORACLE supports row limiting clause since 12c
+		if DBOWNER == "sqlite" {
+			stm = fmt.Sprintf("%s\nLIMIT %d", stm, a.Limit)
+		} else {
+			stm = fmt.Sprintf("%s\nFETCH FIRST %d ROWS ONLY", stm, a.Limit)
+		}
+	}
+	return stm
+}
+
+// helper function to get select list of given fields of inline view
+func projectColumns(fields []string) string {
+	var cols []string
+	for _, f := range fields {
+		cols = append(cols, fmt.Sprintf("PRJ.%s", strings.ToUpper(f)))
+	}
+	return strings.Join(cols, ", ")
+}
+
+// helper function to project given SQL statement to given fields, i.e. to
+// replace its select list with columns of requested fields. It returns false
+// if statement can't be projected, e.g. it is compound query or some field
+// is not part of its select list.
+func projectStatement(stm string, fields []string) (string, bool) {
+	// find positions of top level SELECT and FROM keywords
+	var selects, froms []int
+	var depth int
+	var quoted bool
+	upper := strings.ToUpper(stm)
+	isKeyword := func(i int, kwd string) bool {
+		if !strings.HasPrefix(upper[i:], kwd) {
+			return false
+		}
+		if i > 0 && isIdentChar(upper[i-1]) {
+			return false
+		}
+		j := i + len(kwd)
+		return j == len(upper) || !isIdentChar(upper[j])
+	}
+	for i := 0; i < len(upper); i++ {
+		switch c := upper[i]; {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && isKeyword(i, "SELECT"):
+			selects = append(selects, i)
+		case depth == 0 && isKeyword(i, "FROM"):
+			froms = append(froms, i)
+		}
+	}
+	if len(selects) != 1 || len(froms) == 0 || froms[0] < selects[0] {
+		return stm, false
+	}
+	start := selects[0] + len("SELECT")
+	end := froms[0]
+	list := strings.TrimSpace(stm[start:end])
+	// projection of distinct rows would change number of output records
+	if words := strings.Fields(strings.ToUpper(list)); len(words) > 0 && words[0] == "DISTINCT" {
+		return stm, false
+	}
+
+	// split select list into columns and find their output names
+	items := make(map[string]string)
+	for _, item := range splitSelectList(list) {
+		items[selectItemName(item)] = item
+	}
+	var cols []string
+	for _, f := range fields {
+		item, ok := items[strings.ToUpper(f)]
+		if !ok {
+			return stm, false
+		}
+		cols = append(cols, item)
+	}
+	out := stm[:selects[0]] + "SELECT " + strings.Join(cols, ", ") + "\n" + stm[end:]
+	return out, true
+}
+
+// helper function to check if given character is part of SQL identifier
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '.' || c == '$' || c == '#' ||
+		(c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
+
+// helper function to split select list by top level commas
+func splitSelectList(list string) []string {
+	var items []string
+	var depth, pos int
+	var quoted bool
+	for i := 0; i < len(list); i++ {
+		switch c := list[i]; {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(list[pos:i]))
+			pos = i + 1
+		}
+	}
+	return append(items, strings.TrimSpace(list[pos:]))
+}
+
+// helper function to get output name of select list item, i.e. its alias
+// or column name
+func selectItemName(item string) string {
+	arr := strings.Fields(strings.ToUpper(item))
+	if len(arr) == 0 {
+		return ""
+	}
+	name := strings.Trim(arr[len(arr)-1], `"`)
+	if len(arr) == 1 {
+		if idx := strings.LastIndex(name, "."); idx != -1 {
+			name = name[idx+1:]
+		}
+	} else if strings.ContainsAny(arr[len(arr)-2], "+-*/|") {
+		// expression without alias
+		return ""
+	}
+	for i := 0; i < len(name); i++ {
+		if !isIdentChar(name[i]) || name[i] == '.' {
+			return ""
+		}
+	}
+	return name
+}
+
+// helper function to apply API query options to explicit set of columns
+// and their values used by execute function
+func (a *API) columns(cols []string, vals []interface{}) ([]string, []interface{}) {
+	if a.Count {
+		return []string{"count"}, []interface{}{new(sql.NullInt64)}
+	}
+	if len(a.Fields) == 0 {
+		return cols, vals
+	}
+	var pcols []string
+	var pvals []interface{}
+	for _, f := range a.Fields {
+		for i, c := range cols {
+			if c == f {
+				pcols = append(pcols, c)
+				pvals = append(pvals, vals[i])
+				break
+			}
+		}
+	}
+	return pcols, pvals
+}
+
+// helper function to execute given SQL statement of reader API, it applies
+// API query options and writes results to API writer
+func (a *API) query(stm string, args ...interface{}) error {
+	db := a.readerDB()
+	if err := a.checkCost(a.statement(stm), args...); err != nil {
+		return err
+	}
+	return executeAll(db, a.Writer, a.Separator, a.statement(stm), args...)
+}
@@ -54,7 +54,7 @@ func (a *API) OutputConfigs() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	err = executeAll(a.Writer, a.Separator, stm, args...)
+	err = a.query(stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.outputconfigs.OutputConfigs")
 	}
@@ -336,6 +336,7 @@ func (a *API) InsertOutputConfigs() error {
 		log.Println("unable to commit transaction", err)
 		return Error(err, CommitErrorCode, "", "dbs.outputconfigs.InsertOutputConfigs")
 	}
+	InvalidateCache("OUTPUT_MODULE_CONFIGS", "RELEASE_VERSIONS", "PARAMETER_SET_HASHES", "APPLICATION_EXECUTABLES")
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
@@ -17,6 +17,9 @@ var ApiParametersFile string
 type ApiParameters struct {
 	Api        string
 	Parameters []string
+	Fields     []string
+	OrderBy    []string `json:"order_by"`
+	Selective  []string
 }
 
 // ApiParametersMap represents data type of api parameters
@@ -25,8 +28,23 @@ type ApiParametersMap map[string][]string
 // ApiParamMap an object which holds API parameter records
 var ApiParamMap ApiParametersMap
 
-// LoadApiParameters loads Api parameters and constructs ApiParameters map
-func LoadApiParameters(fname string) (ApiParametersMap, error) {
+// CommonParameters represents parameters accepted by all GET APIs
+var CommonParameters = []string{"format", "consistency"}
+
+// ApiFieldsMap represents data type of api fields
+type ApiFieldsMap map[string][]string
+
+// ApiFieldMap an object which holds API fields records
+var ApiFieldMap ApiFieldsMap
+
+// ApiOrderByMap an object which holds API sortable fields records
+var ApiOrderByMap ApiFieldsMap
+
+// ApiSelectiveMap an object which holds API selective parameters records
+var ApiSelectiveMap ApiFieldsMap
+
+// helper function to read API parameters records from given file
+func readApiParameters(fname string) ([]ApiParameters, error) {
 	data, err := ioutil.ReadFile(fname)
 	if err != nil {
 		log.Printf("Unable to read, file '%s', error: %v\n", fname, err)
@@ -38,6 +56,15 @@ func LoadApiParameters(fname string) (ApiParametersMap, error) {
 		log.Printf("Unable to parse, file '%s', error: %v\n", fname, err)
 		return nil, Error(err, UnmarshalErrorCode, "", "dbs.parameters.LoadParameters")
 	}
+	return records, nil
+}
+
+// LoadApiParameters loads Api parameters and constructs ApiParameters map
+func LoadApiParameters(fname string) (ApiParametersMap, error) {
+	records, err := readApiParameters(fname)
+	if err != nil {
+		return nil, err
+	}
 	pmap := make(ApiParametersMap)
 	for _, rec := range records {
 		pmap[rec.Api] = rec.Parameters
@@ -45,6 +72,106 @@ func LoadApiParameters(fname string) (ApiParametersMap, error) {
 	return pmap, nil
 }
 
+// LoadApiFields loads Api fields and constructs ApiFields map
+func LoadApiFields(fname string) (ApiFieldsMap, error) {
+	records, err := readApiParameters(fname)
+	if err != nil {
+		return nil, err
+	}
+	fmap := make(ApiFieldsMap)
+	for _, rec := range records {
+		if len(rec.Fields) > 0 {
+			fmap[rec.Api] = rec.Fields
+		}
+	}
+	return fmap, nil
+}
+
+// LoadApiOrderBy loads Api sortable fields and constructs ApiFields map
+func LoadApiOrderBy(fname string) (ApiFieldsMap, error) {
+	records, err := readApiParameters(fname)
+	if err != nil {
+		return nil, err
+	}
+	omap := make(ApiFieldsMap)
+	for _, rec := range records {
+		if len(rec.OrderBy) > 0 {
+			omap[rec.Api] = rec.OrderBy
+		}
+	}
+	return omap, nil
+}
+
+// LoadApiSelective loads Api selective parameters and constructs ApiFields map
+func LoadApiSelective(fname string) (ApiFieldsMap, error) {
+	records, err := readApiParameters(fname)
+	if err != nil {
+		return nil, err
+	}
+	smap := make(ApiFieldsMap)
+	for _, rec := range records {
+		if len(rec.Selective) > 0 {
+			smap[rec.Api] = rec.Selective
+		}
+	}
+	return smap, nil
+}
+
+// helper function to load API parameters and fields maps
+func loadApiParameters() error {
+	var err error
+	if ApiParamMap == nil {
+		log.Println("loading", ApiParametersFile)
+		ApiParamMap, err = LoadApiParameters(ApiParametersFile)
+		if err != nil {
+			return Error(GenericErr, LoadErrorCode, "", "dbs.parameters.CheckQueryParameters")
+		}
+	}
+	if ApiFieldMap == nil {
+		ApiFieldMap, err = LoadApiFields(ApiParametersFile)
+		if err != nil {
+			return Error(GenericErr, LoadErrorCode, "", "dbs.parameters.CheckQueryParameters")
+		}
+	}
+	if ApiOrderByMap == nil {
+		ApiOrderByMap, err = LoadApiOrderBy(ApiParametersFile)
+		if err != nil {
+			return Error(GenericErr, LoadErrorCode, "", "dbs.parameters.CheckQueryParameters")
+		}
+	}
+	if ApiSelectiveMap == nil {
+		ApiSelectiveMap, err = LoadApiSelective(ApiParametersFile)
+		if err != nil {
+			return Error(GenericErr, LoadErrorCode, "", "dbs.parameters.CheckQueryParameters")
+		}
+	}
+	return nil
+}
+
+// GetApiParameters returns list of parameters and fields of given API
+func GetApiParameters(api string) ([]string, []string, error) {
+	if err := loadApiParameters(); err != nil {
+		return nil, nil, err
+	}
+	return ApiParamMap[api], ApiFieldMap[api], nil
+}
+
+// GetApiOrderBy returns list of sortable fields of given API
+func GetApiOrderBy(api string) ([]string, error) {
+	if err := loadApiParameters(); err != nil {
+		return nil, err
+	}
+	return ApiOrderByMap[api], nil
+}
+
+// GetApiSelective returns list of selective parameters of given API
+func GetApiSelective(api string) ([]string, error) {
+	if err := loadApiParameters(); err != nil {
+		return nil, err
+	}
+	return ApiSelectiveMap[api], nil
+}
+
 // CreateInvalidParamError creates the error for parameter validation
 func CreateInvalidParamError(param string, api string) error {
 	msg := fmt.Sprintf("parameter '%s' is not accepted by '%s' API", param, api)
@@ -57,15 +184,13 @@ func CreateInvalidParamError(param string, api string) error {
 
 // CheckQueryParameters checks query parameters against API parameters map
 func CheckQueryParameters(r *http.Request, api string) error {
-	var err error
-	if ApiParamMap == nil {
-		log.Println("loading", ApiParametersFile)
-		ApiParamMap, err = LoadApiParameters(ApiParametersFile)
-		if err != nil {
-			return Error(GenericErr, LoadErrorCode, "", "dbs.parameters.CheckQueryParameters")
-		}
+	if err := loadApiParameters(); err != nil {
+		return err
 	}
 	for k, _ := range r.URL.Query() {
+		if utils.InList(k, CommonParameters) {
+			continue
+		}
 		if params, ok := ApiParamMap[api]; ok {
 			if !utils.InList(k, params) {
 				return CreateInvalidParamError(k, api)
@@ -0,0 +1,230 @@
+package dbs
+
+// DBS parentage validation module
+//
+// Bulkblocks APIs insert file_parent_list of injected block as is. When
+// BulkBlocksParentageCheck is set the parentage is validated before the
+// injection: every parent file should exist in DBS, belong to one of the
+// datasets listed in dataset_parent_list (or ds_parent_list) and lumis of
+// every child file should be a subset of the union of lumis of its parents.
+// The validation runs within injection transaction and the problems are
+// reported as ParentageReport in the data of DBS error.
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// BulkBlocksParentageCheck defines how bulkblocks APIs treat inconsistent
+// parentage of injected files: "warn" logs the problems, "reject" aborts the
+// injection and empty value disables the check
+var BulkBlocksParentageCheck string
+
+// ParentageErr represents error of inconsistent parentage of injected files
+var ParentageErr = errors.New("parentage validation error")
+
+// max number of parentage problems reported by DBS error
+const maxReportedProblems = 100
+
+// ParentageProblem represents parentage problem of injected file, the reason
+// is either missing_parent, unlisted_parent_dataset or uncovered_lumis
+type ParentageProblem struct {
+	LogicalFileName       string   `json:"logical_file_name"`
+	ParentLogicalFileName string   `json:"parent_logical_file_name,omitempty"`
+	ParentDataset         string   `json:"parent_dataset,omitempty"`
+	Reason                string   `json:"reason"`
+	Lumis                 LumiMask `json:"lumis,omitempty"`
+}
+
+// ParentageReport represents outcome of parentage validation
+type ParentageReport struct {
+	ProblemCount int                `json:"problem_count"`
+	Problems     []ParentageProblem `json:"problems"`
+}
+
+// parentFile represents dataset and lumis of parent file
+type parentFile struct {
+	Dataset string
+	Lumis   map[[2]int64]bool
+}
+
+// ValidateParentage validates file parentage of given BulkBlocks record
+// against DBS within given transaction and returns list of found problems
+func ValidateParentage(tx *sql.Tx, rec BulkBlocks) ([]ParentageProblem, error) {
+	problems := []ParentageProblem{}
+	parents := make(map[string][]string)
+	var plfns []string
+	for _, r := range rec.FileParentList {
+		lfn := r.LogicalFileName
+		if lfn == "" {
+			lfn = r.ThisLogicalFileName
+		}
+		parents[lfn] = append(parents[lfn], r.ParentLogicalFileName)
+		plfns = append(plfns, r.ParentLogicalFileName)
+	}
+	if len(plfns) == 0 {
+		return problems, nil
+	}
+	plfns = utils.OrderedSet(plfns)
+	datasets := make(map[string]bool)
+	for _, d := range rec.DatasetParentList {
+		datasets[d] = true
+	}
+	for _, d := range rec.DsParentList {
+		datasets[d.ParentDataset] = true
+	}
+
+	tmpl := make(Record)
+	tmpl["Owner"] = DBOWNER
+	stm, err := LoadTemplateSQL("parentage_lumis", tmpl)
+	if err != nil {
+		return problems, Error(err, LoadErrorCode, "", "dbs.parentage.ValidateParentage")
+	}
+	pfiles := make(map[string]*parentFile)
+	for start := 0; start < len(plfns); start += FileAncestryBatchSize {
+		end := start + FileAncestryBatchSize
+		if end > len(plfns) {
+			end = len(plfns)
+		}
+		if err := parentFiles(tx, stm, plfns[start:end], pfiles); err != nil {
+			return problems, err
+		}
+	}
+
+	for _, f := range rec.Files {
+		lfn := f.LogicalFileName
+		if _, ok := parents[lfn]; !ok {
+			continue
+		}
+		lumis := make(map[[2]int64]bool)
+		complete := true
+		for _, plfn := range utils.OrderedSet(parents[lfn]) {
+			pfile, ok := pfiles[plfn]
+			if !ok {
+				problems = append(problems, ParentageProblem{
+					LogicalFileName:       lfn,
+					ParentLogicalFileName: plfn,
+					Reason:                "missing_parent",
+				})
+				complete = false
+				continue
+			}
+			if !datasets[pfile.Dataset] {
+				problems = append(problems, ParentageProblem{
+					LogicalFileName:       lfn,
+					ParentLogicalFileName: plfn,
+					ParentDataset:         pfile.Dataset,
+					Reason:                "unlisted_parent_dataset",
+				})
+			}
+			for k := range pfile.Lumis {
+				lumis[k] = true
+			}
+		}
+		// lumis can not be checked if some of parents are missing
+		if !complete {
+			continue
+		}
+		var builder lumiMaskBuilder
+		uncovered := false
+		for _, l := range f.FileLumiList {
+			if !lumis[[2]int64{l.RunNumber, l.LumiSectionNumber}] {
+				builder.Add(l.RunNumber, l.LumiSectionNumber, 0)
+				uncovered = true
+			}
+		}
+		if uncovered {
+			mask, _, _ := builder.Mask()
+			problems = append(problems, ParentageProblem{
+				LogicalFileName: lfn,
+				Reason:          "uncovered_lumis",
+				Lumis:           mask,
+			})
+		}
+	}
+	return problems, nil
+}
+
+// helper function to fetch datasets and lumis of given batch of parent files
+func parentFiles(tx *sql.Tx, stm string, lfns []string, pfiles map[string]*parentFile) error {
+	var args []interface{}
+	token, binds := TokenGenerator(lfns, 30, "lfn_token")
+	stm = fmt.Sprintf("%s %s", token, stm)
+	cond := fmt.Sprintf(" F.LOGICAL_FILE_NAME in %s", TokenCondition())
+	stm = WhereClause(stm, []string{cond})
+	for _, v := range binds {
+		args = append(args, v)
+	}
+	stm = CleanStatement(stm)
+	if utils.VERBOSE > 1 {
+		utils.PrintSQL(stm, args, "execute")
+	}
+	rows, err := tx.Query(stm, args...)
+	if err != nil {
+		msg := fmt.Sprintf("unable to query statement: %v", stm)
+		return Error(err, QueryErrorCode, msg, "dbs.parentage.parentFiles")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var lfn, dataset string
+		var run, lumi sql.NullInt64
+		if err := rows.Scan(&lfn, &dataset, &run, &lumi); err != nil {
+			return Error(err, RowsScanErrorCode, "", "dbs.parentage.parentFiles")
+		}
+		pfile, ok := pfiles[lfn]
+		if !ok {
+			pfile = &parentFile{Dataset: dataset, Lumis: make(map[[2]int64]bool)}
+			pfiles[lfn] = pfile
+		}
+		if run.Valid && lumi.Valid {
+			pfile.Lumis[[2]int64{run.Int64, lumi.Int64}] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return Error(err, RowsScanErrorCode, "", "dbs.parentage.parentFiles")
+	}
+	return nil
+}
+
+// helper function to validate parentage of files injected by bulkblocks APIs
+func checkBulkBlocksParentage(tx *sql.Tx, rec BulkBlocks, hash string) error {
+	if BulkBlocksParentageCheck == "" {
+		return nil
+	}
+	problems, err := ValidateParentage(tx, rec)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].LogicalFileName < problems[j].LogicalFileName
+	})
+	report := ParentageReport{ProblemCount: len(problems), Problems: problems}
+	if len(problems) > maxReportedProblems {
+		report.Problems = problems[:maxReportedProblems]
+	}
+	msg := fmt.Sprintf("block %s has %d parentage problems", rec.Block.BlockName, len(problems))
+	if BulkBlocksParentageCheck == "warn" {
+		data, err := json.Marshal(report)
+		if err != nil {
+			return Error(err, MarshalErrorCode, "", "dbs.parentage.checkBulkBlocksParentage")
+		}
+		log.Printf("%s WARNING %s: %s", hash, msg, string(data))
+		return nil
+	}
+	return &DBSError{
+		Reason:   ParentageErr.Error(),
+		Message:  msg,
+		Code:     ValidateErrorCode,
+		Function: "dbs.parentage.checkBulkBlocksParentage",
+		Data:     report,
+	}
+}
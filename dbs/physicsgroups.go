@@ -22,7 +22,7 @@ func (a *API) PhysicsGroups() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	err := executeAll(a.Writer, a.Separator, stm, args...)
+	err := executeAll(a.readerDB(), a.Writer, a.Separator, stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.physicsgroups.PhysicsGroups")
 	}
@@ -116,6 +116,7 @@ func (a *API) InsertPhysicsGroups() error {
 	if err != nil {
 		return Error(err, InsertErrorCode, "", "dbs.physicsgroups.InsertPhysicsGroups")
 	}
+	InvalidateCache("PHYSICS_GROUPS")
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
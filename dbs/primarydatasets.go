@@ -23,7 +23,7 @@ func (a *API) PrimaryDatasets() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	err := executeAll(a.Writer, a.Separator, stm, args...)
+	err := a.query(stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.primarydatasets.PrimaryDataset")
 	}
@@ -194,6 +194,7 @@ func (a *API) InsertPrimaryDatasets() error {
 		log.Println("fail to insert primarydatasets", err)
 		return Error(err, CommitErrorCode, "", "dbs.primarydatasets.InsertPrimaryDatasets")
 	}
+	InvalidateCache("PRIMARY_DATASETS", "PRIMARY_DS_TYPES")
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
@@ -19,7 +19,7 @@ func (a *API) PrimaryDSTypes() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	err := executeAll(a.Writer, a.Separator, stm, args...)
+	err := a.query(stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.primarydstypes.PrimaryDSTypes")
 	}
@@ -96,5 +96,6 @@ func (a *API) InsertPrimaryDSTypes() error {
 	if err != nil {
 		return Error(err, InsertErrorCode, "", "dbs.primarydstypes.InsertPrimaryDSTypes")
 	}
+	InvalidateCache("PRIMARY_DS_TYPES")
 	return nil
 }
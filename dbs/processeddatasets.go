@@ -17,7 +17,7 @@ func (a *API) ProcessedDatasets() error {
 	stm := getSQL("processed_datasets")
 
 	// use generic query API to fetch the results from DB
-	err := executeAll(a.Writer, a.Separator, stm, args...)
+	err := executeAll(a.readerDB(), a.Writer, a.Separator, stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.processeddatasets.ProcessedDatasets")
 	}
@@ -105,5 +105,6 @@ func (a *API) InsertProcessedDatasets() error {
 	if err != nil {
 		return Error(err, InsertErrorCode, "", "dbs.processeddatasets.InsertProcessedDatasets")
 	}
+	InvalidateCache("PROCESSED_DATASETS")
 	return nil
 }
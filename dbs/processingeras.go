@@ -22,7 +22,7 @@ func (a *API) ProcessingEras() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	err := executeAll(a.Writer, a.Separator, stm, args...)
+	err := a.query(stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.processingeras.ProcessingEras")
 	}
@@ -134,6 +134,7 @@ func (a *API) InsertProcessingEras() error {
 	if err != nil {
 		return Error(err, InsertErrorCode, "", "dbs.processingeras.InsertProcessingEras")
 	}
+	InvalidateCache("PROCESSING_ERAS")
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
@@ -43,7 +43,7 @@ func (a *API) ReleaseVersions() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	err = executeAll(a.Writer, a.Separator, stm, args...)
+	err = executeAll(a.readerDB(), a.Writer, a.Separator, stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.releaseversions.ReleaseVersions")
 	}
@@ -128,5 +128,6 @@ func (a *API) InsertReleaseVersions() error {
 	if err != nil {
 		return Error(err, InsertErrorCode, "", "dbs.releaseversions.InsertReleaseVersions")
 	}
+	InvalidateCache("RELEASE_VERSIONS")
 	return nil
 }
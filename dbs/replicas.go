@@ -0,0 +1,99 @@
+package dbs
+
+// DBS read replicas module
+//
+// Reader APIs may be served by read-only replicas of DBS database. The
+// replicas are used in round-robin order and their health is periodically
+// checked by CheckReplicas function with the same test query which is used
+// to monitor primary DB connection (see GetTestData). Unhealthy replicas
+// are skipped until they pass the health check again and if no replica is
+// healthy the primary DB is used. Writer and migration APIs always use
+// primary DB, as well as reader APIs called with consistency=strong
+// parameter, e.g. to read data right after it was written.
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Replica represents read-only replica of DBS database
+type Replica struct {
+	DB      *sql.DB // replica DB pointer
+	Name    string  // replica name used in logs
+	healthy int32
+}
+
+// NewReplica creates new replica for given DB pointer, the replica is
+// considered healthy until its health check fails
+func NewReplica(name string, db *sql.DB) *Replica {
+	return &Replica{DB: db, Name: name, healthy: 1}
+}
+
+// Healthy reports if replica passed its last health check
+func (r *Replica) Healthy() bool {
+	return atomic.LoadInt32(&r.healthy) == 1
+}
+
+// helper function to set replica health status
+func (r *Replica) setHealthy(healthy bool) {
+	var val int32
+	if healthy {
+		val = 1
+	}
+	if old := atomic.SwapInt32(&r.healthy, val); old != val {
+		log.Printf("replica %s healthy=%v", r.Name, healthy)
+	}
+}
+
+// Replicas represents list of DBS read replicas
+var Replicas []*Replica
+
+// replica counter used for round-robin selection of replicas
+var replicaCounter uint64
+
+// ReaderDB returns DB pointer to use for read-only queries, i.e. next
+// healthy replica in round-robin order or primary DB if there is none
+func ReaderDB() *sql.DB {
+	nrep := len(Replicas)
+	if nrep == 0 {
+		return DB
+	}
+	idx := atomic.AddUint64(&replicaCounter, 1)
+	for i := 0; i < nrep; i++ {
+		r := Replicas[(idx+uint64(i))%uint64(nrep)]
+		if r.Healthy() {
+			return r.DB
+		}
+	}
+	return DB
+}
+
+// CheckReplicas performs health check of all replicas
+func CheckReplicas() {
+	for _, r := range Replicas {
+		err := getTestData(r.DB)
+		if err != nil {
+			log.Printf("replica %s health check failed, error %v", r.Name, err)
+		}
+		r.setHealthy(err == nil)
+	}
+}
+
+// ReplicaName returns name of the replica for given DB uri which can be
+// used in logs, i.e. DB uri without user credentials
+func ReplicaName(dburi string) string {
+	if idx := strings.LastIndex(dburi, "@"); idx >= 0 {
+		return dburi[idx+1:]
+	}
+	return dburi
+}
+
+// helper function to get DB pointer of reader API queries
+func (a *API) readerDB() *sql.DB {
+	if a.Strong {
+		return DB
+	}
+	return ReaderDB()
+}
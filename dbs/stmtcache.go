@@ -0,0 +1,159 @@
+package dbs
+
+// DBS prepared statements cache module
+//
+// DBS SQL statements are rendered from templates in static area and most of
+// them are executed over and over with different bind values. The statement
+// cache keeps prepared *sql.Stmt for rendered SQL text per connection pool
+// (i.e. per *sql.DB) to avoid re-parsing of the same statements by the DB.
+// Every cache is bounded by StmtCacheSize and least recently used statements
+// are closed when cache is full. The cache is used by reader queries only,
+// the statements are prepared on DB connection pool before transaction is
+// started, since preparing them while transaction holds its connection may
+// exhaust the pool, and used within transaction via tx.Stmt which
+// re-prepares the statement on transaction connection if it was closed in a
+// meantime, therefore eviction of statement used by concurrent request is
+// safe. The cache is disabled by default.
+
+import (
+	"container/list"
+	"database/sql"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// StmtCacheSize defines max number of prepared statements kept per DB
+// connection pool, zero or negative value disables the cache
+var StmtCacheSize int
+
+// StmtCacheStats represents statistics of prepared statements caches
+type StmtCacheStats struct {
+	Hits      uint64 // number of statements found in cache
+	Misses    uint64 // number of prepared statements
+	Evictions uint64 // number of closed least recently used statements
+}
+
+// StmtCacheMetrics keeps statistics of all prepared statements caches
+var StmtCacheMetrics StmtCacheStats
+
+// stmtEntry represents cached prepared statement
+type stmtEntry struct {
+	stm  string
+	stmt *sql.Stmt
+}
+
+// stmtCache represents LRU cache of prepared statements of single DB
+type stmtCache struct {
+	mutex sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+}
+
+// stmtCaches keeps prepared statements caches of DB connection pools
+var stmtCaches = make(map[*sql.DB]*stmtCache)
+var stmtCachesMutex sync.Mutex
+
+// helper function to get statements cache of given DB
+func getStmtCache(db *sql.DB) *stmtCache {
+	stmtCachesMutex.Lock()
+	defer stmtCachesMutex.Unlock()
+	cache, ok := stmtCaches[db]
+	if !ok {
+		cache = &stmtCache{items: make(map[string]*list.Element), order: list.New()}
+		stmtCaches[db] = cache
+	}
+	return cache
+}
+
+// StmtCacheLen returns number of cached prepared statements of given DB
+func StmtCacheLen(db *sql.DB) int {
+	stmtCachesMutex.Lock()
+	cache, ok := stmtCaches[db]
+	stmtCachesMutex.Unlock()
+	if !ok {
+		return 0
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.order.Len()
+}
+
+// CloseStmtCache closes all cached prepared statements of given DB, it
+// should be called before DB connection pool is closed
+func CloseStmtCache(db *sql.DB) {
+	stmtCachesMutex.Lock()
+	cache, ok := stmtCaches[db]
+	delete(stmtCaches, db)
+	stmtCachesMutex.Unlock()
+	if !ok {
+		return
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for _, elem := range cache.items {
+		elem.Value.(*stmtEntry).stmt.Close()
+	}
+	cache.items = make(map[string]*list.Element)
+	cache.order.Init()
+}
+
+// helper function to get prepared statement of given SQL statement from DB
+// cache, it returns nil if cache is disabled or statement can't be prepared
+// and caller should execute the statement directly. It should be called
+// before caller starts its transaction.
+func preparedStmt(db *sql.DB, stm string) *sql.Stmt {
+	if StmtCacheSize <= 0 || db == nil {
+		return nil
+	}
+	cache := getStmtCache(db)
+	cache.mutex.Lock()
+	if elem, ok := cache.items[stm]; ok {
+		cache.order.MoveToFront(elem)
+		cache.mutex.Unlock()
+		atomic.AddUint64(&StmtCacheMetrics.Hits, 1)
+		return elem.Value.(*stmtEntry).stmt
+	}
+	cache.mutex.Unlock()
+
+	// prepare statement outside of the lock to not block other lookups
+	atomic.AddUint64(&StmtCacheMetrics.Misses, 1)
+	stmt, err := db.Prepare(stm)
+	if err != nil {
+		if utils.VERBOSE > 1 {
+			log.Println("unable to prepare statement", err)
+		}
+		return nil
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if elem, ok := cache.items[stm]; ok {
+		// statement was prepared by concurrent request
+		stmt.Close()
+		cache.order.MoveToFront(elem)
+		return elem.Value.(*stmtEntry).stmt
+	}
+	for cache.order.Len() >= StmtCacheSize {
+		elem := cache.order.Back()
+		entry := elem.Value.(*stmtEntry)
+		cache.order.Remove(elem)
+		delete(cache.items, entry.stm)
+		entry.stmt.Close()
+		atomic.AddUint64(&StmtCacheMetrics.Evictions, 1)
+	}
+	cache.items[stm] = cache.order.PushFront(&stmtEntry{stm: stm, stmt: stmt})
+	return stmt
+}
+
+// helper function to execute query of given DB transaction using prepared
+// statement from DB cache, transaction specific statement is closed along
+// with transaction
+func txQuery(tx *sql.Tx, stmt *sql.Stmt, stm string, args ...interface{}) (*sql.Rows, error) {
+	if stmt != nil {
+		return tx.Stmt(stmt).Query(args...)
+	}
+	return tx.Query(stm, args...)
+}
@@ -0,0 +1,118 @@
+package dbs
+
+// DBS temporary tables module
+//
+// APIs which join large user input (e.g. lumi mask ranges or run/lumi pairs
+// of injected files) with DBS tables load it into temporary table instead of
+// building long IN lists. On ORACLE the private temporary tables are used,
+// they require ORACLE 18c or later and are dropped at the end of transaction.
+// The rows are loaded via multi-row INSERT statements, i.e. one round trip
+// per tempTableChunkSize rows.
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// number of rows inserted into temporary table by single statement
+const tempTableChunkSize = 300
+
+// helper function to begin transaction on given DB and create temporary
+// table defined by given SQL template. The temporary table may not be
+// created on read-only replica, in this case the primary DB is used.
+func beginTempTable(db *sql.DB, name string, tmpl Record) (*sql.Tx, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, Error(err, TransactionErrorCode, "", "dbs.temptable.beginTempTable")
+	}
+	err = createTempTable(tx, name, tmpl)
+	if err == nil {
+		return tx, nil
+	}
+	tx.Rollback()
+	if db == DB {
+		return nil, err
+	}
+	log.Printf("unable to create temp table %v on replica, use primary DB, error %v", tmpl["TempTable"], err)
+	return beginTempTable(DB, name, tmpl)
+}
+
+// helper function to create temporary table defined by given SQL template
+// within given transaction
+func createTempTable(tx *sql.Tx, name string, tmpl Record) error {
+	stm, err := LoadTemplateSQL(name, tmpl)
+	if err != nil {
+		return Error(err, LoadErrorCode, "", "dbs.temptable.createTempTable")
+	}
+	stm = CleanStatement(stm)
+	if utils.VERBOSE > 1 {
+		utils.PrintSQL(stm, []interface{}{}, "execute")
+	}
+	if _, err := tx.Exec(stm); err != nil {
+		msg := fmt.Sprintf("unable to create temp table %v", tmpl["TempTable"])
+		return Error(err, QueryErrorCode, msg, "dbs.temptable.createTempTable")
+	}
+	return nil
+}
+
+// helper function to drop temporary table within given transaction, ORACLE
+// private temporary tables are dropped at the end of transaction while
+// SQLite temp tables live as long as DB connection
+func dropTempTable(tx *sql.Tx, table string) error {
+	if DBOWNER != "sqlite" {
+		return nil
+	}
+	if _, err := tx.Exec(fmt.Sprintf("DROP TABLE %s", table)); err != nil {
+		msg := fmt.Sprintf("unable to drop temp table %s", table)
+		return Error(err, QueryErrorCode, msg, "dbs.temptable.dropTempTable")
+	}
+	return nil
+}
+
+// helper function to insert rows into temporary table via multi-row INSERT
+// statements (INSERT ALL on ORACLE)
+func insertTempTable(tx *sql.Tx, table string, cols []string, rows [][]interface{}) error {
+	names := strings.Join(cols, ",")
+	for k := 0; k < len(rows); k += tempTableChunkSize {
+		end := k + tempTableChunkSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		var values []string
+		var args []interface{}
+		for _, row := range rows[k:end] {
+			var binds []string
+			for _, v := range row {
+				args = append(args, v)
+				if DBOWNER == "sqlite" {
+					binds = append(binds, "?")
+				} else {
+					binds = append(binds, fmt.Sprintf(":p%d", len(args)))
+				}
+			}
+			values = append(values, fmt.Sprintf("(%s)", strings.Join(binds, ",")))
+		}
+		var stm string
+		if DBOWNER == "sqlite" {
+			stm = fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, names, strings.Join(values, ","))
+		} else {
+			stm = "INSERT ALL"
+			for _, v := range values {
+				stm = fmt.Sprintf("%s\nINTO %s (%s) VALUES %s", stm, table, names, v)
+			}
+			stm = fmt.Sprintf("%s\nSELECT * FROM dual", stm)
+		}
+		if utils.VERBOSE > 1 {
+			log.Printf("insert %d rows into %s", end-k, table)
+		}
+		if _, err := tx.Exec(stm, args...); err != nil {
+			msg := fmt.Sprintf("unable to insert rows into %s", table)
+			return Error(err, InsertErrorCode, msg, "dbs.temptable.insertTempTable")
+		}
+	}
+	return nil
+}
@@ -22,7 +22,7 @@ func (a *API) DataTiers() error {
 	stm = WhereClause(stm, conds)
 
 	// use generic query API to fetch the results from DB
-	err := executeAll(a.Writer, a.Separator, stm, args...)
+	err := a.query(stm, args...)
 	if err != nil {
 		return Error(err, QueryErrorCode, "", "dbs.tiers.DataTiers")
 	}
@@ -124,6 +124,7 @@ func (a *API) InsertDataTiers() error {
 	if err != nil {
 		return Error(err, InsertErrorCode, "", "dbs.tiers.InsertDataTiers")
 	}
+	InvalidateCache("DATA_TIERS")
 	if a.Writer != nil {
 		a.Writer.Write([]byte(`[]`))
 	}
@@ -2,14 +2,15 @@ package dbs
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/user"
 	"time"
 
+	"github.com/dmwm/dbs2go/utils"
 	"github.com/vkuznet/x509proxy"
 )
 
@@ -120,25 +121,22 @@ func HttpClient(key, cert string, tout int) *http.Client {
 	return &http.Client{Transport: tr}
 }
 
-// helper function to perform HTTP GET request and return its data
+// helper function to perform HTTP GET request and return its data. The request
+// is performed via resilient remote client, i.e. it is retried on transient
+// failures and non-2xx responses are reported as errors
 func getData(rurl string) ([]byte, error) {
-	var out []byte
-	client := HttpClient(Ckey, Cert, Timeout)
-	req, err := http.NewRequest("GET", rurl, nil)
+	client := utils.NewRemoteClient(HttpClient(Ckey, Cert, Timeout))
+	data, err := client.Get(rurl)
 	if err != nil {
-		log.Printf("unable to get data for %s, error %v, http request %+v", rurl, err, req)
-		return out, Error(err, HttpRequestErrorCode, "", "dbs.utils.getData")
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Accept", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return out, Error(err, HttpRequestErrorCode, "", "dbs.utils.getData")
-	}
-	defer resp.Body.Close()
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return data, Error(err, ReaderErrorCode, "", "dbs.utils.getData")
+		var herr *utils.HttpError
+		if errors.As(err, &herr) {
+			msg := fmt.Sprintf("remote call failed with status code %d", herr.StatusCode)
+			return data, Error(err, HttpRequestErrorCode, msg, "dbs.utils.getData")
+		}
+		if errors.Is(err, utils.ErrResponseTooLarge) {
+			return data, Error(err, ReaderErrorCode, "", "dbs.utils.getData")
+		}
+		return data, Error(err, HttpRequestErrorCode, "", "dbs.utils.getData")
 	}
 	return data, nil
 }
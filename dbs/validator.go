@@ -329,23 +329,34 @@ func Validate(r *http.Request) error {
 		for k, vvv := range r.URL.Query() {
 			// vvv here is []string{} type since all HTTP parameters are treated
 			// as list of strings
-			for _, v := range vvv {
-				if utils.InList(k, strParameters) {
-					if err := strType(k, v); err != nil {
-						return Error(err, ValidateErrorCode, "not str type", "dbs.Validate")
-					}
+			for _, vv := range vvv {
+				// values may carry filter expressions, we check them and
+				// validate their plain values
+				vals, err := filterValues(k, vv)
+				if err != nil {
+					return Error(err, ValidateErrorCode, "invalid filter", "dbs.Validate")
 				}
-				if utils.InList(k, intParameters) {
-					if err := intType(k, v); err != nil {
-						return Error(err, ValidateErrorCode, "not int type", "dbs.Validate")
+				for _, v := range vals {
+					if utils.InList(k, strParameters) {
+						if err := strType(k, v); err != nil {
+							return Error(err, ValidateErrorCode, "not str type", "dbs.Validate")
+						}
 					}
-				}
-				if utils.InList(k, mixParameters) {
-					if err := mixType(k, v); err != nil {
-						return Error(err, ValidateErrorCode, "not mix type", "dbs.Validate")
+					if utils.InList(k, intParameters) {
+						if err := intType(k, v); err != nil {
+							return Error(err, ValidateErrorCode, "not int type", "dbs.Validate")
+						}
+					}
+					if utils.InList(k, mixParameters) {
+						if err := mixType(k, v); err != nil {
+							return Error(err, ValidateErrorCode, "not mix type", "dbs.Validate")
+						}
 					}
 				}
 			}
+			if err := checkValuesLimit(k, len(vvv)); err != nil {
+				return Error(err, ValidateErrorCode, "invalid filter", "dbs.Validate")
+			}
 			if utils.VERBOSE > 0 {
 				log.Printf("query parameter key=%s values=%+v\n", k, vvv)
 			}
@@ -68,5 +68,8 @@ CREATE OR REPLACE TRIGGER PE_TRIG before insert on PROCESSING_ERAS for each row
 CREATE OR REPLACE TRIGGER MR_TRIG before insert on MIGRATION_REQUESTS for each row begin if :NEW.MIGRATION_REQUEST_ID is null then select SEQ_MR.nextval into :NEW.MIGRATION_REQUEST_ID from dual; end if; end;
  /
 
+CREATE OR REPLACE TRIGGER MS_TRIG before insert on MIGRATION_SUBSCRIPTIONS for each row begin if :NEW.SUBSCRIPTION_ID is null then select SEQ_MS.nextval into :NEW.SUBSCRIPTION_ID from dual; end if; end;
+ /
+
 CREATE OR REPLACE TRIGGER MB_TRIG before insert on MIGRATION_BLOCKS for each row begin if :NEW.MIGRATION_BLOCK_ID is null then select SEQ_MB.nextval into :NEW.MIGRATION_BLOCK_ID from dual; end if; end;
  /
@@ -353,6 +353,12 @@ CREATE INDEX `IDX_FL_6` ON `FILES` (`CREATION_DATE`);
 
 CREATE INDEX `IDX_FL_7` ON `FILES` (`CREATE_BY`);
 
+CREATE INDEX `IDX_FL_9` ON `FILES` (`ADLER32`, `FILE_SIZE`);
+
+CREATE INDEX `IDX_FL_10` ON `FILES` (`CHECK_SUM`, `FILE_SIZE`);
+
+CREATE INDEX `IDX_FL_11` ON `FILES` (`MD5`, `FILE_SIZE`);
+
 ALTER TABLE `FILES` ADD CONSTRAINT `CC_FL_IS_FILE_VALID` 
     CHECK (IS_FILE_VALID in (1,0));
 
@@ -308,6 +308,24 @@ CREATE SEQUENCE SEQ_MR
     CACHE 5000
     noorder;
 
+CREATE SEQUENCE SEQ_MS
+    START WITH 1
+    INCREMENT BY 1
+    NOMINVALUE
+    NOMAXVALUE
+    nocycle
+    CACHE 5000
+    noorder;
+
+CREATE SEQUENCE SEQ_MBT
+    START WITH 1
+    INCREMENT BY 1
+    NOMINVALUE
+    NOMAXVALUE
+    nocycle
+    CACHE 5000
+    noorder;
+
 CREATE SEQUENCE SEQ_CS
     START WITH 1
     INCREMENT BY 1
@@ -592,6 +610,58 @@ GRANT SELECT ON MIGRATION_REQUESTS TO CMS_DBS3_READ_ROLE;
 GRANT INSERT, UPDATE, DELETE ON MIGRATION_REQUESTS TO CMS_DBS3_WRITE_ROLE;
 GRANT DELETE ON MIGRATION_REQUESTS TO CMS_DBS3_ADMIN_ROLE;
 
+/* ---------------------------------------------------------------------- */
+/* Add table "MIGRATION_SUBSCRIPTIONS"                                    */
+/* ---------------------------------------------------------------------- */
+
+CREATE TABLE MIGRATION_SUBSCRIPTIONS (
+    SUBSCRIPTION_ID INTEGER CONSTRAINT NN_MS_SUBSCRIPTION_ID NOT NULL,
+    MIGRATION_URL VARCHAR2(300),
+    DATASET_PATTERN VARCHAR2(700),
+    DATA_TIER_NAME VARCHAR2(30),
+    DATASET_ACCESS_TYPE VARCHAR2(100),
+    SUBSCRIPTION_STATUS INTEGER,
+    LAST_CHECK_DATE INTEGER,
+    CREATION_DATE INTEGER,
+    CREATE_BY VARCHAR2(500),
+    LAST_MODIFICATION_DATE INTEGER,
+    LAST_MODIFIED_BY VARCHAR2(500),
+    CONSTRAINT PK_MS PRIMARY KEY (SUBSCRIPTION_ID),
+    CONSTRAINT TUC_MS_1 UNIQUE (MIGRATION_URL, DATASET_PATTERN, DATA_TIER_NAME, DATASET_ACCESS_TYPE)
+);
+GRANT SELECT ON MIGRATION_SUBSCRIPTIONS TO CMS_DBS3_READ_ROLE;
+GRANT INSERT, UPDATE, DELETE ON MIGRATION_SUBSCRIPTIONS TO CMS_DBS3_WRITE_ROLE;
+GRANT DELETE ON MIGRATION_SUBSCRIPTIONS TO CMS_DBS3_ADMIN_ROLE;
+
+/* ---------------------------------------------------------------------- */
+/* Add table "MIGRATION_BATCHES"                                          */
+/* ---------------------------------------------------------------------- */
+
+CREATE TABLE MIGRATION_BATCHES (
+    MIGRATION_BATCH_ID INTEGER CONSTRAINT NN_MBT_MIGRATION_BATCH_ID NOT NULL,
+    MIGRATION_REQUEST_ID INTEGER CONSTRAINT NN_MBT_MIGRATION_REQUEST_ID NOT NULL,
+    CREATION_DATE INTEGER,
+    CREATE_BY VARCHAR2(500),
+    CONSTRAINT PK_MBT PRIMARY KEY (MIGRATION_BATCH_ID, MIGRATION_REQUEST_ID)
+);
+GRANT SELECT ON MIGRATION_BATCHES TO CMS_DBS3_READ_ROLE;
+GRANT INSERT, UPDATE, DELETE ON MIGRATION_BATCHES TO CMS_DBS3_WRITE_ROLE;
+GRANT DELETE ON MIGRATION_BATCHES TO CMS_DBS3_ADMIN_ROLE;
+
+/* ---------------------------------------------------------------------- */
+/* Add table "MIGRATION_REPORTS"                                          */
+/* ---------------------------------------------------------------------- */
+
+CREATE TABLE MIGRATION_REPORTS (
+    MIGRATION_REQUEST_ID INTEGER CONSTRAINT NN_MRP_MIGRATION_REQUEST_ID NOT NULL,
+    MIGRATION_REPORT VARCHAR2(4000),
+    CREATION_DATE INTEGER
+);
+CREATE INDEX IDX_MRP_1 ON MIGRATION_REPORTS (MIGRATION_REQUEST_ID);
+GRANT SELECT ON MIGRATION_REPORTS TO CMS_DBS3_READ_ROLE;
+GRANT INSERT, UPDATE, DELETE ON MIGRATION_REPORTS TO CMS_DBS3_WRITE_ROLE;
+GRANT DELETE ON MIGRATION_REPORTS TO CMS_DBS3_ADMIN_ROLE;
+
 /* ---------------------------------------------------------------------- */
 /* Add table "MIGRATION_BLOCKS"                                           */
 /* ---------------------------------------------------------------------- */
@@ -759,6 +829,12 @@ CREATE INDEX IDX_FL_7 ON FILES (CREATE_BY);
 
 CREATE INDEX IDX_FL_8 ON FILES (IS_FILE_VALID);
 
+CREATE INDEX IDX_FL_9 ON FILES (ADLER32, FILE_SIZE);
+
+CREATE INDEX IDX_FL_10 ON FILES (CHECK_SUM, FILE_SIZE);
+
+CREATE INDEX IDX_FL_11 ON FILES (MD5, FILE_SIZE);
+
 ALTER TABLE FILES ADD CONSTRAINT CC_FL_IS_FILE_VALID 
     CHECK (IS_FILE_VALID in (1,0));
 
@@ -991,6 +1067,12 @@ ALTER TABLE FILE_LUMIS ADD CONSTRAINT FL_FLM
 ALTER TABLE MIGRATION_BLOCKS ADD CONSTRAINT MR_MB 
     FOREIGN KEY (MIGRATION_REQUEST_ID) REFERENCES MIGRATION_REQUESTS (MIGRATION_REQUEST_ID) ON DELETE CASCADE;
 
+ALTER TABLE MIGRATION_BATCHES ADD CONSTRAINT MR_MBT 
+    FOREIGN KEY (MIGRATION_REQUEST_ID) REFERENCES MIGRATION_REQUESTS (MIGRATION_REQUEST_ID) ON DELETE CASCADE;
+
+ALTER TABLE MIGRATION_REPORTS ADD CONSTRAINT MR_MRP 
+    FOREIGN KEY (MIGRATION_REQUEST_ID) REFERENCES MIGRATION_REQUESTS (MIGRATION_REQUEST_ID) ON DELETE CASCADE;
+
 GRANT SELECT ON SEQ_AE TO CMS_DBS3_READ_ROLE;
 GRANT SELECT ON SEQ_AF TO CMS_DBS3_READ_ROLE;
 GRANT SELECT ON SEQ_AQE TO CMS_DBS3_READ_ROLE;
@@ -1014,6 +1096,8 @@ GRANT SELECT ON SEQ_FP TO CMS_DBS3_READ_ROLE;
 GRANT SELECT ON SEQ_FT TO CMS_DBS3_READ_ROLE;
 GRANT SELECT ON SEQ_MB TO CMS_DBS3_READ_ROLE;
 GRANT SELECT ON SEQ_MR TO CMS_DBS3_READ_ROLE;
+GRANT SELECT ON SEQ_MS TO CMS_DBS3_READ_ROLE;
+GRANT SELECT ON SEQ_MBT TO CMS_DBS3_READ_ROLE;
 GRANT SELECT ON SEQ_OMC TO CMS_DBS3_READ_ROLE;
 GRANT SELECT ON SEQ_PDS TO CMS_DBS3_READ_ROLE;
 GRANT SELECT ON SEQ_PDT TO CMS_DBS3_READ_ROLE;
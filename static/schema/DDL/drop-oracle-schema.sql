@@ -75,6 +75,10 @@ ALTER TABLE FILE_LUMIS DROP CONSTRAINT FL_FLM;
 
 ALTER TABLE MIGRATION_BLOCKS DROP CONSTRAINT MR_MB;
 
+ALTER TABLE MIGRATION_BATCHES DROP CONSTRAINT MR_MBT;
+
+ALTER TABLE MIGRATION_REPORTS DROP CONSTRAINT MR_MRP;
+
 /* ---------------------------------------------------------------------- */
 /* Drop table "FILE_LUMIS"                                                */
 /* ---------------------------------------------------------------------- */
@@ -325,6 +329,50 @@ ALTER TABLE MIGRATION_BLOCKS DROP CONSTRAINT TUC_MB_1;
 
 DROP TABLE MIGRATION_BLOCKS;
 
+/* ---------------------------------------------------------------------- */
+/* Drop table "MIGRATION_BATCHES"                                         */
+/* ---------------------------------------------------------------------- */
+
+/* Drop constraints */
+
+ALTER TABLE MIGRATION_BATCHES DROP CONSTRAINT NN_MBT_MIGRATION_BATCH_ID;
+
+ALTER TABLE MIGRATION_BATCHES DROP CONSTRAINT NN_MBT_MIGRATION_REQUEST_ID;
+
+ALTER TABLE MIGRATION_BATCHES DROP CONSTRAINT PK_MBT;
+
+/* Drop table */
+
+DROP TABLE MIGRATION_BATCHES;
+
+/* ---------------------------------------------------------------------- */
+/* Drop table "MIGRATION_REPORTS"                                         */
+/* ---------------------------------------------------------------------- */
+
+/* Drop constraints */
+
+ALTER TABLE MIGRATION_REPORTS DROP CONSTRAINT NN_MRP_MIGRATION_REQUEST_ID;
+
+/* Drop table */
+
+DROP TABLE MIGRATION_REPORTS;
+
+/* ---------------------------------------------------------------------- */
+/* Drop table "MIGRATION_SUBSCRIPTIONS"                                   */
+/* ---------------------------------------------------------------------- */
+
+/* Drop constraints */
+
+ALTER TABLE MIGRATION_SUBSCRIPTIONS DROP CONSTRAINT NN_MS_SUBSCRIPTION_ID;
+
+ALTER TABLE MIGRATION_SUBSCRIPTIONS DROP CONSTRAINT PK_MS;
+
+ALTER TABLE MIGRATION_SUBSCRIPTIONS DROP CONSTRAINT TUC_MS_1;
+
+/* Drop table */
+
+DROP TABLE MIGRATION_SUBSCRIPTIONS;
+
 /* ---------------------------------------------------------------------- */
 /* Drop table "MIGRATION_REQUESTS"                                        */
 /* ---------------------------------------------------------------------- */
@@ -689,6 +737,10 @@ DROP SEQUENCE SEQ_MB;
 
 DROP SEQUENCE SEQ_MR;
 
+DROP SEQUENCE SEQ_MS;
+
+DROP SEQUENCE SEQ_MBT;
+
 DROP SEQUENCE SEQ_CS;
 
 DROP ROLE CMS_DBS3_READ_ROLE;
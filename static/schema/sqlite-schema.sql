@@ -226,6 +226,42 @@
 	"LAST_MODIFIED_BY" VARCHAR2(500)
    ) ;
 --------------------------------------------------------
+--  DDL for Table MIGRATION_BATCHES
+--------------------------------------------------------
+
+  CREATE TABLE "MIGRATION_BATCHES" 
+   (	"MIGRATION_BATCH_ID" INTEGER, 
+	"MIGRATION_REQUEST_ID" INTEGER, 
+	"CREATION_DATE" INTEGER, 
+	"CREATE_BY" VARCHAR2(500)
+   ) ;
+--------------------------------------------------------
+--  DDL for Table MIGRATION_REPORTS
+--------------------------------------------------------
+
+  CREATE TABLE "MIGRATION_REPORTS" 
+   (	"MIGRATION_REQUEST_ID" INTEGER, 
+	"MIGRATION_REPORT" VARCHAR2(4000), 
+	"CREATION_DATE" INTEGER
+   ) ;
+--------------------------------------------------------
+--  DDL for Table MIGRATION_SUBSCRIPTIONS
+--------------------------------------------------------
+
+  CREATE TABLE "MIGRATION_SUBSCRIPTIONS" 
+   (	"SUBSCRIPTION_ID" INTEGER, 
+	"MIGRATION_URL" VARCHAR2(300), 
+	"DATASET_PATTERN" VARCHAR2(700), 
+	"DATA_TIER_NAME" VARCHAR2(30), 
+	"DATASET_ACCESS_TYPE" VARCHAR2(100), 
+	"SUBSCRIPTION_STATUS" INTEGER, 
+	"LAST_CHECK_DATE" INTEGER, 
+	"CREATION_DATE" INTEGER, 
+	"CREATE_BY" VARCHAR2(500), 
+	"LAST_MODIFICATION_DATE" INTEGER, 
+	"LAST_MODIFIED_BY" VARCHAR2(500)
+   ) ;
+--------------------------------------------------------
 --  DDL for Table MIGRATION_REQUESTS
 --------------------------------------------------------
 
@@ -530,6 +566,24 @@
   CREATE INDEX "IDX_FL_8" ON "FILES" ("IS_FILE_VALID") 
   ;
 --------------------------------------------------------
+--  DDL for Index IDX_FL_9
+--------------------------------------------------------
+
+  CREATE INDEX "IDX_FL_9" ON "FILES" ("ADLER32", "FILE_SIZE") 
+  ;
+--------------------------------------------------------
+--  DDL for Index IDX_FL_10
+--------------------------------------------------------
+
+  CREATE INDEX "IDX_FL_10" ON "FILES" ("CHECK_SUM", "FILE_SIZE") 
+  ;
+--------------------------------------------------------
+--  DDL for Index IDX_FL_11
+--------------------------------------------------------
+
+  CREATE INDEX "IDX_FL_11" ON "FILES" ("MD5", "FILE_SIZE") 
+  ;
+--------------------------------------------------------
 --  DDL for Index IDX_FP_1
 --------------------------------------------------------
 
@@ -734,6 +788,24 @@
   CREATE UNIQUE INDEX "PK_MR" ON "MIGRATION_REQUESTS" ("MIGRATION_REQUEST_ID") 
   ;
 --------------------------------------------------------
+--  DDL for Index PK_MBT
+--------------------------------------------------------
+
+  CREATE UNIQUE INDEX "PK_MBT" ON "MIGRATION_BATCHES" ("MIGRATION_BATCH_ID", "MIGRATION_REQUEST_ID") 
+  ;
+--------------------------------------------------------
+--  DDL for Index IDX_MRP_1
+--------------------------------------------------------
+
+  CREATE INDEX "IDX_MRP_1" ON "MIGRATION_REPORTS" ("MIGRATION_REQUEST_ID") 
+  ;
+--------------------------------------------------------
+--  DDL for Index PK_MS
+--------------------------------------------------------
+
+  CREATE UNIQUE INDEX "PK_MS" ON "MIGRATION_SUBSCRIPTIONS" ("SUBSCRIPTION_ID") 
+  ;
+--------------------------------------------------------
 --  DDL for Index PK_OMC
 --------------------------------------------------------
 
@@ -866,6 +938,12 @@
   CREATE UNIQUE INDEX "TUC_MR_1" ON "MIGRATION_REQUESTS" ("MIGRATION_INPUT") 
   ;
 --------------------------------------------------------
+--  DDL for Index TUC_MS_1
+--------------------------------------------------------
+
+  CREATE UNIQUE INDEX "TUC_MS_1" ON "MIGRATION_SUBSCRIPTIONS" ("MIGRATION_URL", "DATASET_PATTERN", "DATA_TIER_NAME", "DATASET_ACCESS_TYPE") 
+  ;
+--------------------------------------------------------
 --  DDL for Index TUC_OMC_1
 --------------------------------------------------------
 
@@ -0,0 +1,9 @@
+SELECT L.RUN_NUM, L.LUMI_SECTION_NUM, L.LOGICAL_FILE_NAME, F.LOGICAL_FILE_NAME
+FROM {{.TempTable}} L
+JOIN {{.Owner}}.FILE_LUMIS FL ON FL.RUN_NUM = L.RUN_NUM
+ AND FL.LUMI_SECTION_NUM = L.LUMI_SECTION_NUM
+JOIN {{.Owner}}.FILES F ON F.FILE_ID = FL.FILE_ID
+JOIN {{.Owner}}.DATASETS D ON D.DATASET_ID = F.DATASET_ID
+WHERE D.DATASET = :dataset
+AND F.IS_FILE_VALID = 1
+AND F.LOGICAL_FILE_NAME NOT IN (SELECT LOGICAL_FILE_NAME FROM {{.TempTable}})
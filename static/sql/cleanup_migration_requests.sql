@@ -1,4 +1,13 @@
+{{if .Select}}
+SELECT MIGRATION_REQUEST_ID, MIGRATION_URL,
+       MIGRATION_INPUT, MIGRATION_STATUS, MIGRATION_SERVER,
+       CREATE_BY, CREATION_DATE,
+       LAST_MODIFIED_BY, LAST_MODIFICATION_DATE, RETRY_COUNT
+FROM {{.Owner}}.MIGRATION_REQUESTS
+{{else}}
 DELETE FROM {{.Owner}}.MIGRATION_REQUESTS
-WHERE LAST_MODIFICATION_DATE <= {{.Value}}
-or (migration_status=3 and retry_count>2 and last_modification_date <= {{.FailDate}})
-or (migration_status=9 and retry_count>2 and last_modification_date <= {{.FailDate}})
+{{end}}
+WHERE (MIGRATION_STATUS=2 and LAST_MODIFICATION_DATE <= {{.CompletedDate}})
+or (MIGRATION_STATUS=4 and LAST_MODIFICATION_DATE <= {{.ExistDate}})
+or (MIGRATION_STATUS=3 and RETRY_COUNT>{{.Retries}} and LAST_MODIFICATION_DATE <= {{.FailDate}})
+or (MIGRATION_STATUS=9 and RETRY_COUNT>{{.Retries}} and LAST_MODIFICATION_DATE <= {{.FailDate}})
@@ -0,0 +1,7 @@
+SELECT COUNT(F.FILE_ID), SUM(F.EVENT_COUNT)
+FROM {{.Owner}}.FILES F
+JOIN {{.Owner}}.DATASETS D ON D.DATASET_ID = F.DATASET_ID
+WHERE D.DATASET = :dataset
+{{if .ValidFileOnly}}
+AND F.IS_FILE_VALID = 1
+{{end}}
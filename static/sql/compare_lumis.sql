@@ -0,0 +1,24 @@
+SELECT 'A' SIDE, A.RUN_NUM, A.LUMI_SECTION_NUM
+FROM (
+    SELECT DISTINCT FL.RUN_NUM, FL.LUMI_SECTION_NUM
+    FROM {{.Owner}}.FILE_LUMIS FL
+    JOIN {{.Owner}}.FILES F ON F.FILE_ID = FL.FILE_ID
+    JOIN {{.Owner}}.DATASETS D ON D.DATASET_ID = F.DATASET_ID
+    WHERE D.DATASET = :dataset_a
+{{if .ValidFileOnly}}
+    AND F.IS_FILE_VALID = 1
+{{end}}
+) A
+UNION ALL
+SELECT 'B' SIDE, B.RUN_NUM, B.LUMI_SECTION_NUM
+FROM (
+    SELECT DISTINCT FL.RUN_NUM, FL.LUMI_SECTION_NUM
+    FROM {{.Owner}}.FILE_LUMIS FL
+    JOIN {{.Owner}}.FILES F ON F.FILE_ID = FL.FILE_ID
+    JOIN {{.Owner}}.DATASETS D ON D.DATASET_ID = F.DATASET_ID
+    WHERE D.DATASET = :dataset_b
+{{if .ValidFileOnly}}
+    AND F.IS_FILE_VALID = 1
+{{end}}
+) B
+ORDER BY 2, 3, 1
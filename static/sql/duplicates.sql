@@ -0,0 +1,47 @@
+SELECT DUP.CHECKSUM, F.FILE_SIZE, DUP.COPIES,
+       F.LOGICAL_FILE_NAME, F.IS_FILE_VALID, B.BLOCK_NAME, D.DATASET
+FROM {{.Owner}}.FILES F
+JOIN {{.Owner}}.BLOCKS B ON B.BLOCK_ID = F.BLOCK_ID
+JOIN {{.Owner}}.DATASETS D ON D.DATASET_ID = F.DATASET_ID
+JOIN (
+    SELECT DF.{{.Checksum}} CHECKSUM, DF.FILE_SIZE, COUNT(*) COPIES
+{{if .AllDatasets}}
+    FROM (
+        SELECT DISTINCT SF.{{.Checksum}} CHECKSUM, SF.FILE_SIZE
+        FROM {{.Owner}}.FILES SF
+        JOIN {{.Owner}}.DATASETS SD ON SD.DATASET_ID = SF.DATASET_ID
+        WHERE SD.DATASET = :dataset
+        AND SF.{{.Checksum}} IS NOT NULL
+        AND SF.{{.Checksum}} <> 'NOTSET'
+{{if .ValidFileOnly}}
+        AND SF.IS_FILE_VALID = 1
+{{end}}
+    ) SF
+    JOIN {{.Owner}}.FILES DF ON DF.{{.Checksum}} = SF.CHECKSUM AND DF.FILE_SIZE = SF.FILE_SIZE
+{{if .ValidFileOnly}}
+    WHERE DF.IS_FILE_VALID = 1
+{{end}}
+{{else}}
+    FROM {{.Owner}}.FILES DF
+    JOIN {{.Owner}}.DATASETS DD ON DD.DATASET_ID = DF.DATASET_ID
+    WHERE DD.DATASET = :dataset
+    AND DF.{{.Checksum}} IS NOT NULL
+    AND DF.{{.Checksum}} <> 'NOTSET'
+{{if .ValidFileOnly}}
+    AND DF.IS_FILE_VALID = 1
+{{end}}
+{{end}}
+    GROUP BY DF.{{.Checksum}}, DF.FILE_SIZE
+    HAVING COUNT(*) > 1
+) DUP ON DUP.CHECKSUM = F.{{.Checksum}} AND DUP.FILE_SIZE = F.FILE_SIZE
+{{if .AllDatasets}}
+{{if .ValidFileOnly}}
+WHERE F.IS_FILE_VALID = 1
+{{end}}
+{{else}}
+WHERE D.DATASET = :dataset_name
+{{if .ValidFileOnly}}
+AND F.IS_FILE_VALID = 1
+{{end}}
+{{end}}
+ORDER BY DUP.CHECKSUM, F.FILE_SIZE, F.LOGICAL_FILE_NAME
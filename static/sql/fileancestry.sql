@@ -0,0 +1,8 @@
+SELECT F.LOGICAL_FILE_NAME logical_file_name,
+       R.LOGICAL_FILE_NAME related_logical_file_name,
+       DT.DATA_TIER_NAME data_tier_name
+FROM {{.Owner}}.FILES F
+JOIN {{.Owner}}.FILE_PARENTS FP ON FP.{{.From}} = F.FILE_ID
+JOIN {{.Owner}}.FILES R ON R.FILE_ID = FP.{{.To}}
+LEFT OUTER JOIN {{.Owner}}.DATASETS D ON D.DATASET_ID = R.DATASET_ID
+LEFT OUTER JOIN {{.Owner}}.DATA_TIERS DT ON DT.DATA_TIER_ID = D.DATA_TIER_ID
@@ -0,0 +1,14 @@
+SELECT F.LOGICAL_FILE_NAME, FL.RUN_NUM, FL.LUMI_SECTION_NUM, FL.EVENT_COUNT
+FROM {{.Owner}}.FILE_LUMIS FL
+JOIN {{.TempTable}} M ON M.RUN_NUM = FL.RUN_NUM
+ AND FL.LUMI_SECTION_NUM BETWEEN M.FIRST_LUMI AND M.LAST_LUMI
+JOIN {{.Owner}}.FILES F ON F.FILE_ID = FL.FILE_ID
+JOIN {{.Owner}}.DATASETS D ON D.DATASET_ID = F.DATASET_ID
+{{if .ValidFileOnly}}
+JOIN {{.Owner}}.DATASET_ACCESS_TYPES DT ON DT.DATASET_ACCESS_TYPE_ID = D.DATASET_ACCESS_TYPE_ID
+{{end}}
+WHERE D.DATASET = :dataset
+{{if .ValidFileOnly}}
+AND F.IS_FILE_VALID = 1 AND DT.DATASET_ACCESS_TYPE in ('VALID', 'PRODUCTION')
+{{end}}
+ORDER BY F.LOGICAL_FILE_NAME, FL.RUN_NUM, FL.LUMI_SECTION_NUM
@@ -0,0 +1,20 @@
+WITH PARENTS AS (
+    SELECT PFL.RUN_NUM, PFL.LUMI_SECTION_NUM, PF.FILE_ID, PF.LOGICAL_FILE_NAME
+    FROM {{.Owner}}.FILE_LUMIS PFL
+    JOIN {{.Owner}}.FILES PF ON PF.FILE_ID = PFL.FILE_ID
+    JOIN {{.Owner}}.DATASET_PARENTS DP ON DP.PARENT_DATASET_ID = PF.DATASET_ID
+    JOIN {{.Owner}}.DATASETS D ON D.DATASET_ID = DP.THIS_DATASET_ID
+    WHERE D.DATASET = :child_ds_name
+    AND PF.IS_FILE_VALID = 1
+)
+SELECT F.FILE_ID, F.LOGICAL_FILE_NAME, FL.RUN_NUM, FL.LUMI_SECTION_NUM,
+       P.FILE_ID PARENT_FILE_ID, P.LOGICAL_FILE_NAME PARENT_LOGICAL_FILE_NAME,
+       CASE WHEN EXISTS (
+           SELECT 1 FROM {{.Owner}}.FILE_PARENTS FP WHERE FP.THIS_FILE_ID = F.FILE_ID
+       ) THEN 1 ELSE 0 END HAS_PARENTS
+FROM {{.Owner}}.FILES F
+JOIN {{.Owner}}.BLOCKS B ON B.BLOCK_ID = F.BLOCK_ID
+JOIN {{.Owner}}.FILE_LUMIS FL ON FL.FILE_ID = F.FILE_ID
+LEFT OUTER JOIN PARENTS P ON P.RUN_NUM = FL.RUN_NUM AND P.LUMI_SECTION_NUM = FL.LUMI_SECTION_NUM
+WHERE B.BLOCK_NAME = :child_block_name
+ORDER BY F.LOGICAL_FILE_NAME
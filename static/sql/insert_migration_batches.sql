@@ -0,0 +1,10 @@
+INSERT INTO {{.Owner}}.MIGRATION_BATCHES
+    (MIGRATION_BATCH_ID,
+    MIGRATION_REQUEST_ID,
+    CREATION_DATE,
+    CREATE_BY)
+VALUES
+    (:migration_batch_id,
+    :migration_request_id,
+    :creation_date,
+    :create_by)
@@ -0,0 +1,8 @@
+INSERT INTO {{.Owner}}.MIGRATION_REPORTS
+    (MIGRATION_REQUEST_ID,
+    MIGRATION_REPORT,
+    CREATION_DATE)
+VALUES
+    (:migration_request_id,
+    :migration_report,
+    :creation_date)
@@ -0,0 +1,24 @@
+INSERT INTO {{.Owner}}.MIGRATION_SUBSCRIPTIONS
+    (SUBSCRIPTION_ID,
+    MIGRATION_URL,
+    DATASET_PATTERN,
+    DATA_TIER_NAME,
+    DATASET_ACCESS_TYPE,
+    SUBSCRIPTION_STATUS,
+    LAST_CHECK_DATE,
+    CREATION_DATE,
+    CREATE_BY,
+    LAST_MODIFICATION_DATE,
+    LAST_MODIFIED_BY)
+VALUES
+    (:subscription_id,
+    :migration_url,
+    :dataset_pattern,
+    :data_tier_name,
+    :dataset_access_type,
+    :subscription_status,
+    :last_check_date,
+    :creation_date,
+    :create_by,
+    :last_modification_date,
+    :last_modified_by)
@@ -0,0 +1,5 @@
+SELECT F.LOGICAL_FILE_NAME, F.IS_FILE_VALID, B.BLOCK_NAME, D.DATASET,
+       F.FILE_SIZE, F.CHECK_SUM, F.ADLER32, F.MD5
+FROM {{.Owner}}.FILES F
+JOIN {{.Owner}}.BLOCKS B ON B.BLOCK_ID = F.BLOCK_ID
+JOIN {{.Owner}}.DATASETS D ON D.DATASET_ID = F.DATASET_ID
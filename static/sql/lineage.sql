@@ -0,0 +1,18 @@
+WITH LINEAGE (THIS_DATASET_ID, PARENT_DATASET_ID, LVL) AS (
+    SELECT DP.THIS_DATASET_ID, DP.PARENT_DATASET_ID, 1
+    FROM {{.Owner}}.DATASET_PARENTS DP
+    JOIN {{.Owner}}.DATASETS D ON D.DATASET_ID = DP.{{.From}}
+    WHERE D.DATASET = :dataset
+    UNION ALL
+    SELECT DP.THIS_DATASET_ID, DP.PARENT_DATASET_ID, L.LVL + 1
+    FROM {{.Owner}}.DATASET_PARENTS DP
+    JOIN LINEAGE L ON DP.{{.From}} = L.{{.To}}
+    WHERE L.LVL < :depth
+)
+SELECT DISTINCT PD.DATASET parent_dataset,
+       PD.DATASET_ID parent_dataset_id,
+       CD.DATASET this_dataset,
+       CD.DATASET_ID this_dataset_id
+FROM LINEAGE L
+JOIN {{.Owner}}.DATASETS PD ON PD.DATASET_ID = L.PARENT_DATASET_ID
+JOIN {{.Owner}}.DATASETS CD ON CD.DATASET_ID = L.THIS_DATASET_ID
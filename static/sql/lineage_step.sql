@@ -0,0 +1,7 @@
+SELECT PD.DATASET parent_dataset,
+       PD.DATASET_ID parent_dataset_id,
+       CD.DATASET this_dataset,
+       CD.DATASET_ID this_dataset_id
+FROM {{.Owner}}.DATASET_PARENTS DP
+JOIN {{.Owner}}.DATASETS PD ON PD.DATASET_ID = DP.PARENT_DATASET_ID
+JOIN {{.Owner}}.DATASETS CD ON CD.DATASET_ID = DP.THIS_DATASET_ID
@@ -0,0 +1,19 @@
+SELECT FL.RUN_NUM, FL.LUMI_SECTION_NUM, FL.EVENT_COUNT,
+       F.LOGICAL_FILE_NAME, B.BLOCK_NAME
+FROM {{.Owner}}.FILE_LUMIS FL
+JOIN {{.Owner}}.FILES F ON F.FILE_ID = FL.FILE_ID
+JOIN {{.Owner}}.BLOCKS B ON B.BLOCK_ID = F.BLOCK_ID
+JOIN {{.Owner}}.DATASETS D ON D.DATASET_ID = F.DATASET_ID
+JOIN (
+    SELECT DFL.RUN_NUM, DFL.LUMI_SECTION_NUM
+    FROM {{.Owner}}.FILE_LUMIS DFL
+    JOIN {{.Owner}}.FILES DF ON DF.FILE_ID = DFL.FILE_ID
+    JOIN {{.Owner}}.DATASETS DD ON DD.DATASET_ID = DF.DATASET_ID
+    WHERE DD.DATASET = :dataset
+    AND DF.IS_FILE_VALID = 1
+    GROUP BY DFL.RUN_NUM, DFL.LUMI_SECTION_NUM
+    HAVING COUNT(DISTINCT DFL.FILE_ID) > 1
+) DUP ON DUP.RUN_NUM = FL.RUN_NUM AND DUP.LUMI_SECTION_NUM = FL.LUMI_SECTION_NUM
+WHERE D.DATASET = :dataset_name
+AND F.IS_FILE_VALID = 1
+ORDER BY FL.RUN_NUM, FL.LUMI_SECTION_NUM, F.LOGICAL_FILE_NAME
@@ -0,0 +1,13 @@
+SELECT FL.RUN_NUM, FL.LUMI_SECTION_NUM, FL.EVENT_COUNT
+FROM {{.Owner}}.FILE_LUMIS FL
+JOIN {{.Owner}}.FILES F ON F.FILE_ID = FL.FILE_ID
+{{if .Dataset}}
+JOIN {{.Owner}}.DATASETS D ON D.DATASET_ID = F.DATASET_ID
+{{end}}
+{{if .BlockName}}
+JOIN {{.Owner}}.BLOCKS B ON B.BLOCK_ID = F.BLOCK_ID
+{{end}}
+{{if .ValidFileOnly}}
+JOIN {{.Owner}}.DATASETS VD ON VD.DATASET_ID = F.DATASET_ID
+JOIN {{.Owner}}.DATASET_ACCESS_TYPES DT ON DT.DATASET_ACCESS_TYPE_ID = VD.DATASET_ACCESS_TYPE_ID
+{{end}}
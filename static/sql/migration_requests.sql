@@ -6,6 +6,9 @@ FROM {{.Owner}}.MIGRATION_REQUESTS MR
 {{if .Blocks}}
 JOIN {{.Owner}}.MIGRATION_BLOCKS MB ON MB.MIGRATION_REQUEST_ID=MR.MIGRATION_REQUEST_ID
 {{end}}
+{{if .Batch}}
+JOIN {{.Owner}}.MIGRATION_BATCHES MBT ON MBT.MIGRATION_REQUEST_ID=MR.MIGRATION_REQUEST_ID
+{{end}}
 
 {{if .Oldest}}
 WHERE MR.MIGRATION_STATUS=0
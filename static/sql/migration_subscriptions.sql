@@ -0,0 +1,6 @@
+SELECT MS.SUBSCRIPTION_ID, MS.MIGRATION_URL,
+       MS.DATASET_PATTERN, MS.DATA_TIER_NAME, MS.DATASET_ACCESS_TYPE,
+       MS.SUBSCRIPTION_STATUS, MS.LAST_CHECK_DATE,
+       MS.CREATE_BY, MS.CREATION_DATE,
+       MS.LAST_MODIFIED_BY, MS.LAST_MODIFICATION_DATE
+FROM {{.Owner}}.MIGRATION_SUBSCRIPTIONS MS
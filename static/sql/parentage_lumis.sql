@@ -0,0 +1,4 @@
+SELECT F.LOGICAL_FILE_NAME, D.DATASET, FL.RUN_NUM, FL.LUMI_SECTION_NUM
+FROM {{.Owner}}.FILES F
+JOIN {{.Owner}}.DATASETS D ON D.DATASET_ID = F.DATASET_ID
+LEFT OUTER JOIN {{.Owner}}.FILE_LUMIS FL ON FL.FILE_ID = F.FILE_ID
@@ -0,0 +1,3 @@
+SELECT MR.MIGRATION_REQUEST_ID, MR.MIGRATION_STATUS
+FROM {{.Owner}}.MIGRATION_REQUESTS MR
+WHERE MR.MIGRATION_INPUT=:migration_input
@@ -0,0 +1,8 @@
+{{if eq .Owner "sqlite"}}
+CREATE TEMP TABLE {{.TempTable}}
+(RUN_NUM INTEGER, LUMI_SECTION_NUM INTEGER, LOGICAL_FILE_NAME VARCHAR(500))
+{{else}}
+CREATE PRIVATE TEMPORARY TABLE {{.TempTable}}
+(RUN_NUM INTEGER, LUMI_SECTION_NUM INTEGER, LOGICAL_FILE_NAME VARCHAR2(500))
+ON COMMIT DROP DEFINITION
+{{end}}
@@ -0,0 +1,8 @@
+{{if eq .Owner "sqlite"}}
+CREATE TEMP TABLE {{.TempTable}}
+(RUN_NUM INTEGER, FIRST_LUMI INTEGER, LAST_LUMI INTEGER)
+{{else}}
+CREATE PRIVATE TEMPORARY TABLE {{.TempTable}}
+(RUN_NUM INTEGER, FIRST_LUMI INTEGER, LAST_LUMI INTEGER)
+ON COMMIT DROP DEFINITION
+{{end}}
@@ -0,0 +1,3 @@
+UPDATE {{.Owner}}.MIGRATION_SUBSCRIPTIONS
+    SET LAST_CHECK_DATE = :last_check_date
+WHERE SUBSCRIPTION_ID = :subscription_id
@@ -0,0 +1,5 @@
+UPDATE {{.Owner}}.MIGRATION_SUBSCRIPTIONS
+    SET SUBSCRIPTION_STATUS = :subscription_status,
+    LAST_MODIFIED_BY = :last_modified_by,
+    LAST_MODIFICATION_DATE = :last_modification_date
+WHERE SUBSCRIPTION_ID = :subscription_id
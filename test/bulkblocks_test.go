@@ -3,10 +3,13 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -171,4 +174,249 @@ func TestBulkBlocks(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Fail to process bulkblocks data %v\n", err)
 	}
+
+	// new block with new files but the same lumis should be rejected
+	// when lumi check is enabled
+	var rec dbs.BulkBlocks
+	err = json.Unmarshal(data, &rec)
+	if err != nil {
+		t.Fatalf("Fail to unmarshal bulkblocks data %v\n", err)
+	}
+	rec.Block.BlockName = fmt.Sprintf("%s#duplicate-lumis", rec.Dataset.Dataset)
+	for i := range rec.Files {
+		rec.Files[i].LogicalFileName = strings.Replace(rec.Files[i].LogicalFileName, ".root", "-dup.root", 1)
+		rec.Files[i].IsFileValid = 1
+	}
+	rec.FileConfigList = nil
+	rec.FileParentList = nil
+	data, _ = json.Marshal(rec)
+	dbs.BulkBlocksLumiCheck = "reject"
+	defer func() { dbs.BulkBlocksLumiCheck = "" }()
+	api.Reader = bytes.NewReader(data)
+	err = api.InsertBulkBlocks()
+	if err == nil || !strings.Contains(err.Error(), "already exist in valid files") {
+		t.Fatalf("bulkblocks with duplicate lumis is not rejected, error %v\n", err)
+	}
+}
+
+// TestBulkBlocksParentage tests parentage validation of injected files,
+// it relies on files injected by TestBulkBlocks
+func TestBulkBlocksParentage(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+
+	// use file of bulkblocks0.json as parent of injected files
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Fail to get current directory %v\n", err)
+	}
+	fname := fmt.Sprintf("%s/data/bulkblocks0.json", dir)
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("Fail to read file %s, error %v\n", fname, err)
+	}
+	var prec dbs.BulkBlocks
+	err = json.Unmarshal(data, &prec)
+	if err != nil {
+		t.Fatalf("Fail to unmarshal bulkblocks data %v\n", err)
+	}
+	parent := prec.Files[0]
+
+	// child1 is consistent, parent of child2 does not exist and child3 has
+	// lumi which is not present in its parent
+	rec := dbs.BulkBlocks{
+		Dataset:           dbs.Dataset{Dataset: "/unittest_parentage/Summer2011-pstr-v10/AOD"},
+		Block:             dbs.Block{BlockName: "/unittest_parentage/Summer2011-pstr-v10/AOD#1"},
+		DatasetParentList: []string{prec.Dataset.Dataset},
+	}
+	extra := dbs.FileLumi{RunNumber: 999, LumiSectionNumber: 1}
+	for i, lumis := range [][]dbs.FileLumi{
+		parent.FileLumiList[:1],
+		parent.FileLumiList[:1],
+		append([]dbs.FileLumi{extra}, parent.FileLumiList...),
+	} {
+		lfn := fmt.Sprintf("/store/data/parentage/child%d.root", i+1)
+		rec.Files = append(rec.Files, dbs.File{LogicalFileName: lfn, FileLumiList: lumis})
+		plfn := parent.LogicalFileName
+		if i == 1 {
+			plfn = "/store/data/parentage/missing.root"
+		}
+		rec.FileParentList = append(rec.FileParentList, dbs.FileParentRecord{LogicalFileName: lfn, ParentLogicalFileName: plfn})
+	}
+	// parentage is validated within injection transaction
+	tx, err := dbs.DB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	problems, err := dbs.ValidateParentage(tx, rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []dbs.ParentageProblem{
+		{
+			LogicalFileName:       "/store/data/parentage/child2.root",
+			ParentLogicalFileName: "/store/data/parentage/missing.root",
+			Reason:                "missing_parent",
+		},
+		{
+			LogicalFileName: "/store/data/parentage/child3.root",
+			Reason:          "uncovered_lumis",
+			Lumis:           dbs.LumiMask{"999": {{1, 1}}},
+		},
+	}
+	if !reflect.DeepEqual(problems, expect) {
+		t.Errorf("wrong parentage problems %+v, expected %+v", problems, expect)
+	}
+
+	// parent dataset should be listed in dataset parents
+	rec.DatasetParentList = nil
+	problems, err = dbs.ValidateParentage(tx, rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reasons []string
+	for _, p := range problems {
+		reasons = append(reasons, fmt.Sprintf("%s %s", p.LogicalFileName, p.Reason))
+	}
+	expectReasons := []string{
+		"/store/data/parentage/child1.root unlisted_parent_dataset",
+		"/store/data/parentage/child2.root missing_parent",
+		"/store/data/parentage/child3.root unlisted_parent_dataset",
+		"/store/data/parentage/child3.root uncovered_lumis",
+	}
+	if !reflect.DeepEqual(reasons, expectReasons) {
+		t.Errorf("wrong parentage problems %v, expected %v", reasons, expectReasons)
+	}
+
+	tx.Rollback()
+
+	// bulkblocks API rejects inconsistent parentage
+	dbs.BulkBlocksParentageCheck = "reject"
+	defer func() { dbs.BulkBlocksParentageCheck = "" }()
+	data, _ = json.Marshal(rec)
+	api := dbs.API{Reader: bytes.NewReader(data), Writer: utils.StdoutWriter(""), CreateBy: "tester"}
+	err = api.InsertBulkBlocks()
+	var dbsErr *dbs.DBSError
+	if !errors.As(err, &dbsErr) {
+		t.Fatalf("bulkblocks with inconsistent parentage is not rejected, error %v\n", err)
+	}
+	// parentage report is provided as structured data of DBS error
+	report, ok := dbsErr.Data.(dbs.ParentageReport)
+	if !ok || report.ProblemCount != 4 || len(report.Problems) != 4 || report.Problems[3].Reason != "uncovered_lumis" {
+		t.Errorf("wrong parentage report %+v", dbsErr.Data)
+	}
+	data, _ = json.Marshal(err)
+	if !strings.Contains(string(data), `"data":{"problem_count":4,"problems":[`) {
+		t.Errorf("parentage report is not part of JSON error %s", string(data))
+	}
+}
+
+// TestBulkBlocksInferParentage tests inference of file parentage of blocks
+// injected with infer_file_parents flag and without file parent list, it
+// relies on data of TestBulkBlocks
+func TestBulkBlocksInferParentage(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+
+	// use bulkblocks0.json as template of injected blocks
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Fail to get current directory %v\n", err)
+	}
+	fname := fmt.Sprintf("%s/data/bulkblocks0.json", dir)
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("Fail to read file %s, error %v\n", fname, err)
+	}
+	// helper function to inject block with given files and lumis of run 1
+	inject := func(procds string, parents []string, infer bool, files map[string][]int64, insert func(*dbs.API) error) {
+		var rec dbs.BulkBlocks
+		if err := json.Unmarshal(data, &rec); err != nil {
+			t.Fatalf("Fail to unmarshal bulkblocks data %v\n", err)
+		}
+		rec.Dataset.ProcessedDSName = procds
+		rec.Dataset.Dataset = fmt.Sprintf("/%s/%s/%s", rec.PrimaryDataset.PrimaryDSName, procds, rec.Dataset.DataTierName)
+		rec.Block.BlockName = fmt.Sprintf("%s#1", rec.Dataset.Dataset)
+		rec.DatasetParentList = parents
+		rec.FileConfigList = nil
+		rec.FileParentList = nil
+		rec.InferFileParents = infer
+		template := rec.Files[0]
+		rec.Files = nil
+		for lfn, lumis := range files {
+			f := template
+			f.LogicalFileName = lfn
+			f.IsFileValid = 1
+			f.FileLumiList = nil
+			for _, lumi := range lumis {
+				f.FileLumiList = append(f.FileLumiList, dbs.FileLumi{RunNumber: 1, LumiSectionNumber: lumi})
+			}
+			rec.Files = append(rec.Files, f)
+		}
+		data, _ := json.Marshal(rec)
+		api := dbs.API{Reader: bytes.NewReader(data), Writer: utils.StdoutWriter(""), CreateBy: "tester"}
+		if err := insert(&api); err != nil {
+			t.Fatalf("Fail to inject block %s, error %v\n", rec.Block.BlockName, err)
+		}
+	}
+	parent := "/unittest_web_primary_ds_name_14144/Summer2011-infer-v1/GEN-SIM-RAW"
+	inject("Summer2011-infer-v1", nil, false, map[string][]int64{
+		"/store/data/infer/p1.root": {1, 2},
+		"/store/data/infer/p2.root": {3},
+	}, (*dbs.API).InsertBulkBlocks)
+
+	// parents of child blocks are inferred by both bulkblocks APIs
+	inject("Summer2011-infer-v2", []string{parent}, true, map[string][]int64{
+		"/store/data/infer/c1.root": {1, 2},
+		"/store/data/infer/c2.root": {3, 4},
+	}, (*dbs.API).InsertBulkBlocks)
+	// concurrent bulkblocks API inserts files in chunks
+	chunkSize := dbs.FileChunkSize
+	dbs.FileChunkSize = 50
+	defer func() { dbs.FileChunkSize = chunkSize }()
+	inject("Summer2011-infer-v3", []string{parent}, true, map[string][]int64{
+		"/store/data/infer/c3.root": {2, 3},
+	}, (*dbs.API).InsertBulkBlocksConcurrently)
+	// parents are not inferred unless it is requested
+	inject("Summer2011-infer-v4", []string{parent}, false, map[string][]int64{
+		"/store/data/infer/c4.root": {1},
+	}, (*dbs.API).InsertBulkBlocks)
+
+	rows, err := db.Query(`SELECT F.LOGICAL_FILE_NAME, P.LOGICAL_FILE_NAME FROM FILE_PARENTS FP
+		JOIN FILES F ON F.FILE_ID = FP.THIS_FILE_ID
+		JOIN FILES P ON P.FILE_ID = FP.PARENT_FILE_ID
+		WHERE F.LOGICAL_FILE_NAME LIKE '/store/data/infer/%'
+		ORDER BY 1, 2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var links []string
+	for rows.Next() {
+		var lfn, plfn string
+		if err := rows.Scan(&lfn, &plfn); err != nil {
+			t.Fatal(err)
+		}
+		links = append(links, fmt.Sprintf("%s -> %s", lfn, plfn))
+	}
+	expect := []string{
+		"/store/data/infer/c1.root -> /store/data/infer/p1.root",
+		"/store/data/infer/c2.root -> /store/data/infer/p2.root",
+		"/store/data/infer/c3.root -> /store/data/infer/p1.root",
+		"/store/data/infer/c3.root -> /store/data/infer/p2.root",
+	}
+	if !reflect.DeepEqual(links, expect) {
+		t.Errorf("wrong inferred file parents %v, expected %v", links, expect)
+	}
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -199,6 +200,72 @@ func TestDBSUtilAddParam(t *testing.T) {
 	log.Println("args", args)
 }
 
+// TestDBSUtilAddParamFilters
+func TestDBSUtilAddParamFilters(t *testing.T) {
+	tests := []struct {
+		value string
+		cond  string
+		arg   string
+	}{
+		{"val", "Table.Name = ?", "val"},
+		{"val*", "Table.Name like ?", "val%"},
+		{"!=val", "Table.Name <> ?", "val"},
+		{"!=val*", "Table.Name not like ?", "val%"},
+		{"~val*", "UPPER(Table.Name) like UPPER(?)", "val%"},
+		{"!=~val", "UPPER(Table.Name) <> UPPER(?)", "val"},
+		{">=1e+06", "Table.Name >= ?", "1000000"},
+		{"<10", "Table.Name < ?", "10"},
+	}
+	for _, tt := range tests {
+		params := make(dbs.Record)
+		params["name"] = []string{tt.value}
+		var conds []string
+		var args []interface{}
+		conds, args = dbs.AddParam("name", "Table.Name", params, conds, args)
+		if strings.Trim(conds[0], " ") != tt.cond {
+			t.Errorf("value %s, wrong condition '%s', expect '%s'", tt.value, conds[0], tt.cond)
+		}
+		if args[0] != tt.arg {
+			t.Errorf("value %s, wrong argument '%v', expect '%s'", tt.value, args[0], tt.arg)
+		}
+	}
+
+	// list of values
+	params := make(dbs.Record)
+	params["name"] = []string{"a", "b", "c"}
+	var conds []string
+	var args []interface{}
+	conds, args = dbs.AddParam("name", "Table.Name", params, conds, args)
+	if strings.Trim(conds[0], " ") != "Table.Name IN (?, ?, ?)" {
+		t.Error("fail to add list condition", conds)
+	}
+	if len(args) != 3 || args[0] != "a" || args[2] != "c" {
+		t.Error("fail to add list arguments", args)
+	}
+
+	// repeated values with filter expressions, plain values and patterns
+	// are alternatives while other filters are separate conditions
+	params["name"] = []string{"a", "b*", "!=c", "~d", "e", ">=1e+06"}
+	conds, args = dbs.AddParam("name", "Table.Name", params, nil, nil)
+	expectConds := []string{
+		"( Table.Name IN (?, ?) OR Table.Name like ? )",
+		"Table.Name <> ?",
+		"UPPER(Table.Name) = UPPER(?)",
+		"Table.Name >= ?",
+	}
+	var trimmed []string
+	for _, c := range conds {
+		trimmed = append(trimmed, strings.Trim(c, " "))
+	}
+	if !reflect.DeepEqual(trimmed, expectConds) {
+		t.Errorf("wrong conditions of repeated values %v, expect %v", trimmed, expectConds)
+	}
+	expectArgs := []interface{}{"a", "e", "b%", "c", "d", "1000000"}
+	if !reflect.DeepEqual(args, expectArgs) {
+		t.Errorf("wrong arguments of repeated values %v, expect %v", args, expectArgs)
+	}
+}
+
 // TestDBSUtilFlatLumis
 //gocyclo:ignore
 func TestDBSUtilFlatLumis(t *testing.T) {
@@ -294,6 +361,32 @@ func TestDBSUtilFlatLumis(t *testing.T) {
 	}
 }
 
+// TestDBSUtilLumiMaskRanges
+func TestDBSUtilLumiMaskRanges(t *testing.T) {
+	mask := dbs.LumiMask{
+		"2": [][]int64{{5, 7}, {1, 3}, {4, 4}, {10, 12}},
+		"1": [][]int64{{1, 10}, {5, 20}},
+	}
+	ranges, err := mask.Ranges()
+	if err != nil {
+		t.Fatalf("fail to get lumi mask ranges with error %v", err)
+	}
+	output := []dbs.LumiRange{{1, 1, 20}, {2, 1, 7}, {2, 10, 12}}
+	if !reflect.DeepEqual(ranges, output) {
+		t.Errorf("wrong lumi mask ranges %v, expected %v", ranges, output)
+	}
+	for _, mask := range []dbs.LumiMask{
+		{"abc": [][]int64{{1, 2}}},
+		{"1": [][]int64{{2, 1}}},
+		{"1": [][]int64{{0, 1}}},
+		{"1": [][]int64{{1, 2, 3}}},
+	} {
+		if _, err := mask.Ranges(); err == nil {
+			t.Errorf("invalid lumi mask %v is accepted", mask)
+		}
+	}
+}
+
 // TestDBSRunsConditions
 func TestDBSRunsConditions(t *testing.T) {
 	// run_num=97
@@ -2,6 +2,9 @@ package main
 
 import (
 	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +13,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/dmwm/dbs2go/dbs"
@@ -104,6 +109,1184 @@ func TestHTTPGet(t *testing.T) {
 	}
 }
 
+// TestHTTPGetFields provides test of GET method with fields projection
+func TestHTTPGetFields(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+
+	// add new record to DB that we will query via HTTP request
+	data := []byte(`{"data_tier_name":"RAW-TEST-FIELDS","create_by":"test"}`)
+	api := dbs.API{
+		Reader:   bytes.NewReader(data),
+		Writer:   utils.StdoutWriter(""),
+		CreateBy: "test",
+	}
+	err := api.InsertDataTiers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// request only subset of fields
+	url := "/dbs2go/datatiers?data_tier_name=RAW-TEST-FIELDS&fields=data_tier_name,create_by"
+	rr, err := respRecorder("GET", url, nil, web.DatatiersHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []dbs.Record
+	data = rr.Body.Bytes()
+	err = json.Unmarshal(data, &records)
+	if err != nil {
+		t.Fatalf("unable to unmarshal received data '%s', error %v", string(data), err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("wrong number of records %v", records)
+	}
+	if len(records[0]) != 2 || records[0]["data_tier_name"] != "RAW-TEST-FIELDS" || records[0]["create_by"] != "test" {
+		t.Errorf("wrong projection of record %v", records[0])
+	}
+
+	// request unknown field
+	url = "/dbs2go/datatiers?fields=data_tier_name,file_size"
+	_, err = respRecorder("GET", url, nil, web.DatatiersHandler)
+	if err == nil {
+		t.Errorf("unknown field should be rejected")
+	}
+}
+
+// TestHTTPGetCSV provides test of CSV and TSV output of GET APIs
+func TestHTTPGetCSV(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+
+	// add new record to DB that we will query via HTTP request
+	data := []byte(`{"data_tier_name":"RAW-TEST-CSV","create_by":"test, \"user\""}`)
+	api := dbs.API{
+		Reader:   bytes.NewReader(data),
+		Writer:   utils.StdoutWriter(""),
+		CreateBy: "test, \"user\"",
+	}
+	err := api.InsertDataTiers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// request CSV output via format parameter
+	url := "/dbs2go/datatiers?data_tier_name=RAW-TEST-CSV&fields=data_tier_name,create_by&format=csv"
+	rr, err := respRecorder("GET", url, nil, web.DatatiersHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctype := rr.Header().Get("Content-Type"); ctype != "text/csv" {
+		t.Errorf("wrong content type %s", ctype)
+	}
+	rows, err := csv.NewReader(rr.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("wrong number of CSV rows %v", rows)
+	}
+	if rows[0][0] != "data_tier_name" || rows[0][1] != "create_by" {
+		t.Errorf("wrong CSV header %v", rows[0])
+	}
+	if rows[1][0] != "RAW-TEST-CSV" || rows[1][1] != "test, \"user\"" {
+		t.Errorf("wrong CSV row %v", rows[1])
+	}
+
+	// request gzipped TSV output via Accept header
+	req, _ := http.NewRequest("GET", "/dbs2go/datatiers?data_tier_name=RAW-TEST-CSV", nil)
+	req.Header.Set("Accept", "text/tab-separated-values")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(web.DatatiersHandler).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("wrong status code %d, body %s", rr.Code, rr.Body.String())
+	}
+	gz, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := csv.NewReader(gz)
+	reader.Comma = '\t'
+	rows, err = reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0][1] != "data_tier_name" || rows[1][1] != "RAW-TEST-CSV" {
+		t.Errorf("wrong TSV rows %v", rows)
+	}
+
+	// empty results should contain only header row
+	url = "/dbs2go/datatiers?data_tier_name=RAW-TEST-NONE&fields=data_tier_name&format=csv"
+	rr, err = respRecorder("GET", url, nil, web.DatatiersHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := rr.Body.String(); body != "data_tier_name\n" {
+		t.Errorf("wrong CSV output for empty results '%s'", body)
+	}
+
+	// unknown format should be rejected
+	url = "/dbs2go/datatiers?format=xml"
+	_, err = respRecorder("GET", url, nil, web.DatatiersHandler)
+	if err == nil {
+		t.Errorf("unknown format should be rejected")
+	}
+}
+
+// TestHTTPGetParquet provides test of Parquet output of GET APIs
+func TestHTTPGetParquet(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+
+	// add new record to DB that we will query via HTTP request
+	data := []byte(`{"data_tier_name":"RAW-TEST-PARQUET","create_by":"test"}`)
+	api := dbs.API{
+		Reader:   bytes.NewReader(data),
+		Writer:   utils.StdoutWriter(""),
+		CreateBy: "test",
+	}
+	err := api.InsertDataTiers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := "/dbs2go/datatiers?data_tier_name=RAW-TEST-PARQUET&format=parquet"
+	rr, err := respRecorder("GET", url, nil, web.DatatiersHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctype := rr.Header().Get("Content-Type"); ctype != "application/vnd.apache.parquet" {
+		t.Errorf("wrong content type %s", ctype)
+	}
+	data = rr.Body.Bytes()
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatalf("wrong parquet magic bytes %v", data)
+	}
+	// footer length should fit into the file
+	size := int(data[len(data)-8]) | int(data[len(data)-7])<<8 | int(data[len(data)-6])<<16
+	if size <= 0 || size > len(data)-12 {
+		t.Errorf("wrong parquet footer length %d", size)
+	}
+	footer := data[len(data)-8-size : len(data)-8]
+	for _, col := range []string{"data_tier_id", "data_tier_name", "creation_date", "create_by"} {
+		if !bytes.Contains(footer, []byte(col)) {
+			t.Errorf("column %s is not found in parquet schema", col)
+		}
+	}
+	if !bytes.Contains(data, []byte("RAW-TEST-PARQUET")) {
+		t.Errorf("data tier is not found in parquet data")
+	}
+
+	// blockdump API does not provide tabular output
+	_, err = respRecorder("GET", "/dbs2go/blockdump?format=parquet", nil, web.BlockDumpHandler)
+	if err == nil {
+		t.Errorf("parquet format of blockdump API should be rejected")
+	}
+}
+
+// TestHTTPGetEtag provides test of ETag and conditional GET requests
+func TestHTTPGetEtag(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+
+	web.Config.EtagMaxSize = 1024
+	web.Config.CacheControl = "max-age=300"
+	web.Config.CacheControlApis = map[string]string{"datatiers": "max-age=86400"}
+	defer func() {
+		web.Config.EtagMaxSize = 0
+		web.Config.CacheControl = ""
+		web.Config.CacheControlApis = nil
+	}()
+
+	data := []byte(`{"data_tier_name":"RAW-TEST-ETAG","create_by":"test"}`)
+	api := dbs.API{
+		Reader:   bytes.NewReader(data),
+		Writer:   utils.StdoutWriter(""),
+		CreateBy: "test",
+	}
+	err := api.InsertDataTiers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := "/dbs2go/datatiers?data_tier_name=RAW-TEST-ETAG"
+	rr, err := respRecorder("GET", url, nil, web.DatatiersHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := rr.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("ETag is not set")
+	}
+	if val := rr.Header().Get("Cache-Control"); val != "max-age=86400" {
+		t.Errorf("wrong Cache-Control %s", val)
+	}
+	if !bytes.Contains(rr.Body.Bytes(), []byte("RAW-TEST-ETAG")) {
+		t.Errorf("data tier is not found in response %s", rr.Body.String())
+	}
+
+	// ETag should depend on response content
+	rr, err = respRecorder("GET", "/dbs2go/datatiers?data_tier_name=RAW-TEST-CSV", nil, web.DatatiersHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val := rr.Header().Get("ETag"); val == "" || val == etag {
+		t.Errorf("wrong ETag %s of different content", val)
+	}
+
+	// conditional request with matching ETag
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("If-None-Match", "W/"+etag)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(web.DatatiersHandler).ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("wrong status code %d, expect %d", rr.Code, http.StatusNotModified)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("not modified response has body %s", rr.Body.String())
+	}
+
+	// conditional request with different ETag
+	req, _ = http.NewRequest("GET", url, nil)
+	req.Header.Set("If-None-Match", `"0-abc"`)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(web.DatatiersHandler).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("wrong status code %d, expect %d", rr.Code, http.StatusOK)
+	}
+
+	// default Cache-Control is used by other APIs
+	rr, err = respRecorder("GET", "/dbs2go/datatypes", nil, web.DataTypesHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val := rr.Header().Get("Cache-Control"); val != "max-age=300" {
+		t.Errorf("wrong Cache-Control %s", val)
+	}
+
+	// responses larger than etag_max_size are streamed without ETag
+	web.Config.EtagMaxSize = 10
+	rr, err = respRecorder("GET", url, nil, web.DatatiersHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val := rr.Header().Get("ETag"); val != "" {
+		t.Errorf("streamed response has ETag %s", val)
+	}
+	if !bytes.Contains(rr.Body.Bytes(), []byte("RAW-TEST-ETAG")) {
+		t.Errorf("data tier is not found in streamed response %s", rr.Body.String())
+	}
+}
+
+// TestHTTPGetCache provides test of response cache of GET APIs
+func TestHTTPGetCache(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+
+	dbs.ResponseCache = utils.NewLRUCache(1024 * 1024)
+	web.Config.ResponseCacheTTLs = map[string]int{"datatiers": 60}
+	web.Config.ResponseCacheEntrySize = 1024
+	defer func() {
+		dbs.ResponseCache = nil
+		web.Config.ResponseCacheTTLs = nil
+		web.Config.ResponseCacheEntrySize = 0
+	}()
+
+	// helper function to insert data tier
+	insertTier := func(tier string) {
+		data := []byte(fmt.Sprintf(`{"data_tier_name":"%s","create_by":"test"}`, tier))
+		api := dbs.API{
+			Reader:   bytes.NewReader(data),
+			Writer:   utils.StdoutWriter(""),
+			CreateBy: "test",
+		}
+		if err := api.InsertDataTiers(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// helper function to get data tiers and cache status
+	getTiers := func(rurl string, hdrs map[string]string) (string, string) {
+		req, _ := http.NewRequest("GET", rurl, nil)
+		for k, v := range hdrs {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(web.DatatiersHandler).ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("wrong status code %d of %s", rr.Code, rurl)
+		}
+		return rr.Body.String(), rr.Header().Get(web.CacheHeader)
+	}
+
+	insertTier("RAW-TEST-CACHE1")
+	rurl := "/dbs2go/datatiers?data_tier_name=RAW-TEST-CACHE*&fields=data_tier_name"
+	body, status := getTiers(rurl, nil)
+	if status != "miss" || !strings.Contains(body, "RAW-TEST-CACHE1") {
+		t.Errorf("wrong cache status %s or body %s", status, body)
+	}
+	// the same request with different order of parameters is served from cache
+	cached, status := getTiers("/dbs2go/datatiers?fields=data_tier_name&data_tier_name=RAW-TEST-CACHE*", nil)
+	if status != "hit" || cached != body {
+		t.Errorf("wrong cache status %s or body %s", status, cached)
+	}
+	// compressed response is served from cache too
+	_, status = getTiers(rurl, map[string]string{"Accept-Encoding": "gzip"})
+	if status != "hit" {
+		t.Errorf("wrong cache status %s of gzip response", status)
+	}
+	// other output format is cached separately
+	_, status = getTiers(rurl+"&format=csv", nil)
+	if status != "miss" {
+		t.Errorf("wrong cache status %s of csv response", status)
+	}
+
+	// cache is invalidated by insertion of new data tier
+	insertTier("RAW-TEST-CACHE2")
+	body, status = getTiers(rurl, nil)
+	if status != "miss" || !strings.Contains(body, "RAW-TEST-CACHE2") {
+		t.Errorf("wrong cache status %s or body %s after insertion", status, body)
+	}
+
+	// client may bypass the cache
+	_, status = getTiers(rurl, map[string]string{"Cache-Control": "no-cache"})
+	if status != "bypass" {
+		t.Errorf("wrong cache status %s, expect bypass", status)
+	}
+
+	// APIs without TTL are not cached
+	req, _ := http.NewRequest("GET", "/dbs2go/datatypes", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(web.DataTypesHandler).ServeHTTP(rr, req)
+	if status := rr.Header().Get(web.CacheHeader); status != "" {
+		t.Errorf("wrong cache status %s of non cached API", status)
+	}
+	if dbs.ResponseCache.Hits != 2 {
+		t.Errorf("wrong number of cache hits %d", dbs.ResponseCache.Hits)
+	}
+}
+
+// TestHTTPGetReplicas provides test of routing of GET APIs to DB replicas
+func TestHTTPGetReplicas(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+
+	// create replica DB with its own data tier
+	rdb, err := sql.Open("sqlite3", fmt.Sprintf("%s/replica.db", t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdb.Close()
+	schema, err := os.ReadFile("../static/schema/sqlite-schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rdb.Exec(string(schema)); err != nil {
+		t.Fatal(err)
+	}
+	for _, stm := range []string{
+		"INSERT INTO DATASET_ACCESS_TYPES (DATASET_ACCESS_TYPE_ID, DATASET_ACCESS_TYPE) VALUES (1, 'VALID')",
+		"INSERT INTO DATA_TIERS (DATA_TIER_ID, DATA_TIER_NAME, CREATION_DATE, CREATE_BY) VALUES (1, 'RAW-TEST-REPLICA', 1, 'test')",
+	} {
+		if _, err := rdb.Exec(stm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dbs.Replicas = []*dbs.Replica{dbs.NewReplica("test", rdb)}
+	defer func() { dbs.Replicas = nil }()
+
+	// writer API should use primary DB
+	data := []byte(`{"data_tier_name":"RAW-TEST-PRIMARY","create_by":"test"}`)
+	api := dbs.API{
+		Reader:   bytes.NewReader(data),
+		Writer:   utils.StdoutWriter(""),
+		CreateBy: "test",
+	}
+	if err := api.InsertDataTiers(); err != nil {
+		t.Fatal(err)
+	}
+
+	// helper function to get response of datatiers API
+	getTiers := func(rurl string) string {
+		rr, err := respRecorder("GET", rurl, nil, web.DatatiersHandler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rr.Body.String()
+	}
+	body := getTiers("/dbs2go/datatiers?data_tier_name=RAW-TEST-*")
+	if !strings.Contains(body, "RAW-TEST-REPLICA") || strings.Contains(body, "RAW-TEST-PRIMARY") {
+		t.Errorf("datatiers are not read from replica %s", body)
+	}
+	body = getTiers("/dbs2go/datatiers?data_tier_name=RAW-TEST-*&consistency=strong")
+	if strings.Contains(body, "RAW-TEST-REPLICA") || !strings.Contains(body, "RAW-TEST-PRIMARY") {
+		t.Errorf("datatiers are not read from primary DB with strong consistency %s", body)
+	}
+	if _, err := respRecorder("GET", "/dbs2go/datatiers?consistency=weak", nil, web.DatatiersHandler); err == nil {
+		t.Errorf("invalid consistency value should be rejected")
+	}
+
+	// unhealthy replica should be skipped
+	dbs.CheckReplicas()
+	if !dbs.Replicas[0].Healthy() {
+		t.Fatal("replica should be healthy")
+	}
+	if _, err := rdb.Exec("DROP TABLE DATASET_ACCESS_TYPES"); err != nil {
+		t.Fatal(err)
+	}
+	dbs.CheckReplicas()
+	if dbs.Replicas[0].Healthy() {
+		t.Fatal("replica should not be healthy")
+	}
+	body = getTiers("/dbs2go/datatiers?data_tier_name=RAW-TEST-*")
+	if !strings.Contains(body, "RAW-TEST-PRIMARY") {
+		t.Errorf("datatiers are not read from primary DB when replica is unhealthy %s", body)
+	}
+}
+
+// TestHTTPGetStmtCache provides test of prepared statements cache
+func TestHTTPGetStmtCache(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+
+	dbs.StmtCacheSize = 2
+	dbs.StmtCacheMetrics = dbs.StmtCacheStats{}
+	defer func() {
+		dbs.CloseStmtCache(db)
+		dbs.StmtCacheSize = 0
+	}()
+
+	// helper function to get response of given API
+	get := func(rurl string, hdlr func(http.ResponseWriter, *http.Request)) string {
+		rr, err := respRecorder("GET", rurl, nil, hdlr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rr.Body.String()
+	}
+
+	rurl := "/dbs2go/datatiers?data_tier_name=RAW-TEST-STMT*"
+	if body := get(rurl, web.DatatiersHandler); strings.Contains(body, "RAW-TEST-STMT") {
+		t.Errorf("unexpected data tier %s", body)
+	}
+	// writer API does not use the cache
+	data := []byte(`{"data_tier_name":"RAW-TEST-STMT","create_by":"test"}`)
+	api := dbs.API{
+		Reader:   bytes.NewReader(data),
+		Writer:   utils.StdoutWriter(""),
+		CreateBy: "test",
+	}
+	if err := api.InsertDataTiers(); err != nil {
+		t.Fatal(err)
+	}
+	// the same query is executed via cached statement and sees new data
+	if body := get(rurl, web.DatatiersHandler); !strings.Contains(body, "RAW-TEST-STMT") {
+		t.Errorf("data tier is not found via cached statement %s", body)
+	}
+	if dbs.StmtCacheMetrics.Hits != 1 || dbs.StmtCacheMetrics.Misses != 1 {
+		t.Errorf("wrong statement cache metrics %+v", dbs.StmtCacheMetrics)
+	}
+
+	// cache size is bounded by least recently used statements eviction
+	get("/dbs2go/datatypes", web.DataTypesHandler)
+	get("/dbs2go/primarydstypes", web.PrimaryDSTypesHandler)
+	if n := dbs.StmtCacheLen(db); n != 2 {
+		t.Errorf("wrong number of cached statements %d", n)
+	}
+	if dbs.StmtCacheMetrics.Evictions != 1 {
+		t.Errorf("wrong number of evicted statements %d", dbs.StmtCacheMetrics.Evictions)
+	}
+	// evicted statement is prepared again
+	if body := get(rurl, web.DatatiersHandler); !strings.Contains(body, "RAW-TEST-STMT") {
+		t.Errorf("data tier is not found after eviction %s", body)
+	}
+	if dbs.StmtCacheMetrics.Misses != 4 {
+		t.Errorf("wrong number of statement cache misses %d", dbs.StmtCacheMetrics.Misses)
+	}
+}
+
+// TestHTTPGetGuardrails provides test of query guardrails of GET APIs
+func TestHTTPGetGuardrails(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+
+	if _, err := dbs.GetApiSelective("files"); err != nil {
+		t.Fatal(err)
+	}
+	// use data tiers to test downgraded and query plan guardrails
+	dbs.ApiSelectiveMap["datatiers"] = []string{"data_tier_name"}
+	dbs.GuardrailAction = "reject"
+	dbs.GuardrailMaxRows = 2
+	defer func() {
+		delete(dbs.ApiSelectiveMap, "datatiers")
+		dbs.GuardrailAction = ""
+		dbs.GuardrailMaxRows = 0
+		dbs.GuardrailMaxCost = 0
+		web.Config.GuardrailExemptRoles = nil
+		web.Config.GuardrailExemptGroups = nil
+	}()
+	for _, tier := range []string{"RAW-TEST-GUARD1", "RAW-TEST-GUARD2", "RAW-TEST-GUARD3"} {
+		data := []byte(fmt.Sprintf(`{"data_tier_name":"%s","create_by":"test"}`, tier))
+		api := dbs.API{
+			Reader:   bytes.NewReader(data),
+			Writer:   utils.StdoutWriter(""),
+			CreateBy: "test",
+		}
+		if err := api.InsertDataTiers(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// helper function to get response of given API
+	get := func(rurl string, hdlr func(http.ResponseWriter, *http.Request), hdrs map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", rurl, nil)
+		for k, v := range hdrs {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(hdlr).ServeHTTP(rr, req)
+		return rr
+	}
+	guardrailCode := fmt.Sprintf(`"code":%d`, dbs.QueryGuardrailErrorCode)
+
+	// requests without selective parameters are rejected
+	for _, rurl := range []string{
+		"/dbs2go/files",
+		"/dbs2go/files?dataset=/*/*/*",
+		"/dbs2go/files?dataset=/ZMM*/*/*",
+		"/dbs2go/files?dataset=~/zmm/*/*",
+		"/dbs2go/files?dataset=!=/ZMM/abc/RAW",
+		"/dbs2go/files?logical_file_name=/store/data/*",
+		"/dbs2go/files?run_num=1",
+	} {
+		rr := get(rurl, web.FilesHandler, nil)
+		if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), guardrailCode) {
+			t.Errorf("request %s is not rejected, status %d body %s", rurl, rr.Code, rr.Body.String())
+		}
+	}
+	for _, rurl := range []string{
+		"/dbs2go/files?dataset=/ZMM/*/RAW",
+		"/dbs2go/files?block_name=/ZMM/abc/RAW%23*",
+		"/dbs2go/files?logical_file_name=/store/data/Run2018A/*",
+		"/dbs2go/files?logical_file_name=[/store/a.root,/store/b.root]&run_num=1",
+		"/dbs2go/runs?run_num=1",
+	} {
+		hdlr := web.FilesHandler
+		if strings.HasPrefix(rurl, "/dbs2go/runs") {
+			hdlr = web.RunsHandler
+		}
+		if rr := get(rurl, hdlr, nil); rr.Code != http.StatusOK {
+			t.Errorf("request %s is rejected, status %d body %s", rurl, rr.Code, rr.Body.String())
+		}
+	}
+
+	// admin tools are exempted from guardrails
+	web.Config.GuardrailExemptRoles = []string{"admin"}
+	web.Config.GuardrailExemptGroups = []string{"dbs"}
+	if rr := get("/dbs2go/files?dataset=/*/*/*", web.FilesHandler, map[string]string{"Cms-Authz-Admin": "group:dbs"}); rr.Code != http.StatusOK {
+		t.Errorf("exempted request is rejected, status %d body %s", rr.Code, rr.Body.String())
+	}
+
+	// downgraded requests get limited output
+	dbs.GuardrailAction = "downgrade"
+	rr := get("/dbs2go/datatiers?data_tier_name=*", web.DatatiersHandler, nil)
+	var records []dbs.Record
+	if err := json.Unmarshal(rr.Body.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || rr.Header().Get(dbs.GuardrailHeader) == "" {
+		t.Errorf("request is not downgraded, header '%s' records %v", rr.Header().Get(dbs.GuardrailHeader), records)
+	}
+	// count can't be limited and therefore it is rejected
+	rr = get("/dbs2go/files?dataset=/*/*/*&count=true", web.FilesHandler, nil)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), guardrailCode) {
+		t.Errorf("count request is not rejected, status %d", rr.Code)
+	}
+
+	// query plan with full table scan exceeds max cost, the guardrail error
+	// is nested in API query error
+	dbs.GuardrailAction = "reject"
+	dbs.GuardrailMaxCost = 0.5
+	rr = get("/dbs2go/datatiers?data_tier_name=RAW-TEST-GUARD*", web.DatatiersHandler, nil)
+	nestedCode := fmt.Sprintf("Code:%d", dbs.QueryGuardrailErrorCode)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), nestedCode) {
+		t.Errorf("expensive query is not rejected, status %d body %s", rr.Code, rr.Body.String())
+	}
+	rr = get("/dbs2go/datatiers?data_tier_name=RAW-TEST-GUARD1", web.DatatiersHandler, nil)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "RAW-TEST-GUARD1") {
+		t.Errorf("indexed query is rejected, status %d body %s", rr.Code, rr.Body.String())
+	}
+}
+
+// TestHTTPGetLineage provides test of dataset lineage API
+func TestHTTPGetLineage(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+	fx := newFixture(t, db)
+	defer fx.cleanup()
+	defer func() {
+		dbs.LineageMethod = ""
+	}()
+
+	// lineage with diamond: RAW -> RECO-A, RAW -> RECO-B, both RECOs -> AOD
+	// and AOD -> MINIAOD
+	datasets := map[int]string{
+		9001: "/ZMM/Lineage-v1/RAW",
+		9002: "/ZMM/Lineage-A/RECO",
+		9003: "/ZMM/Lineage-B/RECO",
+		9004: "/ZMM/Lineage-v1/AOD",
+		9005: "/ZMM/Lineage-v1/MINIAOD",
+	}
+	for did, dataset := range datasets {
+		fx.insert("DATASETS", "DATASET_ID, DATASET", did, dataset)
+	}
+	for _, rel := range [][]int{{9002, 9001}, {9003, 9001}, {9004, 9002}, {9004, 9003}, {9005, 9004}} {
+		fx.insert("DATASET_PARENTS", "THIS_DATASET_ID, PARENT_DATASET_ID", rel[0], rel[1])
+	}
+
+	// helper function to get lineage graph of given request
+	lineage := func(rurl string) dbs.LineageGraph {
+		rr, err := respRecorder("GET", rurl, nil, web.LineageHandler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var graphs []dbs.LineageGraph
+		if err := json.Unmarshal(rr.Body.Bytes(), &graphs); err != nil || len(graphs) != 1 {
+			t.Fatalf("unable to parse lineage of %s, error %v body %s", rurl, err, rr.Body.String())
+		}
+		return graphs[0]
+	}
+	// helper function to represent graph nodes as dataset:generation list
+	nodes := func(g dbs.LineageGraph) string {
+		var out []string
+		for _, n := range g.Nodes {
+			out = append(out, fmt.Sprintf("%s:%d", n.Dataset, n.Generation))
+		}
+		return strings.Join(out, ",")
+	}
+
+	for _, method := range []string{"recursive", "iterative"} {
+		dbs.LineageMethod = method
+		// diamond ancestors are reported once
+		g := lineage("/dbs2go/lineage?dataset=/ZMM/Lineage-v1/MINIAOD")
+		expect := "/ZMM/Lineage-v1/RAW:-3,/ZMM/Lineage-A/RECO:-2,/ZMM/Lineage-B/RECO:-2,/ZMM/Lineage-v1/AOD:-1,/ZMM/Lineage-v1/MINIAOD:0"
+		if nodes(g) != expect || len(g.Edges) != 5 {
+			t.Errorf("%s method: wrong lineage nodes %s edges %v", method, nodes(g), g.Edges)
+		}
+		// depth limits number of generations
+		g = lineage("/dbs2go/lineage?dataset=/ZMM/Lineage-v1/MINIAOD&depth=2")
+		expect = "/ZMM/Lineage-A/RECO:-2,/ZMM/Lineage-B/RECO:-2,/ZMM/Lineage-v1/AOD:-1,/ZMM/Lineage-v1/MINIAOD:0"
+		if nodes(g) != expect || len(g.Edges) != 3 {
+			t.Errorf("%s method: wrong lineage of depth 2 nodes %s edges %v", method, nodes(g), g.Edges)
+		}
+		// descendants of the diamond root
+		g = lineage("/dbs2go/lineage?dataset=/ZMM/Lineage-v1/RAW&direction=down")
+		expect = "/ZMM/Lineage-v1/RAW:0,/ZMM/Lineage-A/RECO:1,/ZMM/Lineage-B/RECO:1,/ZMM/Lineage-v1/AOD:2,/ZMM/Lineage-v1/MINIAOD:3"
+		if nodes(g) != expect || len(g.Edges) != 5 {
+			t.Errorf("%s method: wrong descendants nodes %s edges %v", method, nodes(g), g.Edges)
+		}
+		// both directions of one side of the diamond
+		g = lineage("/dbs2go/lineage?dataset=/ZMM/Lineage-A/RECO&direction=both&depth=1")
+		expect = "/ZMM/Lineage-v1/RAW:-1,/ZMM/Lineage-A/RECO:0,/ZMM/Lineage-v1/AOD:1"
+		if nodes(g) != expect || len(g.Edges) != 2 {
+			t.Errorf("%s method: wrong lineage in both directions nodes %s edges %v", method, nodes(g), g.Edges)
+		}
+	}
+
+	// Graphviz DOT output
+	rr, err := respRecorder("GET", "/dbs2go/lineage?dataset=/ZMM/Lineage-v1/AOD&depth=1&format=dot", nil, web.LineageHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := rr.Body.String()
+	if !strings.HasPrefix(body, "digraph lineage {") ||
+		!strings.Contains(body, `"/ZMM/Lineage-v1/AOD" [style=bold];`) ||
+		!strings.Contains(body, `"/ZMM/Lineage-A/RECO" -> "/ZMM/Lineage-v1/AOD";`) {
+		t.Errorf("wrong DOT output %s", body)
+	}
+	if ctype := rr.Header().Get("Content-Type"); ctype != "text/vnd.graphviz" {
+		t.Errorf("wrong content type of DOT output %s", ctype)
+	}
+
+	// invalid requests
+	for _, rurl := range []string{
+		"/dbs2go/lineage?dataset=/ZMM/Lineage-v1/AOD&direction=sideways",
+		"/dbs2go/lineage?dataset=/ZMM/Lineage-v1/AOD&depth=0",
+		"/dbs2go/lineage?dataset=/ZMM/*/AOD",
+		"/dbs2go/lineage?dataset=/ZMM/Lineage-v1/AOD&format=csv",
+		"/dbs2go/datatiers?format=dot",
+	} {
+		hdlr := web.LineageHandler
+		if strings.HasPrefix(rurl, "/dbs2go/datatiers") {
+			hdlr = web.DatatiersHandler
+		}
+		req, _ := http.NewRequest("GET", rurl, nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(hdlr).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("request %s is not rejected, status %d body %s", rurl, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+// TestHTTPPostFileAncestry provides test of file ancestry API
+func TestHTTPPostFileAncestry(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+	fx := newFixture(t, db)
+	defer fx.cleanup()
+	defer func() {
+		dbs.FileAncestryBatchSize = 1000
+	}()
+
+	// file ancestry: RAW files r1, r2 -> RECO files c1 (r1, r2), c2 (r1),
+	// both RECOs -> AOD file a1 -> NANOAOD files n1, n2
+	tiers := []string{"RAW-TEST-ANC", "RECO-TEST-ANC", "AOD-TEST-ANC", "NANOAOD-TEST-ANC"}
+	for i, tier := range tiers {
+		fx.insert("DATA_TIERS", "DATA_TIER_ID, DATA_TIER_NAME", 9101+i, tier)
+		dataset := fmt.Sprintf("/ZMM/Ancestry-v1/%s", tier)
+		fx.insert("DATASETS", "DATASET_ID, DATASET, DATA_TIER_ID", 9101+i, dataset, 9101+i)
+	}
+	files := map[int][]interface{}{
+		9101: {"/store/anc/r1.root", 9101},
+		9102: {"/store/anc/r2.root", 9101},
+		9103: {"/store/anc/c1.root", 9102},
+		9104: {"/store/anc/c2.root", 9102},
+		9105: {"/store/anc/a1.root", 9103},
+		9106: {"/store/anc/n1.root", 9104},
+		9107: {"/store/anc/n2.root", 9104},
+	}
+	for fid, vals := range files {
+		fx.insert("FILES", "FILE_ID, LOGICAL_FILE_NAME, DATASET_ID", fid, vals[0], vals[1])
+	}
+	for _, rel := range [][]int{{9103, 9101}, {9103, 9102}, {9104, 9101}, {9105, 9103}, {9105, 9104}, {9106, 9105}, {9107, 9105}} {
+		fx.insert("FILE_PARENTS", "THIS_FILE_ID, PARENT_FILE_ID", rel[0], rel[1])
+	}
+
+	// helper function to get file ancestry of given request and represent
+	// it as lfn:related:generation list
+	ancestry := func(payload string) string {
+		rr, err := respRecorder("POST", "/dbs2go/fileancestry", strings.NewReader(payload), web.FileAncestryHandler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var records []dbs.FileAncestor
+		if err := json.Unmarshal(rr.Body.Bytes(), &records); err != nil {
+			t.Fatalf("unable to parse file ancestry of %s, error %v body %s", payload, err, rr.Body.String())
+		}
+		var out []string
+		for _, r := range records {
+			out = append(out, fmt.Sprintf("%s:%s:%d",
+				strings.TrimPrefix(r.LogicalFileName, "/store/anc/"),
+				strings.TrimPrefix(r.RelatedLogicalFileName, "/store/anc/"),
+				r.Generation))
+		}
+		return strings.Join(out, ",")
+	}
+
+	// RAW files behind NANOAOD files, diamond ancestors are reported once
+	// per given file, files of each generation are looked up one per batch
+	dbs.FileAncestryBatchSize = 1
+	payload := `{"logical_file_name":["/store/anc/n1.root","/store/anc/n2.root"],"data_tier_name":"RAW-TEST-ANC"}`
+	expect := "n1.root:r1.root:-3,n1.root:r2.root:-3,n2.root:r1.root:-3,n2.root:r2.root:-3"
+	if out := ancestry(payload); out != expect {
+		t.Errorf("wrong RAW ancestors %s", out)
+	}
+	dbs.FileAncestryBatchSize = 1000
+	// depth limits number of generations
+	payload = `{"logical_file_name":["/store/anc/n1.root"],"depth":2}`
+	expect = "n1.root:c1.root:-2,n1.root:c2.root:-2,n1.root:a1.root:-1"
+	if out := ancestry(payload); out != expect {
+		t.Errorf("wrong ancestors of depth 2 %s", out)
+	}
+	// descendants of RAW file
+	payload = `{"logical_file_name":["/store/anc/r2.root"],"direction":"down"}`
+	expect = "r2.root:c1.root:1,r2.root:a1.root:2,r2.root:n1.root:3,r2.root:n2.root:3"
+	if out := ancestry(payload); out != expect {
+		t.Errorf("wrong descendants %s", out)
+	}
+	// both directions of RECO file
+	payload = `{"logical_file_name":["/store/anc/c2.root"],"direction":"both","depth":1}`
+	expect = "c2.root:r1.root:-1,c2.root:a1.root:1"
+	if out := ancestry(payload); out != expect {
+		t.Errorf("wrong ancestry in both directions %s", out)
+	}
+
+	// invalid requests
+	for _, payload := range []string{
+		`{"direction":"up"}`,
+		`{"logical_file_name":["/store/anc/*"]}`,
+		`{"logical_file_name":["/store/anc/n1.root"],"direction":"sideways"}`,
+		`{"logical_file_name":["/store/anc/n1.root"],"depth":0}`,
+	} {
+		if _, err := respRecorder("POST", "/dbs2go/fileancestry", strings.NewReader(payload), web.FileAncestryHandler); err == nil {
+			t.Errorf("request %s is not rejected", payload)
+		}
+	}
+}
+
+// TestHTTPPostFilesByMask provides test of files by lumi mask API
+func TestHTTPPostFilesByMask(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+	fx := newFixture(t, db)
+	defer fx.cleanup()
+
+	// dataset with three files: f1 covers run 1 lumis 1-10, f2 covers run 1
+	// lumis 11-20 and run 2 lumis 1-5, f3 covers run 3 lumis 1-5
+	dataset := "/ZMM/Mask-v1/AOD"
+	fx.insert("DATASETS", "DATASET_ID, DATASET", 9201, dataset)
+	lumis := map[int][][]int64{
+		9201: {{1, 1, 10}},
+		9202: {{1, 11, 20}, {2, 1, 5}},
+		9203: {{3, 1, 5}},
+	}
+	for fid, ranges := range lumis {
+		lfn := fmt.Sprintf("/store/mask/f%d.root", fid-9200)
+		fx.insert("FILES", "FILE_ID, LOGICAL_FILE_NAME, DATASET_ID", fid, lfn, 9201)
+		for _, r := range ranges {
+			for lumi := r[1]; lumi <= r[2]; lumi++ {
+				fx.insert("FILE_LUMIS", "RUN_NUM, LUMI_SECTION_NUM, FILE_ID, EVENT_COUNT", r[0], lumi, fid, 10)
+			}
+		}
+	}
+
+	// helper function to get files of given request
+	files := func(payload string) []dbs.FileMaskRecord {
+		rr, err := respRecorder("POST", "/dbs2go/filesbymask", strings.NewReader(payload), web.FilesByMaskHandler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var records []dbs.FileMaskRecord
+		if err := json.Unmarshal(rr.Body.Bytes(), &records); err != nil {
+			t.Fatalf("unable to parse files of %s, error %v body %s", payload, err, rr.Body.String())
+		}
+		return records
+	}
+
+	// mask overlaps with f1 and f2 only, overlapping mask ranges are merged
+	payload := fmt.Sprintf(`{"dataset":"%s","lumi_mask":{"1":[[5,12],[8,9]],"2":[[4,4],[6,9]],"4":[[1,5]]},"lumis":true}`, dataset)
+	records := files(payload)
+	expect := []dbs.FileMaskRecord{
+		{LogicalFileName: "/store/mask/f1.root", LumiCount: 6, EventCount: 60, LumiMask: dbs.LumiMask{"1": {{5, 10}}}},
+		{LogicalFileName: "/store/mask/f2.root", LumiCount: 3, EventCount: 30, LumiMask: dbs.LumiMask{"1": {{11, 12}}, "2": {{4, 4}}}},
+	}
+	if !reflect.DeepEqual(records, expect) {
+		t.Errorf("wrong files by mask %+v, expected %+v", records, expect)
+	}
+	// lumis of files are provided on demand
+	payload = fmt.Sprintf(`{"dataset":"%s","lumi_mask":{"3":[[5,100]]}}`, dataset)
+	records = files(payload)
+	if len(records) != 1 || records[0].LogicalFileName != "/store/mask/f3.root" || records[0].LumiMask != nil {
+		t.Errorf("wrong files by mask without lumis %+v", records)
+	}
+	// large mask is loaded into temp table in chunks, i.e. every odd lumi
+	// of run 1 is a separate mask range
+	var odd []string
+	for lumi := 1; lumi < 1000; lumi += 2 {
+		odd = append(odd, fmt.Sprintf("[%d,%d]", lumi, lumi))
+	}
+	payload = fmt.Sprintf(`{"dataset":"%s","lumi_mask":{"1":[%s]}}`, dataset, strings.Join(odd, ","))
+	records = files(payload)
+	if len(records) != 2 || records[0].LumiCount != 5 || records[1].LumiCount != 5 {
+		t.Errorf("wrong files by large mask %+v", records)
+	}
+	// mask without overlap
+	payload = fmt.Sprintf(`{"dataset":"%s","lumi_mask":{"5":[[1,100]]}}`, dataset)
+	if records = files(payload); len(records) != 0 {
+		t.Errorf("wrong files by mask without overlap %+v", records)
+	}
+
+	// invalid requests
+	for _, payload := range []string{
+		`{"lumi_mask":{"1":[[1,2]]}}`,
+		`{"dataset":"/ZMM/*/AOD","lumi_mask":{"1":[[1,2]]}}`,
+		fmt.Sprintf(`{"dataset":"%s"}`, dataset),
+		fmt.Sprintf(`{"dataset":"%s","lumi_mask":{"1":[[2,1]]}}`, dataset),
+		fmt.Sprintf(`{"dataset":"%s","lumi_mask":{"1":[1,2]}}`, dataset),
+	} {
+		if _, err := respRecorder("POST", "/dbs2go/filesbymask", strings.NewReader(payload), web.FilesByMaskHandler); err == nil {
+			t.Errorf("request %s is not rejected", payload)
+		}
+	}
+}
+
+// TestHTTPGetLumiMask provides test of lumi mask API
+func TestHTTPGetLumiMask(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+	fx := newFixture(t, db)
+	defer fx.cleanup()
+
+	// dataset with two blocks: f1 and f2 belong to block #1 and f3 belongs
+	// to block #2, f3 contains part of lumi 5 of run 1 of f1
+	dataset := "/ZMM/LumiMask-v1/AOD"
+	fx.insert("DATASETS", "DATASET_ID, DATASET", 9301, dataset)
+	for _, bid := range []int{9301, 9302} {
+		block := fmt.Sprintf("%s#%d", dataset, bid-9300)
+		fx.insert("BLOCKS", "BLOCK_ID, BLOCK_NAME, DATASET_ID", bid, block, 9301)
+	}
+	lumis := map[int][][]int64{
+		9301: {{1, 1, 10}},
+		9302: {{1, 11, 12}, {1, 15, 20}, {2, 1, 5}},
+		9303: {{3, 1, 2}, {1, 5, 5}},
+	}
+	for fid, ranges := range lumis {
+		lfn := fmt.Sprintf("/store/lumimask/f%d.root", fid-9300)
+		bid := 9301
+		if fid == 9303 {
+			bid = 9302
+		}
+		fx.insert("FILES", "FILE_ID, LOGICAL_FILE_NAME, DATASET_ID, BLOCK_ID", fid, lfn, 9301, bid)
+		for _, r := range ranges {
+			for lumi := r[1]; lumi <= r[2]; lumi++ {
+				fx.insert("FILE_LUMIS", "RUN_NUM, LUMI_SECTION_NUM, FILE_ID, EVENT_COUNT", r[0], lumi, fid, 10)
+			}
+		}
+	}
+
+	// helper function to get lumi mask of given request
+	lumiMask := func(method, rurl, payload string) dbs.LumiMaskRecord {
+		var reader io.Reader
+		if payload != "" {
+			reader = strings.NewReader(payload)
+		}
+		rr, err := respRecorder(method, rurl, reader, web.LumiMaskHandler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var records []dbs.LumiMaskRecord
+		if err := json.Unmarshal(rr.Body.Bytes(), &records); err != nil || len(records) != 1 {
+			t.Fatalf("unable to parse lumi mask of %s, error %v body %s", rurl, err, rr.Body.String())
+		}
+		return records[0]
+	}
+
+	// dataset coverage with event counts of lumi ranges
+	rec := lumiMask("GET", "/dbs2go/lumimask?dataset="+dataset+"&events=1", "")
+	expect := dbs.LumiMaskRecord{
+		LumiMask:    dbs.LumiMask{"1": {{1, 12}, {15, 20}}, "2": {{1, 5}}, "3": {{1, 2}}},
+		EventCounts: map[string][]int64{"1": {130, 60}, "2": {50}, "3": {20}},
+		RunCount:    3,
+		LumiCount:   25,
+		EventCount:  260,
+	}
+	if !reflect.DeepEqual(rec, expect) {
+		t.Errorf("wrong dataset lumi mask %+v, expected %+v", rec, expect)
+	}
+	// block coverage without event counts
+	rec = lumiMask("GET", "/dbs2go/lumimask?block_name="+strings.Replace(dataset, "/", "%2F", -1)+"%231", "")
+	mask := dbs.LumiMask{"1": {{1, 12}, {15, 20}}, "2": {{1, 5}}}
+	if !reflect.DeepEqual(rec.LumiMask, mask) || rec.EventCounts != nil || rec.LumiCount != 23 {
+		t.Errorf("wrong block lumi mask %+v", rec)
+	}
+	// run selection
+	rec = lumiMask("GET", "/dbs2go/lumimask?dataset="+dataset+"&run_num=2", "")
+	if !reflect.DeepEqual(rec.LumiMask, dbs.LumiMask{"2": {{1, 5}}}) {
+		t.Errorf("wrong lumi mask of single run %+v", rec)
+	}
+	rec = lumiMask("GET", "/dbs2go/lumimask?dataset="+dataset+"&run_num=2&run_num=3", "")
+	if !reflect.DeepEqual(rec.LumiMask, dbs.LumiMask{"2": {{1, 5}}, "3": {{1, 2}}}) {
+		t.Errorf("wrong lumi mask of list of runs %+v", rec)
+	}
+	// list of files via POST request
+	payload := `{"logical_file_name":["/store/lumimask/f1.root","/store/lumimask/f3.root"],"events":1}`
+	rec = lumiMask("POST", "/dbs2go/lumimask", payload)
+	expect = dbs.LumiMaskRecord{
+		LumiMask:    dbs.LumiMask{"1": {{1, 10}}, "3": {{1, 2}}},
+		EventCounts: map[string][]int64{"1": {110}, "3": {20}},
+		RunCount:    2,
+		LumiCount:   12,
+		EventCount:  130,
+	}
+	if !reflect.DeepEqual(rec, expect) {
+		t.Errorf("wrong files lumi mask %+v, expected %+v", rec, expect)
+	}
+
+	// invalid requests
+	for _, rurl := range []string{
+		"/dbs2go/lumimask",
+		"/dbs2go/lumimask?dataset=/ZMM/*/AOD",
+		"/dbs2go/lumimask?dataset=" + dataset + "&block_name=" + strings.Replace(dataset, "/", "%2F", -1) + "%231",
+		"/dbs2go/lumimask?dataset=" + dataset + "&format=csv",
+	} {
+		if _, err := respRecorder("GET", rurl, nil, web.LumiMaskHandler); err == nil {
+			t.Errorf("request %s is not rejected", rurl)
+		}
+	}
+	payload = `{"logical_file_name":["/store/lumimask/f1.root","/store/lumimask/f3.root"],"run_num":[1,3]}`
+	if _, err := respRecorder("POST", "/dbs2go/lumimask", strings.NewReader(payload), web.LumiMaskHandler); err == nil {
+		t.Errorf("request with lists of files and runs is not rejected")
+	}
+}
+
+// TestHTTPGetCompare provides test of datasets comparison API
+func TestHTTPGetCompare(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+	fx := newFixture(t, db)
+	defer fx.cleanup()
+
+	// dataset a has run 1 lumis 1-12 and run 2 lumis 1-5, dataset b has
+	// run 1 lumis 1-10 (lumi 5 is split between two files), run 2 lumis
+	// 1-5 and run 3 lumis 1-3
+	dsa := "/ZMM/Compare-Prompt-v1/AOD"
+	dsb := "/ZMM/Compare-ReReco-v1/AOD"
+	for i, dataset := range []string{dsa, dsb} {
+		fx.insert("DATASETS", "DATASET_ID, DATASET", 9401+i, dataset)
+	}
+	files := []struct {
+		fid    int
+		did    int
+		events int
+		lumis  [][]int64
+	}{
+		{9401, 9401, 100, [][]int64{{1, 1, 10}}},
+		{9402, 9401, 70, [][]int64{{1, 11, 12}, {2, 1, 5}}},
+		{9403, 9402, 100, [][]int64{{1, 1, 10}}},
+		{9404, 9402, 80, [][]int64{{2, 1, 5}, {3, 1, 3}}},
+		{9405, 9402, 0, [][]int64{{1, 5, 5}}},
+	}
+	for _, f := range files {
+		lfn := fmt.Sprintf("/store/compare/f%d.root", f.fid-9400)
+		fx.insert("FILES", "FILE_ID, LOGICAL_FILE_NAME, DATASET_ID, EVENT_COUNT", f.fid, lfn, f.did, f.events)
+		for _, r := range f.lumis {
+			for lumi := r[1]; lumi <= r[2]; lumi++ {
+				fx.insert("FILE_LUMIS", "RUN_NUM, LUMI_SECTION_NUM, FILE_ID", r[0], lumi, f.fid)
+			}
+		}
+	}
+
+	// helper function to get run records and summary of given comparison
+	compare := func(rurl string) ([]dbs.CompareRun, dbs.CompareSummary) {
+		rr, err := respRecorder("GET", rurl, nil, web.CompareHandler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var records []json.RawMessage
+		if err := json.Unmarshal(rr.Body.Bytes(), &records); err != nil || len(records) == 0 {
+			t.Fatalf("unable to parse comparison of %s, error %v body %s", rurl, err, rr.Body.String())
+		}
+		var runs []dbs.CompareRun
+		for _, data := range records[:len(records)-1] {
+			var run dbs.CompareRun
+			if err := json.Unmarshal(data, &run); err != nil || run.Type != "run" {
+				t.Fatalf("wrong run record %s, error %v", string(data), err)
+			}
+			runs = append(runs, run)
+		}
+		var summary dbs.CompareSummary
+		if err := json.Unmarshal(records[len(records)-1], &summary); err != nil || summary.Type != "summary" {
+			t.Fatalf("wrong summary record %s, error %v", string(records[len(records)-1]), err)
+		}
+		return runs, summary
+	}
+
+	runs, summary := compare(fmt.Sprintf("/dbs2go/compare?a=%s&b=%s", dsa, dsb))
+	expectRuns := []dbs.CompareRun{
+		{Type: "run", RunNum: 1, Common: [][]int64{{1, 10}}, OnlyInA: [][]int64{{11, 12}}, OnlyInB: [][]int64{}},
+		{Type: "run", RunNum: 3, Common: [][]int64{}, OnlyInA: [][]int64{}, OnlyInB: [][]int64{{1, 3}}},
+	}
+	if !reflect.DeepEqual(runs, expectRuns) {
+		t.Errorf("wrong runs of comparison %+v, expected %+v", runs, expectRuns)
+	}
+	expect := dbs.CompareSummary{
+		Type:            "summary",
+		A:               dbs.CompareDataset{Dataset: dsa, FileCount: 2, EventCount: 170, RunCount: 2, LumiCount: 17},
+		B:               dbs.CompareDataset{Dataset: dsb, FileCount: 3, EventCount: 180, RunCount: 3, LumiCount: 18},
+		CommonRunCount:  2,
+		CommonLumiCount: 15,
+		OnlyInALumis:    2,
+		OnlyInBLumis:    3,
+		DiffRunCount:    2,
+	}
+	if summary != expect {
+		t.Errorf("wrong summary of comparison %+v, expected %+v", summary, expect)
+	}
+
+	// dataset is identical to itself
+	runs, summary = compare(fmt.Sprintf("/dbs2go/compare?a=%s&b=%s", dsb, dsb))
+	if len(runs) != 0 || !summary.Identical || summary.CommonLumiCount != 18 {
+		t.Errorf("wrong comparison of identical datasets %+v %+v", runs, summary)
+	}
+
+	// invalid requests
+	for _, rurl := range []string{
+		"/dbs2go/compare?a=" + dsa,
+		"/dbs2go/compare?a=" + dsa + "&b=/ZMM/*/AOD",
+		"/dbs2go/compare?a=" + dsa + "&b=/ZMM/Compare-Unknown-v1/AOD",
+	} {
+		if _, err := respRecorder("GET", rurl, nil, web.CompareHandler); err == nil {
+			t.Errorf("request %s is not rejected", rurl)
+		}
+	}
+}
+
 // TestHTTPPost provides test of GET method for our service
 func TestHTTPPost(t *testing.T) {
 	var rr *httptest.ResponseRecorder
@@ -232,3 +1415,414 @@ func TestHTTPPut(t *testing.T) {
 		t.Errorf("acquisition era is not found after GET request")
 	}
 }
+
+// TestHTTPGetLumiDuplicates provides test of duplicate lumis API
+func TestHTTPGetLumiDuplicates(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+	fx := newFixture(t, db)
+	defer fx.cleanup()
+
+	// lumi 5 of run 1 is present in valid files f1 and f2 of different
+	// blocks, lumi 1 is also present in invalid file f3 and lumi 2 is also
+	// present in file f4 of another dataset
+	dataset := "/ZMM/LumiDuplicates-v1/AOD"
+	for i, ds := range []string{dataset, "/ZMM/LumiDuplicates-v2/AOD"} {
+		fx.insert("DATASETS", "DATASET_ID, DATASET", 9501+i, ds)
+		block := fmt.Sprintf("%s#%d", ds, i+1)
+		fx.insert("BLOCKS", "BLOCK_ID, BLOCK_NAME, DATASET_ID", 9501+i, block, 9501+i)
+	}
+	block := fmt.Sprintf("%s#3", dataset)
+	fx.insert("BLOCKS", "BLOCK_ID, BLOCK_NAME, DATASET_ID", 9503, block, 9501)
+	files := []struct {
+		fid   int
+		did   int
+		bid   int
+		valid int
+		lumis [][]int64
+	}{
+		{9501, 9501, 9501, 1, [][]int64{{1, 1, 5}}},
+		{9502, 9501, 9503, 1, [][]int64{{1, 5, 6}}},
+		{9503, 9501, 9503, 0, [][]int64{{1, 1, 1}}},
+		{9504, 9502, 9502, 1, [][]int64{{1, 2, 2}}},
+	}
+	for _, f := range files {
+		lfn := fmt.Sprintf("/store/lumiduplicates/f%d.root", f.fid-9500)
+		fx.insert("FILES", "FILE_ID, LOGICAL_FILE_NAME, DATASET_ID, BLOCK_ID, IS_FILE_VALID", f.fid, lfn, f.did, f.bid, f.valid)
+		for _, r := range f.lumis {
+			for lumi := r[1]; lumi <= r[2]; lumi++ {
+				fx.insert("FILE_LUMIS", "RUN_NUM, LUMI_SECTION_NUM, FILE_ID, EVENT_COUNT", r[0], lumi, f.fid, 10)
+			}
+		}
+	}
+
+	rr, err := respRecorder("GET", "/dbs2go/lumiduplicates?dataset="+dataset, nil, web.LumiDuplicatesHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []dbs.Record
+	if err := json.Unmarshal(rr.Body.Bytes(), &records); err != nil {
+		t.Fatalf("unable to parse duplicate lumis, error %v body %s", err, rr.Body.String())
+	}
+	var found []string
+	for _, rec := range records {
+		found = append(found, fmt.Sprintf("%v:%v %v %v", rec["run_num"], rec["lumi_section_num"], rec["logical_file_name"], rec["block_name"]))
+	}
+	expect := []string{
+		"1:5 /store/lumiduplicates/f1.root /ZMM/LumiDuplicates-v1/AOD#1",
+		"1:5 /store/lumiduplicates/f2.root /ZMM/LumiDuplicates-v1/AOD#3",
+	}
+	if !reflect.DeepEqual(found, expect) {
+		t.Errorf("wrong duplicate lumis %v, expected %v", found, expect)
+	}
+
+	// dataset without duplicates
+	rr, err = respRecorder("GET", "/dbs2go/lumiduplicates?dataset=/ZMM/LumiDuplicates-v2/AOD", nil, web.LumiDuplicatesHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := strings.TrimSpace(rr.Body.String()); body != "[]" {
+		t.Errorf("wrong duplicate lumis of dataset without duplicates %s", body)
+	}
+
+	// invalid requests
+	for _, rurl := range []string{
+		"/dbs2go/lumiduplicates",
+		"/dbs2go/lumiduplicates?dataset=/ZMM/*/AOD",
+	} {
+		if _, err := respRecorder("GET", rurl, nil, web.LumiDuplicatesHandler); err == nil {
+			t.Errorf("request %s is not rejected", rurl)
+		}
+	}
+}
+
+// TestHTTPGetDuplicates provides test of checksum based file look up and
+// duplicate files API
+func TestHTTPGetDuplicates(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+	fx := newFixture(t, db)
+	defer fx.cleanup()
+
+	// files f1 and f2 have the same adler32 and size, f3 has a copy f4 in
+	// another dataset, f5 differs from f1 by size and f6, f7 have unknown
+	// checksums; files API also requires file type and dataset access type
+	fx.insert("FILE_DATA_TYPES", "FILE_TYPE_ID, FILE_TYPE", 9801, "EDM")
+	fx.insert("DATASET_ACCESS_TYPES", "DATASET_ACCESS_TYPE_ID, DATASET_ACCESS_TYPE", 9801, "VALID")
+	dataset := "/ZMM/Duplicates-v1/AOD"
+	for i, ds := range []string{dataset, "/ZMM/Duplicates-v2/AOD"} {
+		fx.insert("DATASETS", "DATASET_ID, DATASET, DATASET_ACCESS_TYPE_ID", 9801+i, ds, 9801)
+		block := fmt.Sprintf("%s#%d", ds, i+1)
+		fx.insert("BLOCKS", "BLOCK_ID, BLOCK_NAME, DATASET_ID", 9801+i, block, 9801+i)
+	}
+	files := []struct {
+		fid   int
+		did   int
+		valid int
+		size  int
+		adler string
+		md5   string
+	}{
+		{9801, 9801, 1, 100, "aaaa0001", "m1"},
+		{9802, 9801, 0, 100, "aaaa0001", "m2"},
+		{9803, 9801, 1, 200, "aaaa0002", "NOTSET"},
+		{9804, 9802, 1, 200, "aaaa0002", "NOTSET"},
+		{9805, 9801, 1, 101, "aaaa0001", "NOTSET"},
+		{9806, 9801, 1, 300, "NOTSET", "NOTSET"},
+		{9807, 9801, 1, 300, "NOTSET", "NOTSET"},
+	}
+	for _, f := range files {
+		lfn := fmt.Sprintf("/store/duplicates/f%d.root", f.fid-9800)
+		fx.insert("FILES", "FILE_ID, LOGICAL_FILE_NAME, DATASET_ID, BLOCK_ID, FILE_TYPE_ID, IS_FILE_VALID, FILE_SIZE, CHECK_SUM, ADLER32, MD5", f.fid, lfn, f.did, f.did, 9801, f.valid, f.size, "cksum", f.adler, f.md5)
+	}
+
+	// helper function to get records of given API call
+	records := func(rurl string, hdlr func(http.ResponseWriter, *http.Request), attrs ...string) []string {
+		rr, err := respRecorder("GET", rurl, nil, hdlr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var recs []dbs.Record
+		if err := json.Unmarshal(rr.Body.Bytes(), &recs); err != nil {
+			t.Fatalf("unable to parse %s output, error %v body %s", rurl, err, rr.Body.String())
+		}
+		var out []string
+		for _, rec := range recs {
+			var vals []string
+			for _, attr := range attrs {
+				vals = append(vals, fmt.Sprintf("%v", rec[attr]))
+			}
+			out = append(out, strings.Join(vals, " "))
+		}
+		return out
+	}
+
+	// look up files by checksums
+	found := records("/dbs2go/files?adler32=aaaa0002", web.FilesHandler, "logical_file_name")
+	expect := []string{"/store/duplicates/f3.root", "/store/duplicates/f4.root"}
+	if !reflect.DeepEqual(found, expect) {
+		t.Errorf("wrong files of adler32 look up %v, expected %v", found, expect)
+	}
+	found = records("/dbs2go/files?md5=m1&check_sum=cksum", web.FilesHandler, "logical_file_name")
+	expect = []string{"/store/duplicates/f1.root"}
+	if !reflect.DeepEqual(found, expect) {
+		t.Errorf("wrong files of md5 look up %v, expected %v", found, expect)
+	}
+
+	// duplicate files
+	attrs := []string{"checksum", "file_size", "copies", "logical_file_name", "dataset"}
+	tests := []struct {
+		query  string
+		expect []string
+	}{
+		{"", []string{
+			"aaaa0001 100 2 /store/duplicates/f1.root /ZMM/Duplicates-v1/AOD",
+			"aaaa0001 100 2 /store/duplicates/f2.root /ZMM/Duplicates-v1/AOD",
+		}},
+		{"&validFileOnly=1", nil},
+		{"&checksum=md5", nil},
+		{"&scope=all", []string{
+			"aaaa0001 100 2 /store/duplicates/f1.root /ZMM/Duplicates-v1/AOD",
+			"aaaa0001 100 2 /store/duplicates/f2.root /ZMM/Duplicates-v1/AOD",
+			"aaaa0002 200 2 /store/duplicates/f3.root /ZMM/Duplicates-v1/AOD",
+			"aaaa0002 200 2 /store/duplicates/f4.root /ZMM/Duplicates-v2/AOD",
+		}},
+		{"&scope=all&validFileOnly=1", []string{
+			"aaaa0002 200 2 /store/duplicates/f3.root /ZMM/Duplicates-v1/AOD",
+			"aaaa0002 200 2 /store/duplicates/f4.root /ZMM/Duplicates-v2/AOD",
+		}},
+	}
+	for _, tst := range tests {
+		rurl := "/dbs2go/duplicates?dataset=" + dataset + tst.query
+		found := records(rurl, web.DuplicatesHandler, attrs...)
+		if !reflect.DeepEqual(found, tst.expect) {
+			t.Errorf("wrong duplicate files of %s %v, expected %v", rurl, found, tst.expect)
+		}
+	}
+
+	// invalid requests
+	for _, rurl := range []string{
+		"/dbs2go/duplicates",
+		"/dbs2go/duplicates?dataset=/ZMM/*/AOD",
+		"/dbs2go/duplicates?dataset=" + dataset + "&checksum=crc32",
+		"/dbs2go/duplicates?dataset=" + dataset + "&scope=site",
+	} {
+		if _, err := respRecorder("GET", rurl, nil, web.DuplicatesHandler); err == nil {
+			t.Errorf("request %s is not rejected", rurl)
+		}
+	}
+}
+
+// TestHTTPPostInferFileParents provides test of file parentage inference by
+// DBS writer
+func TestHTTPPostInferFileParents(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+	fx := newFixture(t, db)
+	defer fx.cleanup()
+	serverType := web.Config.ServerType
+	web.Config.ServerType = "DBSWriter"
+	defer func() { web.Config.ServerType = serverType }()
+
+	// unmerged dataset is parent of merged one, merged file c1 covers lumis
+	// of p1 and p2, c2 covers lumis of p2, invalid p3 and p4 of another
+	// dataset, c3 already has parent p4
+	datasets := []string{"/ZMM/Unmerged-v1/AOD", "/ZMM/Merged-v1/AOD", "/ZMM/Other-v1/AOD"}
+	for i, dataset := range datasets {
+		fx.insert("DATASETS", "DATASET_ID, DATASET", 9601+i, dataset)
+		block := fmt.Sprintf("%s#1", dataset)
+		fx.insert("BLOCKS", "BLOCK_ID, BLOCK_NAME, DATASET_ID", 9601+i, block, 9601+i)
+	}
+	fx.insert("DATASET_PARENTS", "THIS_DATASET_ID, PARENT_DATASET_ID", 9602, 9601)
+	files := []struct {
+		fid   int
+		lfn   string
+		did   int
+		valid int
+		lumis []int64
+	}{
+		{9601, "/store/infer/p1.root", 9601, 1, []int64{1, 5}},
+		{9602, "/store/infer/p2.root", 9601, 1, []int64{6, 10}},
+		{9603, "/store/infer/p3.root", 9601, 0, []int64{11, 11}},
+		{9604, "/store/infer/p4.root", 9603, 1, []int64{12, 12}},
+		{9611, "/store/infer/c1.root", 9602, 1, []int64{1, 7}},
+		{9612, "/store/infer/c2.root", 9602, 1, []int64{9, 12}},
+		{9613, "/store/infer/c3.root", 9602, 1, []int64{13, 13}},
+	}
+	for _, f := range files {
+		fx.insert("FILES", "FILE_ID, LOGICAL_FILE_NAME, DATASET_ID, BLOCK_ID, IS_FILE_VALID", f.fid, f.lfn, f.did, f.did, f.valid)
+		for lumi := f.lumis[0]; lumi <= f.lumis[1]; lumi++ {
+			fx.insert("FILE_LUMIS", "RUN_NUM, LUMI_SECTION_NUM, FILE_ID", 1, lumi, f.fid)
+		}
+	}
+	fx.insert("FILE_PARENTS", "THIS_FILE_ID, PARENT_FILE_ID", 9613, 9604)
+	// file parents inserted by the API
+	fx.track("FILE_PARENTS", "THIS_FILE_ID", 9611)
+	fx.track("FILE_PARENTS", "THIS_FILE_ID", 9612)
+
+	// file parents by lumi look-up of DBS writer does not insert parents
+	payload := `{"block_name":"/ZMM/Merged-v1/AOD#1"}`
+	if _, err := respRecorder("POST", "/dbs2go/fileparentsbylumi", strings.NewReader(payload), web.FileParentsByLumiHandler); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM FILE_PARENTS WHERE THIS_FILE_ID IN (9611, 9612)").Scan(&count); err != nil || count != 0 {
+		t.Errorf("file parents look-up inserted %d file parents, error %v", count, err)
+	}
+
+	// helper function to infer parents of merged block
+	infer := func() dbs.FileParentsByLumiReport {
+		rr, err := respRecorder("POST", "/dbs2go/inferfileparents", strings.NewReader(payload), web.InferFileParentsHandler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var records []dbs.FileParentsByLumiReport
+		if err := json.Unmarshal(rr.Body.Bytes(), &records); err != nil || len(records) != 1 {
+			t.Fatalf("unable to parse report, error %v body %s", err, rr.Body.String())
+		}
+		return records[0]
+	}
+	report := infer()
+	var links []string
+	for _, l := range report.Links {
+		links = append(links, fmt.Sprintf("%s -> %s", l.LogicalFileName, l.ParentLogicalFileName))
+	}
+	expectLinks := []string{
+		"/store/infer/c1.root -> /store/infer/p1.root",
+		"/store/infer/c1.root -> /store/infer/p2.root",
+		"/store/infer/c2.root -> /store/infer/p2.root",
+	}
+	if !reflect.DeepEqual(links, expectLinks) || report.LinkCount != 3 {
+		t.Errorf("wrong inferred links %v, expected %v", links, expectLinks)
+	}
+	expectUnmatched := map[string]dbs.LumiMask{"/store/infer/c2.root": {"1": {{11, 12}}}}
+	if !reflect.DeepEqual(report.UnmatchedLumis, expectUnmatched) {
+		t.Errorf("wrong unmatched lumis %v, expected %v", report.UnmatchedLumis, expectUnmatched)
+	}
+	if !reflect.DeepEqual(report.SkippedFiles, []string{"/store/infer/c3.root"}) {
+		t.Errorf("wrong skipped files %v", report.SkippedFiles)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM FILE_PARENTS WHERE THIS_FILE_ID IN (9611, 9612)").Scan(&count); err != nil || count != 3 {
+		t.Errorf("wrong number of inserted file parents %d, error %v", count, err)
+	}
+
+	// files with inserted parents are skipped by next request
+	report = infer()
+	if report.LinkCount != 0 || len(report.SkippedFiles) != 3 {
+		t.Errorf("wrong report of repeated request %+v", report)
+	}
+
+	// unknown block is rejected
+	payload = `{"block_name":"/ZMM/Merged-v1/AOD#2"}`
+	if _, err := respRecorder("POST", "/dbs2go/inferfileparents", strings.NewReader(payload), web.InferFileParentsHandler); err == nil {
+		t.Errorf("request with unknown block is not rejected")
+	}
+}
+
+// TestHTTPPostLfnLookup provides test of bulk LFN lookup API
+func TestHTTPPostLfnLookup(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+	fx := newFixture(t, db)
+	defer fx.cleanup()
+	// use small batches to look up files by several queries
+	dbs.LfnLookupBatchSize = 2
+	defer func() { dbs.LfnLookupBatchSize = 1000 }()
+
+	dataset := "/ZMM/LfnLookup-v1/AOD"
+	fx.insert("DATASETS", "DATASET_ID, DATASET", 9701, dataset)
+	fx.insert("BLOCKS", "BLOCK_ID, BLOCK_NAME, DATASET_ID", 9701, dataset+"#1", 9701)
+	for i, valid := range []int{1, 0} {
+		lfn := fmt.Sprintf("/store/lfnlookup/f%d.root", i+1)
+		fx.insert("FILES", "FILE_ID, LOGICAL_FILE_NAME, DATASET_ID, BLOCK_ID, IS_FILE_VALID, FILE_SIZE, CHECK_SUM, ADLER32", 9701+i, lfn, 9701, 9701, valid, 100*(i+1), "123", "abc")
+	}
+
+	// helper function to get summary of lookup records
+	summary := func(records []dbs.LfnRecord) []string {
+		var out []string
+		for _, r := range records {
+			s := fmt.Sprintf("%s %v", r.LogicalFileName, r.Exists)
+			if r.Exists {
+				s = fmt.Sprintf("%s %d %s %s %d %s %s", s, *r.IsFileValid, r.BlockName, r.Dataset, r.FileSize, r.CheckSum, r.Adler32)
+			}
+			out = append(out, s)
+		}
+		return out
+	}
+	f1 := "/store/lfnlookup/f1.root true 1 /ZMM/LfnLookup-v1/AOD#1 /ZMM/LfnLookup-v1/AOD 100 123 abc"
+	f2 := "/store/lfnlookup/f2.root true 0 /ZMM/LfnLookup-v1/AOD#1 /ZMM/LfnLookup-v1/AOD 200 123 abc"
+	missing := "/store/lfnlookup/missing.root false"
+
+	// JSON list of files
+	payload := `["/store/lfnlookup/f1.root", "/store/lfnlookup/missing.root", "/store/lfnlookup/f2.root"]`
+	rr, err := respRecorder("POST", "/dbs2go/lfnlookup", strings.NewReader(payload), web.LfnLookupHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []dbs.LfnRecord
+	if err := json.Unmarshal(rr.Body.Bytes(), &records); err != nil {
+		t.Fatalf("unable to parse lookup records, error %v body %s", err, rr.Body.String())
+	}
+	if found, expect := summary(records), []string{f1, missing, f2}; !reflect.DeepEqual(found, expect) {
+		t.Errorf("wrong lookup of JSON list %v, expected %v", found, expect)
+	}
+
+	// gzipped NDJSON stream of files and records
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Write([]byte(`"/store/lfnlookup/f2.root"
+{"logical_file_name": "/store/lfnlookup/missing.root"}
+["/store/lfnlookup/f1.root", "/store/lfnlookup/f2.root"]
+`))
+	gw.Close()
+	req, err := http.NewRequest("POST", "/dbs2go/lfnlookup", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/ndjson")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Accept", "application/ndjson")
+	rec := httptest.NewRecorder()
+	http.HandlerFunc(web.LfnLookupHandler).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("wrong status code %d body %s", rec.Code, rec.Body.String())
+	}
+	records = nil
+	dec := json.NewDecoder(rec.Body)
+	for dec.More() {
+		var r dbs.LfnRecord
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	if found, expect := summary(records), []string{f2, missing, f1, f2}; !reflect.DeepEqual(found, expect) {
+		t.Errorf("wrong lookup of NDJSON stream %v, expected %v", found, expect)
+	}
+
+	// invalid file value
+	payload = `[123]`
+	if _, err := respRecorder("POST", "/dbs2go/lfnlookup", strings.NewReader(payload), web.LfnLookupHandler); err == nil {
+		t.Errorf("request with invalid file value is not rejected")
+	}
+}
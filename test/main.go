@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/dmwm/dbs2go/dbs"
@@ -76,6 +77,64 @@ func initDB(dryRun bool, dburi string) *sql.DB {
 	return db
 }
 
+// testFixture keeps track of rows inserted into DBS tables by a test, the
+// rows are deleted by cleanup in reverse order of their insertion
+type testFixture struct {
+	t    *testing.T
+	db   *sql.DB
+	rows []fixtureRow
+}
+
+// fixtureRow represents table rows matching given column values
+type fixtureRow struct {
+	table string
+	cols  []string
+	vals  []interface{}
+}
+
+// helper function to create test fixture for given DB, it should be
+// followed by defer fixture.cleanup()
+func newFixture(t *testing.T, db *sql.DB) *testFixture {
+	return &testFixture{t: t, db: db}
+}
+
+// insert inserts row with given (comma separated) columns and values
+func (f *testFixture) insert(table, cols string, vals ...interface{}) {
+	row := f.track(table, cols, vals...)
+	binds := strings.TrimSuffix(strings.Repeat("?, ", len(vals)), ", ")
+	stm := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(row.cols, ", "), binds)
+	if _, err := f.db.Exec(stm, vals...); err != nil {
+		f.t.Fatalf("unable to insert %s row %v, error %v", table, vals, err)
+	}
+}
+
+// track registers rows with given column values, e.g. created by tested
+// API, to be deleted by cleanup
+func (f *testFixture) track(table, cols string, vals ...interface{}) fixtureRow {
+	row := fixtureRow{table: table, vals: vals}
+	for _, col := range strings.Split(cols, ",") {
+		row.cols = append(row.cols, strings.TrimSpace(col))
+	}
+	f.rows = append(f.rows, row)
+	return row
+}
+
+// cleanup deletes all rows of the fixture
+func (f *testFixture) cleanup() {
+	for i := len(f.rows) - 1; i >= 0; i-- {
+		row := f.rows[i]
+		var conds []string
+		for _, col := range row.cols {
+			conds = append(conds, fmt.Sprintf("%s = ?", col))
+		}
+		stm := fmt.Sprintf("DELETE FROM %s WHERE %s", row.table, strings.Join(conds, " AND "))
+		if _, err := f.db.Exec(stm, row.vals...); err != nil {
+			f.t.Errorf("unable to delete %s row %v, error %v", row.table, row.vals, err)
+		}
+	}
+	f.rows = nil
+}
+
 // creates a URL given a hostname, endpoint, and parameters
 func parseURL(t *testing.T, hostname string, endpoint string, params url.Values) *url.URL {
 	url2, err := url.Parse(hostname)
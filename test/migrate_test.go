@@ -5,8 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/dmwm/dbs2go/dbs"
 	"github.com/dmwm/dbs2go/utils"
@@ -376,3 +382,441 @@ func TestMigrateRemove(t *testing.T) {
 		t.Fatalf("wrong number of migration records did not reduced orig status records %d, remooved records %d, new set of status records %d", origStatusRecords, len(removeRecords), len(statusRecords))
 	}
 }
+
+// TestBlockDumpHash tests canonical hash and comparison of block dumps
+func TestBlockDumpHash(t *testing.T) {
+	blk := "/a/b/c#123"
+	f1 := dbs.File{
+		LogicalFileName: "/store/file1.root",
+		CheckSum:        "123",
+		FileSize:        100,
+		FileLumiList: []dbs.FileLumi{
+			{RunNumber: 1, LumiSectionNumber: 1},
+			{RunNumber: 1, LumiSectionNumber: 2},
+		},
+	}
+	f2 := dbs.File{LogicalFileName: "/store/file2.root", CheckSum: "456", FileSize: 200}
+	local := dbs.BulkBlocks{
+		Block: dbs.Block{BlockName: blk, CreationDate: 1},
+		Files: []dbs.File{f1, f2},
+	}
+
+	// remote block has different order of files and lumis and different dates
+	f1r := f1
+	f1r.FileLumiList = []dbs.FileLumi{
+		{RunNumber: 1, LumiSectionNumber: 2},
+		{RunNumber: 1, LumiSectionNumber: 1},
+	}
+	remote := dbs.BulkBlocks{
+		Block: dbs.Block{BlockName: blk, CreationDate: 2},
+		Files: []dbs.File{f2, f1r},
+	}
+	lhash, err := dbs.BlockDumpHash(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rhash, err := dbs.BlockDumpHash(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lhash != rhash {
+		t.Fatalf("block dump hashes should not depend on order, local %s remote %s", lhash, rhash)
+	}
+	report, err := dbs.CompareBlockDumps(local, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Match || len(report.Diffs) != 0 {
+		t.Fatalf("wrong verification report %+v", report)
+	}
+
+	// remote block with different checksum and additional file
+	f2r := f2
+	f2r.CheckSum = "789"
+	f3 := dbs.File{LogicalFileName: "/store/file3.root"}
+	remote.Files = []dbs.File{f1r, f2r, f3}
+	report, err = dbs.CompareBlockDumps(local, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Match {
+		t.Fatalf("verification report should not match %+v", report)
+	}
+	if len(report.Diffs) != 2 {
+		t.Fatalf("wrong number of differences %v", report.Diffs)
+	}
+	log.Println("verification report", report.String())
+}
+
+// TestSubscriptionAPIs tests submit, status, pause and resume migration subscription APIs
+func TestSubscriptionAPIs(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+	utils.VERBOSE = 1
+
+	// submit new subscription
+	rec := dbs.MigrationSubscription{
+		MIGRATION_URL:   "http://localhost:8989/dbs-one-reader",
+		DATASET_PATTERN: "/unittest*/*/GEN-SIM-RAW",
+		DATA_TIER_NAME:  "GEN-SIM-RAW",
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr, err := respRecorder("POST", "/dbs2go/subscribe", bytes.NewReader(data), web.SubscriptionSubmitHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []dbs.MigrationSubscription
+	data = rr.Body.Bytes()
+	err = json.Unmarshal(data, &records)
+	if err != nil {
+		t.Fatalf("unable to unmarshal received data '%s', error %v", string(data), err)
+	}
+	if len(records) != 1 || records[0].SUBSCRIPTION_ID == 0 {
+		t.Fatalf("wrong subscription records %+v", records)
+	}
+	sid := records[0].SUBSCRIPTION_ID
+	if records[0].DATASET_ACCESS_TYPE != "VALID" {
+		t.Fatalf("wrong default dataset access type %+v", records[0])
+	}
+
+	// helper function to check subscription status
+	checkStatus := func(status int64) {
+		url := fmt.Sprintf("/dbs2go/subscriptions?subscription_id=%d", sid)
+		rr, err := respRecorder("GET", url, nil, web.SubscriptionStatusHandler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var records []dbs.MigrationSubscription
+		data := rr.Body.Bytes()
+		err = json.Unmarshal(data, &records)
+		if err != nil {
+			t.Fatalf("unable to unmarshal received data '%s', error %v", string(data), err)
+		}
+		if len(records) != 1 || records[0].SUBSCRIPTION_STATUS != status {
+			t.Fatalf("wrong subscription records %+v, expect status %d", records, status)
+		}
+	}
+	checkStatus(dbs.SUBSCRIPTION_ACTIVE)
+
+	// pause and resume subscription
+	data = []byte(fmt.Sprintf(`{"subscription_id":%d}`, sid))
+	_, err = respRecorder("POST", "/dbs2go/pause", bytes.NewReader(data), web.SubscriptionPauseHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStatus(dbs.SUBSCRIPTION_PAUSED)
+	subs, err := dbs.MigrationSubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range subs {
+		if s.SUBSCRIPTION_ID == sid {
+			t.Fatalf("paused subscription %d should not be evaluated", sid)
+		}
+	}
+	_, err = respRecorder("POST", "/dbs2go/resume", bytes.NewReader(data), web.SubscriptionResumeHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStatus(dbs.SUBSCRIPTION_ACTIVE)
+
+	// pause of non-existing subscription should fail
+	data = []byte(`{"subscription_id":123456789}`)
+	_, err = respRecorder("POST", "/dbs2go/pause", bytes.NewReader(data), web.SubscriptionPauseHandler)
+	if err == nil {
+		t.Fatal("pause of non-existing subscription should fail")
+	}
+}
+
+// TestMigrationCleanup tests cleanup of migration requests with per-status retention
+func TestMigrationCleanup(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+	utils.VERBOSE = 1
+
+	day := int64(24 * 60 * 60)
+	now := time.Now().Unix()
+	dbs.MigrationRetries = 3
+	dbs.MigrationCleanupOffset = 90 * day
+	dbs.MigrationCleanupRetention = map[int]int64{
+		dbs.COMPLETED:   30 * day,
+		dbs.TERM_FAILED: 14 * day,
+		dbs.EXIST_IN_DB: 30 * day,
+	}
+	archive := fmt.Sprintf("%s/migration_archive.jsonl", t.TempDir())
+	dbs.MigrationCleanupArchive = archive
+
+	// migration requests with different status and age
+	// pending and in-progress requests are never removed, failed requests
+	// are removed only after all retries
+	requests := []struct {
+		input   string
+		status  int64
+		retries int64
+		age     int64
+		purge   bool
+	}{
+		{"/cleanup/completed/old#1", dbs.COMPLETED, 0, 40 * day, true},
+		{"/cleanup/completed/new#1", dbs.COMPLETED, 0, 10 * day, false},
+		{"/cleanup/termfailed/old#1", dbs.TERM_FAILED, 4, 20 * day, true},
+		{"/cleanup/termfailed/new#1", dbs.TERM_FAILED, 4, 1 * day, false},
+		{"/cleanup/termfailed/retry#1", dbs.TERM_FAILED, 1, 20 * day, false},
+		{"/cleanup/failed/old#1", dbs.FAILED, 4, 20 * day, true},
+		{"/cleanup/failed/retry#1", dbs.FAILED, 2, 20 * day, false},
+		{"/cleanup/existindb/old#1", dbs.EXIST_IN_DB, 0, 31 * day, true},
+		{"/cleanup/pending/new#1", dbs.PENDING, 0, 40 * day, false},
+		{"/cleanup/pending/old#1", dbs.PENDING, 0, 100 * day, false},
+		{"/cleanup/inprogress/new#1", dbs.IN_PROGRESS, 0, 1 * day, false},
+		{"/cleanup/inprogress/old#1", dbs.IN_PROGRESS, 0, 100 * day, false},
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range requests {
+		tstamp := now - r.age
+		rec := dbs.MigrationRequest{
+			MIGRATION_URL:          "http://localhost:8989/dbs-one-reader",
+			MIGRATION_INPUT:        r.input,
+			MIGRATION_STATUS:       r.status,
+			CREATE_BY:              "test",
+			CREATION_DATE:          tstamp,
+			LAST_MODIFIED_BY:       "test",
+			LAST_MODIFICATION_DATE: tstamp,
+			RETRY_COUNT:            r.retries,
+		}
+		if err := rec.Insert(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	var expect []string
+	for _, r := range requests {
+		if r.purge {
+			expect = append(expect, r.input)
+		}
+	}
+	sort.Strings(expect)
+
+	// helper function to get inputs of migration requests
+	inputs := func(data []byte) []string {
+		var records []dbs.MigrationRequest
+		err := json.Unmarshal(data, &records)
+		if err != nil {
+			t.Fatalf("unable to unmarshal received data '%s', error %v", string(data), err)
+		}
+		var out []string
+		for _, r := range records {
+			if strings.HasPrefix(r.MIGRATION_INPUT, "/cleanup/") {
+				out = append(out, r.MIGRATION_INPUT)
+			}
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	// dry run should report migration requests without removing them
+	for i := 0; i < 2; i++ {
+		rr, err := respRecorder("GET", "/dbs2go/cleanup?dry_run=true", nil, web.MigrationCleanupHandler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out := inputs(rr.Body.Bytes()); !utils.Equal(out, expect) {
+			t.Fatalf("wrong dry run output %v, expect %v", out, expect)
+		}
+	}
+	_, err = respRecorder("GET", "/dbs2go/cleanup?dry_run=false", nil, web.MigrationCleanupHandler)
+	if err == nil {
+		t.Fatal("GET cleanup without dry run should fail")
+	}
+	// POST cleanup honours dry run as well
+	payload := []byte(`{"dry_run":true}`)
+	rr, err := respRecorder("POST", "/dbs2go/cleanup", bytes.NewReader(payload), web.MigrationCleanupHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := inputs(rr.Body.Bytes()); !utils.Equal(out, expect) {
+		t.Fatalf("wrong POST dry run output %v, expect %v", out, expect)
+	}
+
+	// perform cleanup
+	rr, err = respRecorder("POST", "/dbs2go/cleanup", bytes.NewReader([]byte("{}")), web.MigrationCleanupHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := inputs(rr.Body.Bytes()); !utils.Equal(out, expect) {
+		t.Fatalf("wrong cleanup output %v, expect %v", out, expect)
+	}
+	rr, err = respRecorder("GET", "/dbs2go/cleanup?dry_run=true", nil, web.MigrationCleanupHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := inputs(rr.Body.Bytes()); len(out) != 0 {
+		t.Fatalf("migration requests %v were not removed", out)
+	}
+
+	// check archive of removed migration requests
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != len(expect) {
+		t.Fatalf("wrong number of archived records %d, expect %d", len(lines), len(expect))
+	}
+}
+
+// migration batch report
+type migrationBatchReport struct {
+	MigrationBatchID    int64             `json:"migration_batch_id"`
+	MigrationRequestIDs []int64           `json:"migration_request_ids"`
+	Reports             []json.RawMessage `json:"reports"`
+}
+
+// TestMigrationBatchSubmit tests submission of batch migration request
+func TestMigrationBatchSubmit(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+	utils.VERBOSE = 1
+
+	// we use non-existing DBS url, therefore all inputs should be reported
+	// as not allowed for migration
+	payloads := []string{
+		`{"migration_url":"http://localhost:1/dbs","migration_input":["/a/b/c","/x/y/z#123","/a/b/c"]}`,
+		`{"migration_url":"http://localhost:1/dbs","migration_inputs":["/a/b/c","/x/y/z#123"]}`,
+	}
+	for _, payload := range payloads {
+		reader := bytes.NewReader([]byte(payload))
+		rr, err := respRecorder("POST", "/dbs2go/submit", reader, web.MigrationSubmitHandler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reports []migrationBatchReport
+		data := rr.Body.Bytes()
+		err = json.Unmarshal(data, &reports)
+		if err != nil {
+			t.Fatalf("unable to unmarshal received data '%s', error %v", string(data), err)
+		}
+		if len(reports) != 1 {
+			t.Fatalf("wrong number of batch reports %+v", reports)
+		}
+		report := reports[0]
+		if report.MigrationBatchID == 0 {
+			t.Fatalf("batch report does not have batch id %+v", report)
+		}
+		if len(report.MigrationRequestIDs) != 0 {
+			t.Fatalf("wrong list of child migration requests %+v", report)
+		}
+		if len(report.Reports) != 2 {
+			t.Fatalf("inputs of batch request are not deduplicated %+v", report)
+		}
+	}
+}
+
+// TestMigrationBatchClosure tests that parent closure of batch migration
+// request is computed once for all inputs
+func TestMigrationBatchClosure(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+	utils.VERBOSE = 1
+
+	// remote DBS where both child blocks have the same parent block
+	parent := "/Closure/Parent-v1/GEN-SIM#1"
+	parents := map[string][]string{
+		"/Closure/Child-v1/AOD#1": {parent},
+		"/Closure/Child-v1/AOD#2": {parent},
+		parent:                    {},
+	}
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var records []map[string]string
+		switch r.URL.Path {
+		case "/datasets":
+			dataset := r.URL.Query().Get("dataset")
+			records = append(records, map[string]string{"dataset": dataset, "dataset_access_type": "VALID"})
+		case "/blockparents":
+			block := r.URL.Query().Get("block_name")
+			mu.Lock()
+			calls[block] += 1
+			mu.Unlock()
+			for _, p := range parents[block] {
+				records = append(records, map[string]string{"this_block_name": block, "parent_block_name": p})
+			}
+		}
+		if records == nil {
+			records = []map[string]string{}
+		}
+		data, _ := json.Marshal(records)
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	payload := fmt.Sprintf(`{"migration_url":"%s","migration_input":["/Closure/Child-v1/AOD#1","/Closure/Child-v1/AOD#2"]}`, ts.URL)
+	rr, err := respRecorder("POST", "/dbs2go/submit", bytes.NewReader([]byte(payload)), web.MigrationSubmitHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reports []migrationBatchReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &reports); err != nil {
+		t.Fatalf("unable to unmarshal received data '%s', error %v", rr.Body.String(), err)
+	}
+	if len(reports) != 1 || len(reports[0].MigrationRequestIDs) != 2 {
+		t.Fatalf("wrong batch report %+v", reports)
+	}
+
+	// migration batch is started in background, wait until blocks of its
+	// last migration request are registered
+	var count int
+	for i := 0; i < 50; i++ {
+		err := db.QueryRow("SELECT COUNT(*) FROM MIGRATION_BLOCKS WHERE MIGRATION_BLOCK_NAME=?", "/Closure/Child-v1/AOD#2").Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count > 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if count == 0 {
+		t.Fatal("migration blocks of batch migration request are not registered")
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM MIGRATION_REQUESTS WHERE MIGRATION_INPUT=?", parent).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("parent block should be migrated once, found %d migration requests", count)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for blk := range parents {
+		if calls[blk] != 1 {
+			t.Errorf("parents of %s fetched %d times, expected once", blk, calls[blk])
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// thriftReader provides minimal decoder of Thrift compact protocol which
+// decodes structs into maps of field ids and their values
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) byte() byte {
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case 1:
+		return true
+	case 2:
+		return false
+	case 3:
+		return int64(r.byte())
+	case 4, 5, 6:
+		return r.zigzag()
+	case 7:
+		v := binary.LittleEndian.Uint64(r.data[r.pos:])
+		r.pos += 8
+		return math.Float64frombits(v)
+	case 8:
+		n := int(r.uvarint())
+		s := string(r.data[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case 9, 10:
+		h := r.byte()
+		size := int(h >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.value(h & 0x0f)
+		}
+		return list
+	case 12:
+		return r.structure()
+	}
+	panic("unsupported thrift type")
+}
+
+func (r *thriftReader) structure() map[int16]interface{} {
+	rec := make(map[int16]interface{})
+	var id int16
+	for {
+		h := r.byte()
+		if h == 0 {
+			return rec
+		}
+		if delta := int16(h >> 4); delta > 0 {
+			id += delta
+		} else {
+			id = int16(r.zigzag())
+		}
+		rec[id] = r.value(h & 0x0f)
+	}
+}
+
+// helper function to decode values of single column chunk of parquet file
+func parquetColumn(t *testing.T, data []byte, offset int64, typ int64) []interface{} {
+	r := &thriftReader{data: data, pos: int(offset)}
+	header := r.structure()
+	page := header[5].(map[int16]interface{})
+	numValues := int(page[1].(int64))
+	body := data[r.pos : r.pos+int(header[3].(int64))]
+
+	// RLE encoded definition levels
+	size := int(binary.LittleEndian.Uint32(body))
+	lr := &thriftReader{data: body[4 : 4+size]}
+	var levels []byte
+	for lr.pos < len(lr.data) {
+		run := lr.uvarint()
+		if run&1 != 0 {
+			t.Fatalf("unexpected bit-packed run of definition levels")
+		}
+		level := lr.byte()
+		for i := 0; i < int(run>>1); i++ {
+			levels = append(levels, level)
+		}
+	}
+	if len(levels) != numValues {
+		t.Fatalf("wrong number of definition levels %d, expect %d", len(levels), numValues)
+	}
+
+	// PLAIN encoded non-NULL values
+	pos := 4 + size
+	var vals []interface{}
+	for _, level := range levels {
+		if level == 0 {
+			vals = append(vals, nil)
+			continue
+		}
+		switch typ {
+		case 2: // INT64
+			vals = append(vals, int64(binary.LittleEndian.Uint64(body[pos:])))
+			pos += 8
+		case 5: // DOUBLE
+			vals = append(vals, math.Float64frombits(binary.LittleEndian.Uint64(body[pos:])))
+			pos += 8
+		case 6: // BYTE_ARRAY
+			n := int(binary.LittleEndian.Uint32(body[pos:]))
+			vals = append(vals, string(body[pos+4:pos+4+n]))
+			pos += 4 + n
+		default:
+			t.Fatalf("unexpected parquet type %d", typ)
+		}
+	}
+	if pos != len(body) {
+		t.Errorf("data page has %d unread bytes", len(body)-pos)
+	}
+	return vals
+}
+
+// TestUtilsParquet decodes row groups written by ParquetWriter and checks
+// column types and values
+func TestUtilsParquet(t *testing.T) {
+	rr := httptest.NewRecorder()
+	pw := utils.NewParquetWriter(rr)
+	pw.RowGroupSize = 3
+	cols := []string{"file_id", "file_size", "logical_file_name", "event_count"}
+	kinds := []string{utils.IntColumn, utils.FloatColumn, utils.StringColumn, ""}
+	rows := [][]interface{}{
+		{int64(1), 1.5, "/a.root", nil},
+		{int64(2), nil, "/b.root", int64(10)},
+		{int64(3), float64(3), nil, float64(20)},
+		{int64(4), 4.5, "/d.root", int64(30)},
+	}
+	if err := pw.WriteColumns(cols, kinds); err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range rows {
+		if err := pw.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+		// rows are buffered until row group is complete
+		if started := i+1 >= pw.RowGroupSize; pw.Started() != started {
+			t.Errorf("parquet data is started %v after %d rows", pw.Started(), i+1)
+		}
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := rr.Body.Bytes()
+	if string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatalf("wrong parquet magic bytes")
+	}
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := &thriftReader{data: data[len(data)-8-size : len(data)-8]}
+	meta := r.structure()
+	if meta[3].(int64) != int64(len(rows)) {
+		t.Errorf("wrong number of rows %v", meta[3])
+	}
+
+	// schema of columns, the event_count kind is defined by its first value
+	schema := meta[2].([]interface{})[1:]
+	expectTypes := []int64{2, 5, 6, 2}
+	for i, elem := range schema {
+		rec := elem.(map[int16]interface{})
+		if rec[4] != cols[i] || rec[1] != expectTypes[i] || rec[3] != int64(1) {
+			t.Errorf("wrong schema of column %s: %v", cols[i], rec)
+		}
+	}
+
+	// values of all row groups
+	groups := meta[4].([]interface{})
+	if len(groups) != 2 {
+		t.Fatalf("wrong number of row groups %d", len(groups))
+	}
+	values := make([][]interface{}, len(cols))
+	for _, g := range groups {
+		for i, c := range g.(map[int16]interface{})[1].([]interface{}) {
+			chunk := c.(map[int16]interface{})[3].(map[int16]interface{})
+			if chunk[1] != expectTypes[i] {
+				t.Errorf("wrong type %v of column chunk %s", chunk[1], cols[i])
+			}
+			vals := parquetColumn(t, data, chunk[9].(int64), chunk[1].(int64))
+			values[i] = append(values[i], vals...)
+		}
+	}
+	expect := [][]interface{}{
+		{int64(1), int64(2), int64(3), int64(4)},
+		{1.5, nil, 3.0, 4.5},
+		{"/a.root", "/b.root", nil, "/d.root"},
+		{nil, int64(10), int64(20), int64(30)},
+	}
+	for i, col := range cols {
+		if !reflect.DeepEqual(values[i], expect[i]) {
+			t.Errorf("column %s has values %v, expect %v", col, values[i], expect[i])
+		}
+	}
+}
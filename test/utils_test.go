@@ -1,9 +1,16 @@
 package main
 
 import (
+	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/dmwm/dbs2go/utils"
 )
@@ -143,3 +150,122 @@ func TestUtilsPipe(t *testing.T) {
 		t.Errorf("written data %s, read data %s", msg, string(data))
 	}
 }
+
+// TestUtilsRemoteClient
+func TestUtilsRemoteClient(t *testing.T) {
+	var calls, posts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			// fail first call with 5xx status code
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`[{"block_name":"/a/b/c#1"}]`))
+		case "/post":
+			// POST request always fails with 5xx status code
+			atomic.AddInt32(&posts, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/notfound":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("no such api"))
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte(`[1,2,3]`))
+			gz.Close()
+		case "/ndjson":
+			w.Header().Set("Content-Type", "application/ndjson")
+			w.Write([]byte("{\"a\":1}\n{\"a\":2}\n"))
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 1024)))
+		}
+	}))
+	defer ts.Close()
+
+	client := utils.NewRemoteClient(ts.Client())
+	client.Retries = 2
+	client.Backoff = time.Millisecond
+	client.MaxSize = 512
+
+	// transient error should be retried
+	data, err := client.Get(ts.URL + "/flaky")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `[{"block_name":"/a/b/c#1"}]` || atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("unexpected response %s after %d calls", string(data), atomic.LoadInt32(&calls))
+	}
+
+	// POST request should not be retried unless client is idempotent
+	r := client.Fetch(ts.URL+"/post", []byte(`{"block_name":"/a/b/c#1"}`))
+	if r.Error == nil || atomic.LoadInt32(&posts) != 1 {
+		t.Errorf("POST request is called %d times, error %v", atomic.LoadInt32(&posts), r.Error)
+	}
+	client.Idempotent = true
+	client.Fetch(ts.URL+"/post", []byte(`{"block_name":"/a/b/c#1"}`))
+	if atomic.LoadInt32(&posts) != 4 {
+		t.Errorf("idempotent POST request is called %d times", atomic.LoadInt32(&posts)-1)
+	}
+	client.Idempotent = false
+
+	// client error should not be retried and should carry status code
+	r = client.Fetch(ts.URL+"/notfound", nil)
+	var herr *utils.HttpError
+	if !errors.As(r.Error, &herr) || herr.StatusCode != http.StatusNotFound || r.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected error %v", r.Error)
+	}
+
+	// gzip and ndjson responses
+	data, err = client.Get(ts.URL + "/gzip")
+	if err != nil || string(data) != "[1,2,3]" {
+		t.Errorf("unexpected gzip response %s, error %v", string(data), err)
+	}
+	data, err = client.Get(ts.URL + "/ndjson")
+	if err != nil || string(data) != `[{"a":1},{"a":2}]` {
+		t.Errorf("unexpected ndjson response %s, error %v", string(data), err)
+	}
+
+	// response size limit
+	_, err = client.Get(ts.URL + "/large")
+	if !errors.Is(err, utils.ErrResponseTooLarge) {
+		t.Errorf("expected size limit error, got %v", err)
+	}
+}
+
+// TestUtilsLRUCache
+func TestUtilsLRUCache(t *testing.T) {
+	cache := utils.NewLRUCache(10)
+	cache.Set(utils.CacheEntry{Key: "a", Api: "datatiers", Data: []byte("1234")}, time.Minute)
+	cache.Set(utils.CacheEntry{Key: "b", Api: "blocks", Data: []byte("1234")}, time.Minute)
+	if _, ok := cache.Get("a"); !ok {
+		t.Errorf("entry a is not found")
+	}
+	// entry b is least recently used one and should be evicted
+	cache.Set(utils.CacheEntry{Key: "c", Api: "datatiers", Data: []byte("1234")}, time.Minute)
+	if _, ok := cache.Get("b"); ok {
+		t.Errorf("entry b should be evicted")
+	}
+	if cache.Len() != 2 || cache.Size() != 8 {
+		t.Errorf("wrong cache length %d or size %d", cache.Len(), cache.Size())
+	}
+	// entries larger than cache are not stored
+	cache.Set(utils.CacheEntry{Key: "d", Api: "files", Data: []byte("12345678901")}, time.Minute)
+	if _, ok := cache.Get("d"); ok {
+		t.Errorf("entry d should not be cached")
+	}
+	// expired entry
+	cache.Set(utils.CacheEntry{Key: "e", Api: "files", Data: []byte("1")}, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, ok := cache.Get("e"); ok {
+		t.Errorf("entry e should be expired")
+	}
+	cache.Invalidate(func(api string) bool { return api == "datatiers" })
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Errorf("wrong cache length %d or size %d after invalidation", cache.Len(), cache.Size())
+	}
+	if cache.Hits != 1 || cache.Misses != 3 || cache.Evictions != 2 || cache.Invalidations != 2 {
+		t.Errorf("wrong cache stats %+v", cache.CacheStats)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -187,6 +188,54 @@ func TestValidatorBlocksAPI(t *testing.T) {
 	}
 }
 
+// TestValidatorFilters
+func TestValidatorFilters(t *testing.T) {
+	// set DBS lexicon patterns
+	lexiconFile := os.Getenv("DBS_LEXICON_FILE")
+	if lexiconFile == "" {
+		t.Error(errors.New("Please setup DBS_LEXICON_FILE env"))
+	}
+	lexPatterns, err := dbs.LoadPatterns(lexiconFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbs.LexiconPatterns = lexPatterns
+
+	host := "http://localhost:8111/dbs2go"
+	valid := []string{
+		"/datasets?dataset=" + url.QueryEscape("!=/a/b/RAW"),
+		"/datasets?dataset=" + url.QueryEscape("~/zmm/summer-v1/RAW"),
+		"/datasets?data_tier_name=" + url.QueryEscape("[RAW,GEN-SIM]"),
+		"/datasets?cdate=" + url.QueryEscape(">=1600000000"),
+		"/files?dataset=/a/b/RAW&file_size=" + url.QueryEscape("<1e+06"),
+	}
+	for _, v := range valid {
+		req, _ := http.NewRequest("GET", host+v, nil)
+		if err := dbs.Validate(req); err != nil {
+			t.Errorf("valid filter %s is rejected, error %v", v, err)
+		}
+	}
+	invalid := []string{
+		// lexicon validation of value
+		"/datasets?dataset=" + url.QueryEscape("!=a/b"),
+		"/datasets?data_tier_name=" + url.QueryEscape("[RAW,bla bla]"),
+		// comparison of non-numeric parameter
+		"/datasets?dataset=" + url.QueryEscape(">/a/b/RAW"),
+		// non-numeric value of comparison
+		"/files?dataset=/a/b/RAW&file_size=" + url.QueryEscape(">=abc"),
+		// operator within list of values
+		"/datasets?data_tier_name=" + url.QueryEscape("[RAW,!=AOD]"),
+		// too many repeated values
+		"/datasets?data_tier_name=RAW" + strings.Repeat("&data_tier_name=RAW", dbs.FilterMaxValues),
+	}
+	for _, v := range invalid {
+		req, _ := http.NewRequest("GET", host+v, nil)
+		if err := dbs.Validate(req); err == nil {
+			t.Errorf("invalid filter %s is accepted", v)
+		}
+	}
+}
+
 // TestValidatePostPayload
 func TestValidatePostPayload(t *testing.T) {
 	var req *http.Request
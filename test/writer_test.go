@@ -10,7 +10,9 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/dmwm/dbs2go/dbs"
 	"github.com/dmwm/dbs2go/utils"
@@ -144,6 +146,230 @@ func TestDBSWriter(t *testing.T) {
 	}
 }
 
+// TestDBSWriterQueryOptions provides a test of order_by and count options
+// of reader APIs using data inserted by TestDBSWriter
+func TestDBSWriterQueryOptions(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+
+	// helper function to get records from given GET API
+	getRecords := func(rurl string, hdlr func(http.ResponseWriter, *http.Request)) []dbs.Record {
+		rr, err := respRecorder("GET", rurl, nil, hdlr)
+		if err != nil {
+			t.Fatalf("fail to call %s, error %v", rurl, err)
+		}
+		var records []dbs.Record
+		data := rr.Body.Bytes()
+		if err := json.Unmarshal(data, &records); err != nil {
+			t.Fatalf("unable to unmarshal received data '%s', error %v", string(data), err)
+		}
+		return records
+	}
+
+	// files sorted by their ids in descending order
+	records := getRecords("/dbs2go/files?dataset=*&order_by=file_id:desc&fields=file_id", web.FilesHandler)
+	if len(records) < 2 {
+		t.Fatalf("not enough files %v", records)
+	}
+	for i := 1; i < len(records); i++ {
+		prev := records[i-1]["file_id"].(float64)
+		if cur := records[i]["file_id"].(float64); cur > prev {
+			t.Errorf("files are not sorted in descending order %v", records)
+		}
+	}
+
+	// count of files should match number of files
+	counts := getRecords("/dbs2go/files?dataset=*&count=true", web.FilesHandler)
+	if len(counts) != 1 || counts[0]["count"] != float64(len(records)) {
+		t.Errorf("wrong count of files %v, expect %d", counts, len(records))
+	}
+	counts = getRecords("/dbs2go/datasets?dataset=*&dataset_access_type=*&count=true", web.DatasetsHandler)
+	datasets := getRecords("/dbs2go/datasets?dataset=*&dataset_access_type=*", web.DatasetsHandler)
+	if len(counts) != 1 || counts[0]["count"] != float64(len(datasets)) {
+		t.Errorf("wrong count of datasets %v, expect %d", counts, len(datasets))
+	}
+
+	// fields are projected with and without detailed output and sorting
+	// by field which is not projected
+	for _, rurl := range []string{
+		"/dbs2go/datasets?dataset=*&dataset_access_type=*&fields=dataset",
+		"/dbs2go/datasets?dataset=*&dataset_access_type=*&fields=dataset&order_by=dataset_id:desc",
+	} {
+		records := getRecords(rurl, web.DatasetsHandler)
+		if len(records) != len(datasets) {
+			t.Errorf("wrong number of datasets %d of %s, expect %d", len(records), rurl, len(datasets))
+		}
+		for _, rec := range records {
+			if _, ok := rec["dataset"]; !ok || len(rec) != 1 {
+				t.Errorf("wrong projection of dataset %v of %s", rec, rurl)
+			}
+		}
+	}
+	files := getRecords("/dbs2go/files?dataset=*&fields=file_size,logical_file_name", web.FilesHandler)
+	if len(files) != len(records) {
+		t.Errorf("wrong number of files %d, expect %d", len(files), len(records))
+	}
+	for _, rec := range files {
+		if _, ok := rec["file_size"]; !ok || len(rec) != 2 {
+			t.Errorf("wrong projection of file %v", rec)
+		}
+	}
+
+	// non sortable field should be rejected
+	_, err := respRecorder("GET", "/dbs2go/files?dataset=*&order_by=md5", nil, web.FilesHandler)
+	if err == nil {
+		t.Errorf("order_by with non sortable field should fail")
+	}
+}
+
+// TestDBSWriterLastModified provides test of Last-Modified header and
+// If-Modified-Since conditional requests
+func TestDBSWriterLastModified(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+
+	web.Config.EtagMaxSize = 1024 * 1024
+	defer func() { web.Config.EtagMaxSize = 0 }()
+
+	rurl := "/dbs2go/datasets?dataset=*&dataset_access_type=*&detail=true"
+	rr, err := respRecorder("GET", rurl, nil, web.DatasetsHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []dbs.Record
+	if err := json.Unmarshal(rr.Body.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	var maxDate int64
+	for _, r := range records {
+		if val, ok := r["last_modification_date"].(float64); ok && int64(val) > maxDate {
+			maxDate = int64(val)
+		}
+	}
+	if maxDate == 0 {
+		t.Fatalf("no last_modification_date in datasets %v", records)
+	}
+	lastModified := rr.Header().Get("Last-Modified")
+	if expect := time.Unix(maxDate, 0).UTC().Format(http.TimeFormat); lastModified != expect {
+		t.Errorf("wrong Last-Modified %s, expect %s", lastModified, expect)
+	}
+
+	// not modified since last modification date
+	req, _ := http.NewRequest("GET", rurl, nil)
+	req.Header.Set("If-Modified-Since", lastModified)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(web.DatasetsHandler).ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("wrong status code %d, expect %d", rr.Code, http.StatusNotModified)
+	}
+
+	// modified since earlier date
+	req, _ = http.NewRequest("GET", rurl, nil)
+	req.Header.Set("If-Modified-Since", time.Unix(maxDate-1, 0).UTC().Format(http.TimeFormat))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(web.DatasetsHandler).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("wrong status code %d, expect %d", rr.Code, http.StatusOK)
+	}
+}
+
+// TestDBSWriterFilters provides a test of filter expressions of GET APIs
+func TestDBSWriterFilters(t *testing.T) {
+	// initialize DB for testing
+	dburi := os.Getenv("DBS_DB_FILE")
+	if dburi == "" {
+		log.Fatal("DBS_DB_FILE not defined")
+	}
+	db := initDB(false, dburi)
+	defer db.Close()
+
+	// helper function to get records from given GET API
+	getRecords := func(rurl string, hdlr func(http.ResponseWriter, *http.Request)) []dbs.Record {
+		rr, err := respRecorder("GET", rurl, nil, hdlr)
+		if err != nil {
+			t.Fatalf("fail to call %s, error %v", rurl, err)
+		}
+		var records []dbs.Record
+		data := rr.Body.Bytes()
+		if err := json.Unmarshal(data, &records); err != nil {
+			t.Fatalf("unable to unmarshal received data '%s', error %v", string(data), err)
+		}
+		return records
+	}
+
+	files := getRecords("/dbs2go/files?dataset=*&fields=logical_file_name,file_size", web.FilesHandler)
+	if len(files) < 2 {
+		t.Fatalf("not enough files %v", files)
+	}
+	lfn := files[0]["logical_file_name"].(string)
+	size := int64(files[0]["file_size"].(float64))
+
+	// negation
+	records := getRecords("/dbs2go/files?dataset=*&logical_file_name="+url.QueryEscape("!="+lfn), web.FilesHandler)
+	if len(records) != len(files)-1 {
+		t.Errorf("wrong number of files %d with negated lfn, expect %d", len(records), len(files)-1)
+	}
+
+	// numeric comparison
+	rurl := fmt.Sprintf("/dbs2go/files?dataset=*&file_size=%s", url.QueryEscape(fmt.Sprintf(">=%d", size)))
+	records = getRecords(rurl, web.FilesHandler)
+	var expect int
+	for _, r := range files {
+		if int64(r["file_size"].(float64)) >= size {
+			expect++
+		}
+	}
+	if len(records) != expect {
+		t.Errorf("wrong number of files %d with file_size>=%d, expect %d", len(records), size, expect)
+	}
+
+	// case-insensitive match
+	rurl = "/dbs2go/files?dataset=*&logical_file_name=" + url.QueryEscape("~"+strings.ToUpper(lfn))
+	records = getRecords(rurl, web.FilesHandler)
+	if len(records) != 1 {
+		t.Errorf("wrong number of files %d with case-insensitive lfn, expect 1", len(records))
+	}
+
+	// list of values
+	datasets := getRecords("/dbs2go/datasets?dataset=*&dataset_access_type=*", web.DatasetsHandler)
+	if len(datasets) == 0 {
+		t.Fatal("no datasets found")
+	}
+	var names []string
+	for _, r := range datasets {
+		names = append(names, r["dataset"].(string))
+	}
+	rurl = "/dbs2go/blocks?dataset=" + url.QueryEscape("["+strings.Join(names, ",")+"]")
+	records = getRecords(rurl, web.BlocksHandler)
+	blocks := getRecords("/dbs2go/blocks?dataset=*", web.BlocksHandler)
+	if len(records) != len(blocks) {
+		t.Errorf("wrong number of blocks %d for list of datasets, expect %d", len(records), len(blocks))
+	}
+
+	// repeated values with filter expressions
+	rurl = "/dbs2go/datasets?dataset_access_type=*&dataset=*&dataset=" + url.QueryEscape("!="+names[0])
+	records = getRecords(rurl, web.DatasetsHandler)
+	if len(records) != len(datasets)-1 {
+		t.Errorf("wrong number of datasets %d for repeated filters, expect %d", len(records), len(datasets)-1)
+	}
+
+	// filter expressions are not allowed in list of file names
+	rurl = "/dbs2go/files?logical_file_name=" + url.QueryEscape(lfn) + "&logical_file_name=" + url.QueryEscape("!="+lfn)
+	if _, err := respRecorder("GET", rurl, nil, web.FilesHandler); err == nil {
+		t.Errorf("filter expression in list of files is accepted")
+	}
+}
+
 // insertData provides a test to insert DBS data
 func insertData(t *testing.T, db *sql.DB, method, api, dataFile, attr string, hdlr func(http.ResponseWriter, *http.Request)) {
 	// setup HTTP request
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// BufferWriter provides the same functionality as http.ResponseWriter
+// and keeps written data in internal buffer. It is used by internal
+// DBS API calls which require results of another DBS API.
+type BufferWriter struct {
+	Buffer     *bytes.Buffer
+	StatusCode int
+}
+
+// NewBufferWriter returns new instance of BufferWriter
+func NewBufferWriter() *BufferWriter {
+	return &BufferWriter{Buffer: new(bytes.Buffer), StatusCode: http.StatusOK}
+}
+
+// Header implements Header() API of http.ResponseWriter interface
+func (b *BufferWriter) Header() http.Header {
+	return http.Header{}
+}
+
+// Write implements Write API of http.ResponseWriter interface
+func (b *BufferWriter) Write(data []byte) (int, error) {
+	return b.Buffer.Write(data)
+}
+
+// WriteHeader implements WriteHeader API of http.ResponseWriter interface
+func (b *BufferWriter) WriteHeader(statusCode int) {
+	b.StatusCode = statusCode
+}
+
+// Bytes returns content of internal buffer
+func (b *BufferWriter) Bytes() []byte {
+	return b.Buffer.Bytes()
+}
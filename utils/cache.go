@@ -0,0 +1,126 @@
+package utils
+
+// cache module provides bounded in-memory LRU cache
+//
+// The cache keeps API responses (as bytes) along with API name they belong
+// to, expiration time and last modification time of their records. The total
+// size of cached data is bounded, least recently used entries are evicted
+// when new entry does not fit into the cache. All cache operations are
+// accounted in CacheStats.
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStats keeps track of cache operations
+type CacheStats struct {
+	Hits          uint64 // total number of cache hits
+	Misses        uint64 // total number of cache misses
+	Evictions     uint64 // total number of evicted (or expired) entries
+	Invalidations uint64 // total number of invalidated entries
+}
+
+// CacheEntry represents single cache entry
+type CacheEntry struct {
+	Key     string    // cache key
+	Api     string    // API name of the entry
+	Data    []byte    // cached data
+	ModTime int64     // last modification time of cached records
+	Expire  time.Time // expiration time of the entry
+}
+
+// LRUCache represents bounded LRU cache
+type LRUCache struct {
+	CacheStats
+	MaxSize int64 // max size of cached data in bytes
+	size    int64
+	mutex   sync.Mutex
+	entries *list.List
+	items   map[string]*list.Element
+}
+
+// NewLRUCache creates new LRU cache with given max size in bytes
+func NewLRUCache(maxSize int64) *LRUCache {
+	return &LRUCache{
+		MaxSize: maxSize,
+		entries: list.New(),
+		items:   make(map[string]*list.Element),
+	}
+}
+
+// Get returns non-expired cache entry of given key
+func (c *LRUCache) Get(key string) (CacheEntry, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*CacheEntry)
+		if time.Now().Before(entry.Expire) {
+			c.entries.MoveToFront(elem)
+			atomic.AddUint64(&c.Hits, 1)
+			return *entry, true
+		}
+		c.remove(elem)
+		atomic.AddUint64(&c.Evictions, 1)
+	}
+	atomic.AddUint64(&c.Misses, 1)
+	return CacheEntry{}, false
+}
+
+// Set adds given entry to the cache with given time to live, entries larger
+// than cache size are ignored
+func (c *LRUCache) Set(entry CacheEntry, ttl time.Duration) {
+	size := int64(len(entry.Data))
+	if size > c.MaxSize || ttl <= 0 {
+		return
+	}
+	entry.Expire = time.Now().Add(ttl)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if elem, ok := c.items[entry.Key]; ok {
+		c.remove(elem)
+	}
+	for c.size+size > c.MaxSize {
+		c.remove(c.entries.Back())
+		atomic.AddUint64(&c.Evictions, 1)
+	}
+	c.items[entry.Key] = c.entries.PushFront(&entry)
+	c.size += size
+}
+
+// Invalidate removes all entries whose API matches given function
+func (c *LRUCache) Invalidate(match func(api string) bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for elem := c.entries.Front(); elem != nil; {
+		next := elem.Next()
+		if match(elem.Value.(*CacheEntry).Api) {
+			c.remove(elem)
+			atomic.AddUint64(&c.Invalidations, 1)
+		}
+		elem = next
+	}
+}
+
+// Len returns number of cache entries
+func (c *LRUCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.entries.Len()
+}
+
+// Size returns size of cached data in bytes
+func (c *LRUCache) Size() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.size
+}
+
+// helper function to remove given element from the cache
+func (c *LRUCache) remove(elem *list.Element) {
+	entry := c.entries.Remove(elem).(*CacheEntry)
+	delete(c.items, entry.Key)
+	c.size -= int64(len(entry.Data))
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// RowWriter represents writer of records in tabular form, i.e. header with
+// column names and their kinds (IntColumn, FloatColumn, StringColumn or
+// empty string if kind is unknown) followed by rows of values in the same order
+type RowWriter interface {
+	WriteColumns(cols, kinds []string) error
+	WriteRow(vals []interface{}) error
+}
+
+// CSVWriter provides the same functionality as http.ResponseWriter
+// and writes records in CSV (or TSV) format row by row to
+// given http.ResponseWriter
+type CSVWriter struct {
+	CSVWriter *csv.Writer
+	Writer    http.ResponseWriter
+}
+
+// NewCSVWriter creates new CSV writer with given field delimiter, e.g.
+// comma for CSV and tab for TSV format
+func NewCSVWriter(w http.ResponseWriter, delimiter rune) CSVWriter {
+	cw := csv.NewWriter(w)
+	cw.Comma = delimiter
+	return CSVWriter{CSVWriter: cw, Writer: w}
+}
+
+// Header implements Header() API of http.ResponseWriter interface
+func (c CSVWriter) Header() http.Header {
+	return c.Writer.Header()
+}
+
+// Write implements Write API of http.ResponseWriter interface
+func (c CSVWriter) Write(b []byte) (int, error) {
+	return c.Writer.Write(b)
+}
+
+// WriteHeader implements WriteHeader API of http.ResponseWriter interface
+func (c CSVWriter) WriteHeader(statusCode int) {
+	c.Writer.WriteHeader(statusCode)
+}
+
+// Unwrap returns underlying http.ResponseWriter
+func (c CSVWriter) Unwrap() http.ResponseWriter {
+	return c.Writer
+}
+
+// WriteColumns implements WriteColumns API of RowWriter interface
+func (c CSVWriter) WriteColumns(cols, kinds []string) error {
+	c.CSVWriter.Write(cols)
+	c.CSVWriter.Flush()
+	return c.CSVWriter.Error()
+}
+
+// WriteRow implements WriteRow API of RowWriter interface, NULL values
+// are written as empty fields
+func (c CSVWriter) WriteRow(vals []interface{}) error {
+	row := make([]string, len(vals))
+	for i, v := range vals {
+		row[i] = csvValue(v)
+	}
+	c.CSVWriter.Write(row)
+	c.CSVWriter.Flush()
+	return c.CSVWriter.Error()
+}
+
+// helper function to convert value into CSV field
+func csvValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -27,3 +27,8 @@ func (g GzipWriter) Write(b []byte) (int, error) {
 func (g GzipWriter) WriteHeader(statusCode int) {
 	g.Writer.WriteHeader(statusCode)
 }
+
+// Unwrap returns underlying http.ResponseWriter
+func (g GzipWriter) Unwrap() http.ResponseWriter {
+	return g.Writer
+}
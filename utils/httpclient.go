@@ -0,0 +1,262 @@
+package utils
+
+// module provides resilient HTTP client to talk to remote services
+//
+// The RemoteClient wraps standard http.Client and adds per-call timeout,
+// retries with exponential backoff and jitter on 5xx and connection errors,
+// status code aware errors, response size limit, gzip and ndjson support.
+// Only GET requests are retried, POST requests are retried only if client
+// is explicitly marked as Idempotent. Every remote call is accounted in
+// RemoteMetrics.
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// RemoteTimeout represents default timeout (in seconds) of single remote call
+var RemoteTimeout int
+
+// RemoteRetries represents default number of retries of failed remote call
+var RemoteRetries int
+
+// RemoteBackoff represents default base delay (in milliseconds) between retries
+var RemoteBackoff int
+
+// RemoteMaxSize represents default limit (in bytes) of remote response size
+var RemoteMaxSize int64
+
+// ErrResponseTooLarge is returned when remote response exceeds size limit
+var ErrResponseTooLarge = errors.New("remote response exceeds size limit")
+
+// HttpError represents non-2xx response of remote service
+type HttpError struct {
+	Url        string // request url
+	Status     string // http status string
+	StatusCode int    // http status code
+	Body       string // (truncated) body of the response
+}
+
+// Error implements error interface for HttpError
+func (e *HttpError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("%s returned %s: %s", e.Url, e.Status, e.Body)
+	}
+	return fmt.Sprintf("%s returned %s", e.Url, e.Status)
+}
+
+// Temporary reports if remote call can be retried
+func (e *HttpError) Temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// RemoteStats keeps track of remote calls
+type RemoteStats struct {
+	Calls   uint64 // total number of remote calls
+	Errors  uint64 // total number of failed remote calls
+	Retries uint64 // total number of retried remote calls
+	Latency uint64 // total latency of remote calls in nanoseconds
+}
+
+// AvgLatency returns average latency of remote calls in seconds
+func (r *RemoteStats) AvgLatency() float64 {
+	calls := atomic.LoadUint64(&r.Calls)
+	if calls == 0 {
+		return 0
+	}
+	return float64(atomic.LoadUint64(&r.Latency)) / float64(calls) / float64(time.Second)
+}
+
+// RemoteMetrics keeps metrics of all remote calls
+var RemoteMetrics RemoteStats
+
+// RemoteClient represents resilient HTTP client
+type RemoteClient struct {
+	Client  *http.Client  // underlying HTTP client
+	Timeout time.Duration // timeout of single call, zero means no timeout
+	Retries int           // number of retries of failed call
+	Backoff time.Duration // base delay between retries
+	MaxSize int64         // max size of response, zero means no limit
+
+	Idempotent bool // POST requests are idempotent and can be retried
+}
+
+// NewRemoteClient creates new remote client for given HTTP client using
+// default remote settings
+func NewRemoteClient(client *http.Client) *RemoteClient {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &RemoteClient{
+		Client:  client,
+		Timeout: time.Duration(RemoteTimeout) * time.Second,
+		Retries: RemoteRetries,
+		Backoff: time.Duration(RemoteBackoff) * time.Millisecond,
+		MaxSize: RemoteMaxSize,
+	}
+}
+
+// Get fetches data for provided URL via HTTP GET request
+func (c *RemoteClient) Get(rurl string) ([]byte, error) {
+	r := c.Fetch(rurl, nil)
+	return r.Data, r.Error
+}
+
+// Fetch fetches data for provided URL, args is a json dump of arguments
+// which is sent via HTTP POST request. The POST request is not retried
+// unless client is marked as Idempotent.
+func (c *RemoteClient) Fetch(rurl string, args []byte) ResponseType {
+	var response ResponseType
+	retries := c.Retries
+	if len(args) > 0 && !c.Idempotent {
+		retries = 0
+	}
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			atomic.AddUint64(&RemoteMetrics.Retries, 1)
+			delay := c.delay(attempt)
+			if VERBOSE > 0 {
+				log.Printf("retry %d of %s in %v, error %v", attempt, rurl, delay, response.Error)
+			}
+			time.Sleep(delay)
+		}
+		var retry bool
+		response, retry = c.fetch(rurl, args)
+		if response.Error == nil || !retry {
+			break
+		}
+	}
+	if response.Error != nil {
+		atomic.AddUint64(&RemoteMetrics.Errors, 1)
+	}
+	return response
+}
+
+// helper function to calculate delay of given retry attempt, it uses
+// exponential backoff with random jitter
+func (c *RemoteClient) delay(attempt int) time.Duration {
+	if c.Backoff <= 0 {
+		return 0
+	}
+	delay := c.Backoff << uint(attempt-1)
+	return delay + time.Duration(rand.Int63n(int64(c.Backoff)))
+}
+
+// helper function to perform single remote call, it returns response and
+// flag if call can be retried
+func (c *RemoteClient) fetch(rurl string, args []byte) (ResponseType, bool) {
+	response := ResponseType{Url: rurl}
+	ctx := context.Background()
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	var req *http.Request
+	var err error
+	if len(args) > 0 {
+		req, err = http.NewRequestWithContext(ctx, "POST", rurl, bytes.NewReader(args))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	} else {
+		req, err = http.NewRequestWithContext(ctx, "GET", rurl, nil)
+	}
+	if err != nil {
+		response.Error = err
+		return response, false
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	atomic.AddUint64(&RemoteMetrics.Calls, 1)
+	time0 := time.Now()
+	defer func() {
+		atomic.AddUint64(&RemoteMetrics.Latency, uint64(time.Since(time0)))
+	}()
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		// connection errors and timeouts are transient
+		response.Error = err
+		return response, true
+	}
+	defer resp.Body.Close()
+	response.Status = resp.Status
+	response.StatusCode = resp.StatusCode
+
+	var reader io.Reader = resp.Body
+	if strings.Contains(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			response.Error = err
+			return response, false
+		}
+		defer gz.Close()
+		reader = gz
+	}
+	if c.MaxSize > 0 {
+		reader = io.LimitReader(reader, c.MaxSize+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		response.Error = err
+		return response, true
+	}
+	if c.MaxSize > 0 && int64(len(data)) > c.MaxSize {
+		response.Error = fmt.Errorf("%w, url=%s limit=%d", ErrResponseTooLarge, rurl, c.MaxSize)
+		return response, false
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body := string(data)
+		if len(body) > 512 {
+			body = body[:512]
+		}
+		herr := &HttpError{
+			Url:        rurl,
+			Status:     resp.Status,
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(body),
+		}
+		response.Error = herr
+		return response, herr.Temporary()
+	}
+	if strings.Contains(resp.Header.Get("Content-Type"), "ndjson") {
+		data, err = NDJSONToJSON(data)
+		if err != nil {
+			response.Error = err
+			return response, false
+		}
+	}
+	response.Data = data
+	return response, false
+}
+
+// NDJSONToJSON converts ndjson data into JSON list
+func NDJSONToJSON(data []byte) ([]byte, error) {
+	var records []json.RawMessage
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			return nil, fmt.Errorf("invalid ndjson record %q", string(line))
+		}
+		records = append(records, json.RawMessage(line))
+	}
+	if records == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(records)
+}
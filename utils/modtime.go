@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+)
+
+// ModTimeWriter represents writer which keeps track of last modification
+// time of written records, e.g. to provide Last-Modified HTTP header
+type ModTimeWriter interface {
+	SetModTime(ts int64)
+}
+
+// FindModTimeWriter looks-up ModTimeWriter in a chain of writers, the
+// wrapping writers should provide their underlying writer via Unwrap method
+func FindModTimeWriter(w io.Writer) ModTimeWriter {
+	for w != nil {
+		if m, ok := w.(ModTimeWriter); ok {
+			return m
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		rw := u.Unwrap()
+		if rw == nil {
+			return nil
+		}
+		w = rw
+	}
+	return nil
+}
@@ -0,0 +1,475 @@
+package utils
+
+// module provides streaming writer of Apache Parquet files
+//
+// The ParquetWriter implements RowWriter interface and writes rows in
+// Parquet format, i.e. PAR1 magic followed by row groups and file footer
+// with Thrift (compact protocol) encoded metadata. Rows are buffered only
+// within single row group, therefore memory usage is bounded by row group
+// size. Every column is optional (NULL values are allowed) and stored in
+// single PLAIN encoded and uncompressed data page per row group.
+// For format specification see https://github.com/apache/parquet-format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ParquetRowGroupSize represents number of rows in single Parquet row group
+var ParquetRowGroupSize = 10000
+
+// column kinds used by RowWriter
+const (
+	IntColumn    = "int"    // integer column
+	FloatColumn  = "float"  // floating point column
+	StringColumn = "string" // string column
+)
+
+// parquet physical types, encodings and other enums
+const (
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+	parquetOptional  = 1
+	parquetUTF8      = 0
+	parquetPlain     = 0
+	parquetRLE       = 3
+	parquetDataPage  = 0
+	parquetMagic     = "PAR1"
+)
+
+// parquet column chunk metadata
+type parquetChunk struct {
+	Name      string
+	Type      int32
+	NumValues int64
+	Size      int64
+	Offset    int64
+}
+
+// parquet row group metadata
+type parquetRowGroup struct {
+	Chunks  []parquetChunk
+	NumRows int64
+	Size    int64
+}
+
+// ParquetWriter provides the same functionality as http.ResponseWriter
+// and writes records in Parquet format to given http.ResponseWriter
+type ParquetWriter struct {
+	Writer       http.ResponseWriter
+	RowGroupSize int
+	columns      []string
+	kinds        []string
+	rows         [][]interface{}
+	groups       []parquetRowGroup
+	offset       int64
+	numRows      int64
+}
+
+// NewParquetWriter creates new Parquet writer
+func NewParquetWriter(w http.ResponseWriter) *ParquetWriter {
+	size := ParquetRowGroupSize
+	if size <= 0 {
+		size = 10000
+	}
+	return &ParquetWriter{Writer: w, RowGroupSize: size}
+}
+
+// Header implements Header() API of http.ResponseWriter interface
+func (p *ParquetWriter) Header() http.Header {
+	return p.Writer.Header()
+}
+
+// Write implements Write API of http.ResponseWriter interface
+func (p *ParquetWriter) Write(b []byte) (int, error) {
+	return p.Writer.Write(b)
+}
+
+// WriteHeader implements WriteHeader API of http.ResponseWriter interface
+func (p *ParquetWriter) WriteHeader(statusCode int) {
+	p.Writer.WriteHeader(statusCode)
+}
+
+// Unwrap returns underlying http.ResponseWriter
+func (p *ParquetWriter) Unwrap() http.ResponseWriter {
+	return p.Writer
+}
+
+// WriteColumns implements WriteColumns API of RowWriter interface. Columns
+// of unknown kind get their kind from values of the first row group.
+func (p *ParquetWriter) WriteColumns(cols, kinds []string) error {
+	p.columns = cols
+	p.kinds = make([]string, len(cols))
+	copy(p.kinds, kinds)
+	return nil
+}
+
+// WriteRow implements WriteRow API of RowWriter interface
+func (p *ParquetWriter) WriteRow(vals []interface{}) error {
+	row := make([]interface{}, len(vals))
+	copy(row, vals)
+	p.rows = append(p.rows, row)
+	if len(p.rows) >= p.RowGroupSize {
+		return p.flush()
+	}
+	return nil
+}
+
+// Close writes remaining rows and Parquet file footer
+func (p *ParquetWriter) Close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	if err := p.magic(); err != nil {
+		return err
+	}
+	p.resolveKinds()
+	meta := p.fileMetaData()
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(meta)))
+	for _, data := range [][]byte{meta, size, []byte(parquetMagic)} {
+		if err := p.write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// helper function to write data and keep track of file offset
+func (p *ParquetWriter) write(data []byte) error {
+	n, err := p.Writer.Write(data)
+	p.offset += int64(n)
+	return err
+}
+
+// helper function to write magic bytes at the beginning of the file
+func (p *ParquetWriter) magic() error {
+	if p.offset > 0 {
+		return nil
+	}
+	return p.write([]byte(parquetMagic))
+}
+
+// helper function to resolve kinds of columns from buffered values, the
+// column without any value is treated as string column
+func (p *ParquetWriter) resolveKinds() {
+	for i, kind := range p.kinds {
+		if kind != "" {
+			continue
+		}
+		p.kinds[i] = StringColumn
+	rows:
+		for _, row := range p.rows {
+			if row[i] == nil {
+				continue
+			}
+			// kind is defined by the first non-NULL value
+			switch row[i].(type) {
+			case int64, int32, int:
+				p.kinds[i] = IntColumn
+			case float64, float32:
+				p.kinds[i] = FloatColumn
+			}
+			break rows
+		}
+	}
+}
+
+// Started reports if any Parquet data has been written, after that the
+// response can not be replaced by an error message
+func (p *ParquetWriter) Started() bool {
+	return p.offset > 0
+}
+
+// helper function to write buffered rows as new row group
+func (p *ParquetWriter) flush() error {
+	if len(p.rows) == 0 {
+		return nil
+	}
+	if err := p.magic(); err != nil {
+		return err
+	}
+	p.resolveKinds()
+	group := parquetRowGroup{NumRows: int64(len(p.rows))}
+	for i, col := range p.columns {
+		page, err := p.dataPage(i)
+		if err != nil {
+			return err
+		}
+		chunk := parquetChunk{
+			Name:      col,
+			Type:      parquetType(p.kinds[i]),
+			NumValues: int64(len(p.rows)),
+			Size:      int64(len(page)),
+			Offset:    p.offset,
+		}
+		if err := p.write(page); err != nil {
+			return err
+		}
+		group.Chunks = append(group.Chunks, chunk)
+		group.Size += chunk.Size
+	}
+	p.groups = append(p.groups, group)
+	p.numRows += group.NumRows
+	p.rows = p.rows[:0]
+	return nil
+}
+
+// helper function to encode data page (page header followed by definition
+// levels and PLAIN encoded non-NULL values) of given column
+func (p *ParquetWriter) dataPage(idx int) ([]byte, error) {
+	var values bytes.Buffer
+	levels := make([]byte, len(p.rows))
+	for i, row := range p.rows {
+		if row[idx] == nil {
+			continue
+		}
+		levels[i] = 1
+		if err := plainValue(&values, p.kinds[idx], row[idx]); err != nil {
+			return nil, fmt.Errorf("column %s: %w", p.columns[idx], err)
+		}
+	}
+	rle := rleLevels(levels)
+	var body bytes.Buffer
+	binary.Write(&body, binary.LittleEndian, uint32(len(rle)))
+	body.Write(rle)
+	body.Write(values.Bytes())
+
+	// page header
+	var t thriftWriter
+	t.i32Field(1, parquetDataPage)
+	t.i32Field(2, int32(body.Len()))
+	t.i32Field(3, int32(body.Len()))
+	t.structField(5)
+	t.i32Field(1, int32(len(p.rows)))
+	t.i32Field(2, parquetPlain)
+	t.i32Field(3, parquetRLE)
+	t.i32Field(4, parquetRLE)
+	t.stop()
+	t.stop()
+	return append(t.Bytes(), body.Bytes()...), nil
+}
+
+// helper function to encode file metadata
+func (p *ParquetWriter) fileMetaData() []byte {
+	var t thriftWriter
+	t.i32Field(1, 1) // version
+	t.listField(2, thriftStruct, len(p.columns)+1)
+	// root of the schema
+	t.begin()
+	t.binaryField(4, "schema")
+	t.i32Field(5, int32(len(p.columns)))
+	t.stop()
+	for i, col := range p.columns {
+		t.begin()
+		t.i32Field(1, parquetType(p.kinds[i]))
+		t.i32Field(3, parquetOptional)
+		t.binaryField(4, col)
+		if p.kinds[i] == StringColumn {
+			t.i32Field(6, parquetUTF8)
+		}
+		t.stop()
+	}
+	t.i64Field(3, p.numRows)
+	t.listField(4, thriftStruct, len(p.groups))
+	for _, g := range p.groups {
+		t.begin()
+		t.listField(1, thriftStruct, len(g.Chunks))
+		for _, c := range g.Chunks {
+			t.begin()
+			t.i64Field(2, c.Offset)
+			t.structField(3)
+			t.i32Field(1, c.Type)
+			t.listField(2, thriftI32, 2)
+			t.i32(parquetPlain)
+			t.i32(parquetRLE)
+			t.listField(3, thriftBinary, 1)
+			t.binary(c.Name)
+			t.i32Field(4, 0) // uncompressed
+			t.i64Field(5, c.NumValues)
+			t.i64Field(6, c.Size)
+			t.i64Field(7, c.Size)
+			t.i64Field(9, c.Offset)
+			t.stop()
+			t.stop()
+		}
+		t.i64Field(2, g.Size)
+		t.i64Field(3, g.NumRows)
+		t.stop()
+	}
+	t.binaryField(6, "dbs2go")
+	t.stop()
+	return t.Bytes()
+}
+
+// helper function to get parquet physical type of given column kind
+func parquetType(kind string) int32 {
+	switch kind {
+	case IntColumn:
+		return parquetInt64
+	case FloatColumn:
+		return parquetDouble
+	}
+	return parquetByteArray
+}
+
+// helper function to write PLAIN encoded value of given column kind
+func plainValue(buf *bytes.Buffer, kind string, val interface{}) error {
+	switch kind {
+	case IntColumn:
+		var v int64
+		switch vvv := val.(type) {
+		case int64:
+			v = vvv
+		case int32:
+			v = int64(vvv)
+		case int:
+			v = int64(vvv)
+		case float64:
+			v = int64(vvv)
+		default:
+			i, err := strconv.ParseInt(strings.TrimSpace(csvValue(val)), 10, 64)
+			if err != nil {
+				return err
+			}
+			v = i
+		}
+		return binary.Write(buf, binary.LittleEndian, v)
+	case FloatColumn:
+		var v float64
+		switch vvv := val.(type) {
+		case float64:
+			v = vvv
+		case float32:
+			v = float64(vvv)
+		case int64:
+			v = float64(vvv)
+		default:
+			f, err := strconv.ParseFloat(strings.TrimSpace(csvValue(val)), 64)
+			if err != nil {
+				return err
+			}
+			v = f
+		}
+		return binary.Write(buf, binary.LittleEndian, math.Float64bits(v))
+	}
+	s := csvValue(val)
+	binary.Write(buf, binary.LittleEndian, uint32(len(s)))
+	buf.WriteString(s)
+	return nil
+}
+
+// helper function to encode definition levels (bit width 1) using RLE runs
+// of RLE/bit-packing hybrid encoding
+func rleLevels(levels []byte) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		buf.Write(uvarint(uint64(j-i) << 1))
+		buf.WriteByte(levels[i])
+		i = j
+	}
+	return buf.Bytes()
+}
+
+// helper function to encode unsigned varint
+func uvarint(v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, v)
+	return buf[:n]
+}
+
+// thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftStruct = 12
+)
+
+// thriftWriter provides minimal encoder of Thrift compact protocol
+type thriftWriter struct {
+	bytes.Buffer
+	last  int16   // last field id of current struct
+	stack []int16 // field ids of outer structs
+}
+
+// helper function to write field header
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.WriteByte(typ)
+		t.Write(uvarint(uint64((id << 1) ^ (id >> 15))))
+	}
+	t.last = id
+}
+
+// begin starts new struct (e.g. list element)
+func (t *thriftWriter) begin() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+// stop ends current struct
+func (t *thriftWriter) stop() {
+	t.WriteByte(0)
+	if n := len(t.stack); n > 0 {
+		t.last = t.stack[n-1]
+		t.stack = t.stack[:n-1]
+	}
+}
+
+func (t *thriftWriter) i32(v int32) {
+	t.Write(uvarint(uint64(uint32((v << 1) ^ (v >> 31)))))
+}
+
+func (t *thriftWriter) i64(v int64) {
+	t.Write(uvarint(uint64((v << 1) ^ (v >> 63))))
+}
+
+func (t *thriftWriter) binary(v string) {
+	t.Write(uvarint(uint64(len(v))))
+	t.WriteString(v)
+}
+
+func (t *thriftWriter) i32Field(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.i32(v)
+}
+
+func (t *thriftWriter) i64Field(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.i64(v)
+}
+
+func (t *thriftWriter) binaryField(id int16, v string) {
+	t.field(id, thriftBinary)
+	t.binary(v)
+}
+
+// structField starts struct field which should be ended by stop
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+// listField writes list header, elements should follow it
+func (t *thriftWriter) listField(id int16, typ byte, size int) {
+	t.field(id, 9)
+	if size < 15 {
+		t.WriteByte(byte(size)<<4 | typ)
+	} else {
+		t.WriteByte(0xf0 | typ)
+		t.Write(uvarint(uint64(size)))
+	}
+}
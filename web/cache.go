@@ -0,0 +1,100 @@
+package web
+
+// module provides response cache of reader GET APIs
+//
+// The responses of APIs listed in Config.ResponseCacheTTLs are kept in
+// dbs.ResponseCache for given number of seconds. The cache key is composed
+// of API name, output format and normalised API parameters. Cached
+// responses are removed by writer APIs (see dbs.InvalidateCache) and the
+// cache can be bypassed by clients via Cache-Control: no-cache header.
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dmwm/dbs2go/dbs"
+	"github.com/dmwm/dbs2go/utils"
+)
+
+// CacheHeader represents HTTP header which reports status of response
+// cache, i.e. hit, miss or bypass
+const CacheHeader = "X-Dbs-Cache"
+
+// cacheWriter keeps copy of written data to store it in response cache
+type cacheWriter struct {
+	http.ResponseWriter
+	buffer   bytes.Buffer
+	maxSize  int
+	overflow bool
+	modTime  int64
+}
+
+// Unwrap returns underlying http.ResponseWriter
+func (c *cacheWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// Write implements Write API of http.ResponseWriter interface
+func (c *cacheWriter) Write(data []byte) (int, error) {
+	if !c.overflow {
+		if c.buffer.Len()+len(data) > c.maxSize {
+			c.overflow = true
+			c.buffer.Reset()
+		} else {
+			c.buffer.Write(data)
+		}
+	}
+	return c.ResponseWriter.Write(data)
+}
+
+// SetModTime implements ModTimeWriter interface
+func (c *cacheWriter) SetModTime(ts int64) {
+	if ts > c.modTime {
+		c.modTime = ts
+	}
+	if mtw := utils.FindModTimeWriter(c.ResponseWriter); mtw != nil {
+		mtw.SetModTime(ts)
+	}
+}
+
+// helper function to get cache TTL of given API, zero TTL means that API
+// responses are not cached
+func cacheTTL(api string) time.Duration {
+	if dbs.ResponseCache == nil {
+		return 0
+	}
+	return time.Duration(Config.ResponseCacheTTLs[api]) * time.Second
+}
+
+// helper function to check if client requested to bypass response cache
+func cacheBypass(r *http.Request) bool {
+	for _, v := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "no-cache" || v == "no-store" {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function to compose cache key of given API, output format and API
+// parameters, parameters and their values are sorted to get the same key
+// regardless of their order in HTTP request
+func cacheKey(api, format string, params dbs.Record) string {
+	values := make(url.Values)
+	for k, v := range params {
+		if vals, ok := v.([]string); ok {
+			vals = append([]string{}, vals...)
+			sort.Strings(vals)
+			values[k] = vals
+		} else {
+			values.Set(k, fmt.Sprintf("%v", v))
+		}
+	}
+	return fmt.Sprintf("%s/%s?%s", api, format, values.Encode())
+}
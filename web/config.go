@@ -14,33 +14,48 @@ import (
 
 // Configuration stores dbs configuration parameters
 type Configuration struct {
-	Port            int      `json:"port"`              // dbs port number
-	StaticDir       string   `json:"staticdir"`         // location of static directory
-	Base            string   `json:"base"`              // dbs base path
-	Verbose         int      `json:"verbose"`           // verbosity level
-	LogFile         string   `json:"log_file"`          // server log file (should ends with .log) or log area
-	UTC             bool     `json:"utc"`               // report logger time in UTC
-	MonitType       string   `json:"monit_type"`        // monit record type
-	MonitProducer   string   `json:"monit_producer"`    // monit record producer
-	Hmac            string   `json:"hmac"`              // cmsweb hmac file location
-	LimiterPeriod   string   `json:"limiter_rate"`      // limiter rate value
-	LimiterHeader   string   `json:"limiter_header"`    // limiter header to use
-	LimiterSkipList []string `json:"limiter_skip_list"` // limiter skip list
-	MetricsPrefix   string   `json:"metrics_prefix"`    // metrics prefix used for prometheus
-	ServerType      string   `json:"server_type"`       // DBS server type to start: DBSReader, DBSWriter, DBSMigrate, DBSMigration
-	Etag            string   `json:"etag"`              // etag value to use for ETag generation
-	CacheControl    string   `json:"cache_control"`     // Cache-Control value, e.g. max-age=300
-	CMSRole         []string `json:"cms_role"`          // cms role for write access
-	CMSGroup        []string `json:"cms_group"`         // cms group for write access
+	Port             int               `json:"port"`               // dbs port number
+	StaticDir        string            `json:"staticdir"`          // location of static directory
+	Base             string            `json:"base"`               // dbs base path
+	Verbose          int               `json:"verbose"`            // verbosity level
+	LogFile          string            `json:"log_file"`           // server log file (should ends with .log) or log area
+	UTC              bool              `json:"utc"`                // report logger time in UTC
+	MonitType        string            `json:"monit_type"`         // monit record type
+	MonitProducer    string            `json:"monit_producer"`     // monit record producer
+	Hmac             string            `json:"hmac"`               // cmsweb hmac file location
+	LimiterPeriod    string            `json:"limiter_rate"`       // limiter rate value
+	LimiterHeader    string            `json:"limiter_header"`     // limiter header to use
+	LimiterSkipList  []string          `json:"limiter_skip_list"`  // limiter skip list
+	MetricsPrefix    string            `json:"metrics_prefix"`     // metrics prefix used for prometheus
+	ServerType       string            `json:"server_type"`        // DBS server type to start: DBSReader, DBSWriter, DBSMigrate, DBSMigration
+	Etag             string            `json:"etag"`               // etag seed used for ETag generation of GET responses
+	EtagMaxSize      int               `json:"etag_max_size"`      // max size of GET response to compute its ETag
+	CacheControl     string            `json:"cache_control"`      // Cache-Control value, e.g. max-age=300
+	CacheControlApis map[string]string `json:"cache_control_apis"` // Cache-Control values of individual APIs
+	CMSRole          []string          `json:"cms_role"`           // cms role for write access
+	CMSGroup         []string          `json:"cms_group"`          // cms group for write access
 
 	// Migration server settings
-	MigrationDBFile          string `json:"migration_dbfile"`           // dbfile with secrets
-	MigrationServerInterval  int    `json:"migration_server_interval"`  // migration process interval
-	MigrationProcessTimeout  int    `json:"migration_process_timeout"`  // migration process timeout
-	MigrationCleanupInterval int    `json:"migration_cleanup_interval"` // migration cleanup interval
-	MigrationCleanupOffset   int64  `json:"migration_cleanup_offset"`   // migration cleanup offset
-	MigrationRetries         int64  `json:"migration_retries"`          // migration retries
-	MigrationAsyncTimeout    int    `json:"migration_async_timeout"`    // timeout for aysnc migration request
+	MigrationDBFile               string `json:"migration_dbfile"`                // dbfile with secrets
+	MigrationServerInterval       int    `json:"migration_server_interval"`       // migration process interval
+	MigrationProcessTimeout       int    `json:"migration_process_timeout"`       // migration process timeout
+	MigrationCleanupInterval      int    `json:"migration_cleanup_interval"`      // migration cleanup interval
+	MigrationCleanupOffset        int64  `json:"migration_cleanup_offset"`        // migration cleanup offset
+	MigrationRetries              int64  `json:"migration_retries"`               // migration retries
+	MigrationAsyncTimeout         int    `json:"migration_async_timeout"`         // timeout for aysnc migration request
+	MigrationVerify               bool   `json:"migration_verify"`                // verify migrated blocks against source DBS
+	MigrationSubscriptionInterval int    `json:"migration_subscription_interval"` // migration subscriptions interval
+	MigrationCleanup              bool   `json:"migration_cleanup"`               // enable migration cleanup server
+	MigrationCleanupCompleted     int64  `json:"migration_cleanup_completed"`     // retention of completed migration requests
+	MigrationCleanupTermFailed    int64  `json:"migration_cleanup_term_failed"`   // retention of terminally failed migration requests
+	MigrationCleanupExistInDB     int64  `json:"migration_cleanup_exist_in_db"`   // retention of exist in db migration requests
+	MigrationCleanupArchive       string `json:"migration_cleanup_archive"`       // JSONL file to archive removed migration requests
+
+	// remote HTTP client settings used by migration and fetch utilities
+	RemoteTimeout int   `json:"remote_timeout"`  // timeout of single remote call in seconds
+	RemoteRetries int   `json:"remote_retries"`  // number of retries of failed remote call
+	RemoteBackoff int   `json:"remote_backoff"`  // base delay between retries in milliseconds
+	RemoteMaxSize int64 `json:"remote_max_size"` // max size of remote response in bytes
 
 	// db related configuration
 	DBFile               string `json:"dbfile"`                  // dbs db file with secrets
@@ -54,6 +69,34 @@ type Configuration struct {
 	FileLumiMaxSize      int    `json:"file_lumi_max_size"`      // max size for []FileLumi insertion
 	FileLumiInsertMethod string `json:"file_lumi_insert_method"` // insert method for FileLumi list
 	ConcurrentBulkBlocks bool   `json:"concurrent_bulkblocks"`   // use concurrent BulkBlocks API
+	ParquetRowGroupSize  int    `json:"parquet_row_group_size"`  // number of rows in parquet row group
+
+	// read replicas settings
+	ReplicaDBFiles       []string `json:"replica_dbfiles"`        // dbfiles of read replicas of DBS DB
+	ReplicaCheckInterval int      `json:"replica_check_interval"` // replicas health check interval in seconds
+
+	// response cache settings
+	ResponseCacheTTLs      map[string]int `json:"response_cache_ttls"`       // cache TTLs (in seconds) of reader APIs, empty map disables the cache
+	ResponseCacheSize      int64          `json:"response_cache_size"`       // max size of response cache in bytes
+	ResponseCacheEntrySize int            `json:"response_cache_entry_size"` // max size of cached response in bytes
+
+	// prepared statements cache settings
+	StmtCacheSize int `json:"stmt_cache_size"` // max number of prepared statements per DB connection pool, zero (default) disables the cache
+
+	// query guardrails settings
+	QueryGuardrails       string   `json:"query_guardrails"`        // action for expensive queries: reject or downgrade, empty value disables guardrails
+	GuardrailMaxRows      int      `json:"guardrail_max_rows"`      // max number of records of downgraded queries
+	GuardrailMaxCost      float64  `json:"guardrail_max_cost"`      // max cost of query plan, zero value disables query plan check
+	GuardrailExemptRoles  []string `json:"guardrail_exempt_roles"`  // cms roles exempted from query guardrails
+	GuardrailExemptGroups []string `json:"guardrail_exempt_groups"` // cms groups of exempted roles
+
+	// dataset lineage settings
+	LineageMethod   string `json:"lineage_method"`    // method to walk dataset lineage: recursive (default) or iterative
+	LineageMaxDepth int    `json:"lineage_max_depth"` // max depth of dataset lineage and file ancestry
+
+	// bulkblocks settings
+	BulkBlocksLumiCheck      string `json:"bulkblocks_lumi_check"`      // action for injected lumis which already exist in valid files of the dataset: warn or reject, empty value disables the check
+	BulkBlocksParentageCheck string `json:"bulkblocks_parentage_check"` // action for inconsistent parentage of injected files: warn or reject, empty value disables the check
 
 	// server static parts
 	Templates string `json:"templates"` // location of server templates
@@ -119,9 +162,48 @@ func ParseConfig(configFile string) error {
 	if Config.MigrationCleanupInterval == 0 {
 		Config.MigrationCleanupInterval = 600 // in seconds
 	}
+	if Config.MigrationSubscriptionInterval == 0 {
+		Config.MigrationSubscriptionInterval = 600 // in seconds
+	}
 	if Config.MigrationCleanupOffset == 0 {
 		Config.MigrationCleanupOffset = 3 * 30 * 24 * 60 * 60 // 3 months in seconds
 	}
+	if Config.MigrationCleanupCompleted == 0 {
+		Config.MigrationCleanupCompleted = Config.MigrationCleanupOffset
+	}
+	if Config.MigrationCleanupExistInDB == 0 {
+		Config.MigrationCleanupExistInDB = Config.MigrationCleanupOffset
+	}
+	if Config.MigrationCleanupTermFailed == 0 {
+		Config.MigrationCleanupTermFailed = 2 * 7 * 24 * 60 * 60 // 2 weeks in seconds
+	}
+	if Config.RemoteTimeout == 0 {
+		Config.RemoteTimeout = 300 // in seconds
+	}
+	if Config.RemoteRetries == 0 {
+		Config.RemoteRetries = 3
+	}
+	if Config.RemoteBackoff == 0 {
+		Config.RemoteBackoff = 500 // in milliseconds
+	}
+	if Config.RemoteMaxSize == 0 {
+		Config.RemoteMaxSize = 1024 * 1024 * 1024 // 1GB
+	}
+	if Config.ParquetRowGroupSize == 0 {
+		Config.ParquetRowGroupSize = 10000
+	}
+	if Config.ReplicaCheckInterval == 0 {
+		Config.ReplicaCheckInterval = 30 // in seconds
+	}
+	if Config.EtagMaxSize == 0 {
+		Config.EtagMaxSize = 1024 * 1024 // 1MB
+	}
+	if Config.ResponseCacheSize == 0 {
+		Config.ResponseCacheSize = 64 * 1024 * 1024 // 64MB
+	}
+	if Config.ResponseCacheEntrySize == 0 {
+		Config.ResponseCacheEntrySize = 1024 * 1024 // 1MB
+	}
 	if Config.MetricsPrefix == "" {
 		Config.MetricsPrefix = "dbs2go"
 	}
@@ -150,5 +232,26 @@ func ParseConfig(configFile string) error {
 	if Config.TlsRefreshInterval == 0 {
 		Config.TlsRefreshInterval = 4 * 60 * 60 // 4 hours
 	}
+	if Config.GuardrailMaxRows == 0 {
+		Config.GuardrailMaxRows = 1000
+	}
+	if Config.LineageMaxDepth == 0 {
+		Config.LineageMaxDepth = 20
+	}
+	if Config.LineageMethod != "" && Config.LineageMethod != "recursive" && Config.LineageMethod != "iterative" {
+		return fmt.Errorf("invalid lineage_method value '%s', should be recursive or iterative", Config.LineageMethod)
+	}
+	if Config.BulkBlocksLumiCheck != "" && Config.BulkBlocksLumiCheck != "warn" && Config.BulkBlocksLumiCheck != "reject" {
+		return fmt.Errorf("invalid bulkblocks_lumi_check value '%s', should be warn or reject", Config.BulkBlocksLumiCheck)
+	}
+	if Config.BulkBlocksParentageCheck != "" && Config.BulkBlocksParentageCheck != "warn" && Config.BulkBlocksParentageCheck != "reject" {
+		return fmt.Errorf("invalid bulkblocks_parentage_check value '%s', should be warn or reject", Config.BulkBlocksParentageCheck)
+	}
+	if Config.QueryGuardrails != "" && Config.QueryGuardrails != "reject" && Config.QueryGuardrails != "downgrade" {
+		return fmt.Errorf("invalid query_guardrails value '%s', should be reject or downgrade", Config.QueryGuardrails)
+	}
+	if len(Config.GuardrailExemptRoles) != len(Config.GuardrailExemptGroups) {
+		return fmt.Errorf("not equal length of guardrail_exempt_roles and guardrail_exempt_groups attributes")
+	}
 	return nil
 }
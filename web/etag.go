@@ -0,0 +1,145 @@
+package web
+
+// module provides content-aware ETag and Last-Modified headers of GET APIs
+//
+// The response of GET API is buffered (up to Config.EtagMaxSize bytes) and
+// its ETag is computed from the response content, while Last-Modified header
+// is derived from max last_modification_date of the written records. If the
+// client provides matching If-None-Match or If-Modified-Since headers the
+// server replies with 304 Not Modified status without a body. Responses
+// which exceed the buffer size are streamed without ETag.
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// etagWriter implements http.ResponseWriter and ModTimeWriter interfaces
+type etagWriter struct {
+	http.ResponseWriter
+	request   *http.Request
+	buffer    bytes.Buffer
+	maxSize   int
+	status    int
+	streaming bool
+	modTime   int64
+	cache     string
+}
+
+// helper function to create new etag writer for given HTTP request and
+// Cache-Control value of successful responses
+func newEtagWriter(w http.ResponseWriter, r *http.Request, cache string) *etagWriter {
+	return &etagWriter{ResponseWriter: w, request: r, maxSize: Config.EtagMaxSize, cache: cache}
+}
+
+// Unwrap returns underlying http.ResponseWriter
+func (e *etagWriter) Unwrap() http.ResponseWriter {
+	return e.ResponseWriter
+}
+
+// SetModTime implements ModTimeWriter interface
+func (e *etagWriter) SetModTime(ts int64) {
+	if ts > e.modTime {
+		e.modTime = ts
+	}
+}
+
+// WriteHeader implements WriteHeader API of http.ResponseWriter interface,
+// non successful responses are written without ETag
+func (e *etagWriter) WriteHeader(statusCode int) {
+	if e.streaming {
+		e.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	e.status = statusCode
+	if statusCode != http.StatusOK {
+		e.stream()
+	}
+}
+
+// Write implements Write API of http.ResponseWriter interface
+func (e *etagWriter) Write(data []byte) (int, error) {
+	if !e.streaming && e.buffer.Len()+len(data) > e.maxSize {
+		e.stream()
+	}
+	if e.streaming {
+		return e.ResponseWriter.Write(data)
+	}
+	return e.buffer.Write(data)
+}
+
+// helper function to write buffered data and switch to streaming mode
+func (e *etagWriter) stream() {
+	e.streaming = true
+	if e.status == 0 || e.status == http.StatusOK {
+		e.setCacheControl()
+	}
+	if e.status != 0 {
+		e.ResponseWriter.WriteHeader(e.status)
+	}
+	if e.buffer.Len() > 0 {
+		e.ResponseWriter.Write(e.buffer.Bytes())
+		e.buffer.Reset()
+	}
+}
+
+// helper function to complete the response, i.e. to set ETag and
+// Last-Modified headers and write either buffered data or 304 status
+func (e *etagWriter) finish() {
+	if e.streaming {
+		return
+	}
+	// ETag seed allows to invalidate ETags of all responses, e.g. when
+	// server output changes
+	etag := Etag(Config.Etag+e.buffer.String(), false)
+	e.Header().Set("ETag", etag)
+	e.setCacheControl()
+	if e.modTime > 0 {
+		e.Header().Set("Last-Modified", time.Unix(e.modTime, 0).UTC().Format(http.TimeFormat))
+	}
+	if notModified(e.request, etag, e.modTime) {
+		e.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if e.status != 0 {
+		e.ResponseWriter.WriteHeader(e.status)
+	}
+	e.ResponseWriter.Write(e.buffer.Bytes())
+}
+
+// helper function to set Cache-Control header of the response
+func (e *etagWriter) setCacheControl() {
+	if e.cache != "" {
+		e.Header().Set("Cache-Control", e.cache)
+	}
+}
+
+// helper function to check conditional headers of HTTP request, the
+// If-None-Match header takes precedence over If-Modified-Since one
+func notModified(r *http.Request, etag string, modTime int64) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if since := r.Header.Get("If-Modified-Since"); since != "" && modTime > 0 {
+		if t, err := http.ParseTime(since); err == nil && modTime <= t.Unix() {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function to get Cache-Control value of given API
+func cacheControl(api string) string {
+	if val, ok := Config.CacheControlApis[api]; ok {
+		return val
+	}
+	return Config.CacheControl
+}
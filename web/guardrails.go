@@ -0,0 +1,22 @@
+package web
+
+// module provides query guardrails of reader GET APIs
+//
+// Expensive queries of reader APIs are rejected or downgraded by
+// dbs.CheckGuardrails according to Config.QueryGuardrails action. Clients
+// with one of the roles listed in Config.GuardrailExemptRoles, e.g. admin
+// tools, are exempted from guardrails.
+
+import (
+	"net/http"
+)
+
+// helper function to check if client is exempted from query guardrails
+func guardrailExempt(r *http.Request) bool {
+	for i, role := range Config.GuardrailExemptRoles {
+		if CMSAuth.CheckCMSAuthz(r.Header, role, Config.GuardrailExemptGroups[i], "") {
+			return true
+		}
+	}
+	return false
+}
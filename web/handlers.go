@@ -302,6 +302,19 @@ func ApisHandler(w http.ResponseWriter, r *http.Request) {
 		rec := make(dbs.Record)
 		rec["api"] = api
 		rec["methods"] = methods
+		// api parameters file uses API names, i.e. last part of route path
+		name := api[strings.LastIndex(api, "/")+1:]
+		if params, fields, err := dbs.GetApiParameters(name); err == nil {
+			if len(params) > 0 {
+				rec["parameters"] = params
+			}
+			if len(fields) > 0 {
+				rec["fields"] = fields
+			}
+		}
+		if order, err := dbs.GetApiOrderBy(name); err == nil && len(order) > 0 {
+			rec["order_by"] = order
+		}
 		records = append(records, rec)
 	}
 	data, err := json.Marshal(records)
@@ -311,6 +324,56 @@ func ApisHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
+// formatContentTypes defines content types of supported output formats
+var formatContentTypes = map[string]string{
+	"json":    "application/json",
+	"ndjson":  "application/ndjson",
+	"csv":     "text/csv",
+	"tsv":     "text/tab-separated-values",
+	"parquet": "application/vnd.apache.parquet",
+	"dot":     "text/vnd.graphviz",
+}
+
+// list of GET APIs which do not provide tabular output
+var nonTabularApis = []string{"blockdump", "verify", "cleanup", "lineage", "lumimask", "compare"}
+
+// list of GET APIs which provide graph output in Graphviz DOT format
+var graphApis = []string{"lineage"}
+
+// helper function to determine output format of GET API from format
+// parameter or Accept HTTP header
+func outputFormat(r *http.Request, api string) (string, error) {
+	format := "json"
+	accept := r.Header.Get("Accept")
+	if accept == "application/ndjson" {
+		format = "ndjson"
+	} else if strings.Contains(accept, "text/csv") {
+		format = "csv"
+	} else if strings.Contains(accept, "text/tab-separated-values") {
+		format = "tsv"
+	} else if strings.Contains(accept, "application/vnd.apache.parquet") {
+		format = "parquet"
+	} else if strings.Contains(accept, "text/vnd.graphviz") {
+		format = "dot"
+	}
+	if val := r.URL.Query().Get("format"); val != "" {
+		format = strings.ToLower(val)
+		if _, ok := formatContentTypes[format]; !ok {
+			msg := fmt.Sprintf("unsupported format '%s', supported formats: json, ndjson, csv, tsv, parquet, dot", val)
+			return "", dbs.Error(dbs.InvalidParamErr, dbs.ParametersErrorCode, msg, "web.outputFormat")
+		}
+	}
+	if format == "dot" && !utils.InList(api, graphApis) {
+		msg := fmt.Sprintf("%s API does not support %s format", api, format)
+		return "", dbs.Error(dbs.InvalidParamErr, dbs.ParametersErrorCode, msg, "web.outputFormat")
+	}
+	if format != "json" && format != "ndjson" && format != "dot" && utils.InList(api, nonTabularApis) {
+		msg := fmt.Sprintf("%s API does not support %s format", api, format)
+		return "", dbs.Error(dbs.InvalidParamErr, dbs.ParametersErrorCode, msg, "web.outputFormat")
+	}
+	return format, nil
+}
+
 // helper function to parse POST HTTP request payload
 func parseParams(r *http.Request) (dbs.Record, error) {
 	params := make(dbs.Record)
@@ -460,7 +523,8 @@ func DBSPostHandler(w http.ResponseWriter, r *http.Request, a string) {
 	}
 
 	headerContentType := r.Header.Get("Content-Type")
-	if headerContentType != "application/json" {
+	// lfnlookup API accepts stream of files in NDJSON data-format
+	if headerContentType != "application/json" && !(a == "lfnlookup" && headerContentType == "application/ndjson") {
 		msg := fmt.Sprintf("unsupported Content-Type: '%s'", headerContentType)
 		e := dbs.Error(dbs.ContentTypeErr, dbs.ContentTypeErrorCode, msg, "web.DBSPostHandler")
 		responseMsg(w, r, e, http.StatusUnsupportedMediaType)
@@ -502,7 +566,7 @@ func DBSPostHandler(w http.ResponseWriter, r *http.Request, a string) {
 		defer gw.Close()
 		api.Writer = utils.GzipWriter{GzipWriter: gw, Writer: w}
 	}
-	if a == "fileArray" || a == "datasetlist" || a == "fileparentsbylumi" || a == "filelumis" || a == "blockparents" || a == "process" {
+	if a == "fileArray" || a == "datasetlist" || a == "fileparentsbylumi" || a == "inferfileparents" || a == "filelumis" || a == "blockparents" || a == "process" || a == "fileancestry" || a == "lumimask" {
 		params, err = parsePayload(r)
 		if err != nil {
 			responseMsg(w, r, err, http.StatusInternalServerError)
@@ -547,6 +611,16 @@ func DBSPostHandler(w http.ResponseWriter, r *http.Request, a string) {
 		err = api.FileArray()
 	} else if a == "fileparentsbylumi" {
 		err = api.FileParentsByLumi()
+	} else if a == "inferfileparents" {
+		err = api.InsertFileParentsByLumi()
+	} else if a == "fileancestry" {
+		err = api.FileAncestry()
+	} else if a == "filesbymask" {
+		err = api.FilesByMask()
+	} else if a == "lfnlookup" {
+		err = api.LfnLookup()
+	} else if a == "lumimask" {
+		err = api.LumiMask()
 	} else if a == "filelumis" {
 		err = api.FileLumis()
 	} else if a == "blockparents" {
@@ -559,6 +633,14 @@ func DBSPostHandler(w http.ResponseWriter, r *http.Request, a string) {
 		err = api.ProcessMigrationCtx(dbs.MigrationProcessTimeout)
 	} else if a == "remove" {
 		err = api.RemoveMigration()
+	} else if a == "subscribe" {
+		err = api.SubmitSubscription()
+	} else if a == "pause" {
+		err = api.PauseSubscription()
+	} else if a == "resume" {
+		err = api.ResumeSubscription()
+	} else if a == "cleanup" {
+		err = api.CleanupMigration(false)
 	}
 	if err != nil {
 		responseMsg(w, r, err, http.StatusBadRequest)
@@ -579,38 +661,91 @@ func DBSGetHandler(w http.ResponseWriter, r *http.Request, a string) {
 		return
 	}
 
-	// all outputs will be added to output list
+	// determine output format, all JSON outputs will be added to output list
+	format, err := outputFormat(r, a)
+	if err != nil {
+		responseMsg(w, r, err, http.StatusBadRequest)
+		return
+	}
 	sep := ","
-	if r.Header.Get("Accept") == "application/ndjson" {
+	if format == "ndjson" {
 		sep = ""
 	}
-	if sep != "" {
-		w.Header().Add("Content-Type", "application/json")
-	} else {
-		w.Header().Add("Content-Type", "application/ndjson")
-	}
+	w.Header().Add("Content-Type", formatContentTypes[format])
 
 	params, err := parseParams(r)
 	if err != nil {
 		responseMsg(w, r, err, http.StatusBadRequest)
 		return
 	}
+	delete(params, "format")
 	if utils.VERBOSE > 0 {
 		dn, _ := r.Header["Cms-Authn-Dn"]
 		log.Printf("DBSGetHandler: API=%s, dn=%s, uri=%+v, params: %+v", a, dn, requestURI(r), params)
 	}
+	// cache key should be composed before query options are parsed
+	var key string
+	ttl := cacheTTL(a)
+	if ttl > 0 {
+		key = cacheKey(a, format, params)
+	}
 	api := &dbs.API{
 		Writer:    w,
 		Params:    params,
 		Separator: sep,
 		Api:       a,
+		Format:    format,
+	}
+	if err := api.ParseOptions(); err != nil {
+		responseMsg(w, r, err, http.StatusBadRequest)
+		return
+	}
+	if !guardrailExempt(r) {
+		if err := api.CheckGuardrails(); err != nil {
+			responseMsg(w, r, err, http.StatusBadRequest)
+			return
+		}
 	}
+	// buffer API output to compute its ETag, the etag writer should be
+	// finished after gzip writer is closed
+	ew := newEtagWriter(w, r, cacheControl(a))
+	defer ew.finish()
+	w = ew
+	api.Writer = w
 	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
 		w.Header().Set("Content-Encoding", "gzip")
 		gw := gzip.NewWriter(w)
 		defer gw.Close()
 		api.Writer = utils.GzipWriter{GzipWriter: gw, Writer: w}
 	}
+	// serve response from the cache or keep its copy, the cache contains
+	// output of format writers
+	var cw *cacheWriter
+	if ttl > 0 {
+		// strong consistency requires data from primary DB and downgraded
+		// query should get limited output
+		if cacheBypass(r) || api.Strong || api.Limit > 0 {
+			w.Header().Set(CacheHeader, "bypass")
+		} else if entry, ok := dbs.ResponseCache.Get(key); ok {
+			w.Header().Set(CacheHeader, "hit")
+			ew.SetModTime(entry.ModTime)
+			api.Writer.Write(entry.Data)
+			return
+		} else {
+			w.Header().Set(CacheHeader, "miss")
+		}
+		cw = &cacheWriter{ResponseWriter: api.Writer, maxSize: Config.ResponseCacheEntrySize}
+		api.Writer = cw
+	}
+	var pw *utils.ParquetWriter
+	if format == "csv" {
+		api.Writer = utils.NewCSVWriter(api.Writer, ',')
+	} else if format == "tsv" {
+		api.Writer = utils.NewCSVWriter(api.Writer, '\t')
+	} else if format == "parquet" {
+		pw = utils.NewParquetWriter(api.Writer)
+		api.Writer = pw
+	}
 	if utils.VERBOSE > 0 {
 		log.Println(api.String())
 	}
@@ -672,17 +807,48 @@ func DBSGetHandler(w http.ResponseWriter, r *http.Request, a string) {
 		err = api.ParentDatasetFileLumiIds()
 	} else if a == "datasetaccesstypes" {
 		err = api.DatasetAccessTypes()
+	} else if a == "lineage" {
+		err = api.Lineage()
+	} else if a == "lumimask" {
+		err = api.LumiMask()
+	} else if a == "compare" {
+		err = api.Compare()
+	} else if a == "lumiduplicates" {
+		err = api.LumiDuplicates()
+	} else if a == "duplicates" {
+		err = api.Duplicates()
 	} else if a == "status" {
 		err = api.StatusMigration()
 	} else if a == "total" {
 		err = api.TotalMigration()
+	} else if a == "verify" {
+		err = api.VerifyMigration()
+	} else if a == "subscriptions" {
+		err = api.StatusSubscription()
+	} else if a == "cleanup" {
+		err = api.CleanupMigration(true)
 	} else {
 		err = dbs.NotImplementedApiErr
 	}
+	if err == nil && pw != nil {
+		// write remaining row group and parquet footer
+		err = pw.Close()
+	}
+	if err != nil && pw != nil && pw.Started() {
+		// parquet data is already sent, instead of appending JSON error to
+		// it we abort the response and client gets truncated file
+		log.Printf("abort parquet response of %s API, error %v", a, err)
+		panic(http.ErrAbortHandler)
+	}
 	if err != nil {
 		responseMsg(w, r, err, http.StatusBadRequest)
 		return
 	}
+	// downgraded responses are not cached since they are incomplete
+	if cw != nil && !cw.overflow && api.Limit == 0 {
+		entry := utils.CacheEntry{Key: key, Api: a, Data: cw.buffer.Bytes(), ModTime: cw.modTime}
+		dbs.ResponseCache.Set(entry, ttl)
+	}
 }
 
 // NotImplementedHandler returns server status error
@@ -875,6 +1041,12 @@ func DatasetChildrenHandler(w http.ResponseWriter, r *http.Request) {
 	DBSGetHandler(w, r, "datasetchildren")
 }
 
+// LineageHandler provides access to Lineage DBS API.
+// Takes the following arguments: dataset, direction, depth
+func LineageHandler(w http.ResponseWriter, r *http.Request) {
+	DBSGetHandler(w, r, "lineage")
+}
+
 // DatasetAccessTypesHandler provides access to DatasetAccessTypes DBS API.
 // Takes the following arguments: dataset_access_type
 func DatasetAccessTypesHandler(w http.ResponseWriter, r *http.Request) {
@@ -926,6 +1098,35 @@ func FileLumisHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// LumiMaskHandler provides access to LumiMask DBS API.
+// Takes the following arguments: dataset, block_name, logical_file_name,
+// run_num, validFileOnly, events
+func LumiMaskHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		DBSPostHandler(w, r, "lumimask")
+	} else {
+		DBSGetHandler(w, r, "lumimask")
+	}
+}
+
+// CompareHandler provides access to Compare DBS API.
+// Takes the following arguments: a, b, validFileOnly
+func CompareHandler(w http.ResponseWriter, r *http.Request) {
+	DBSGetHandler(w, r, "compare")
+}
+
+// LumiDuplicatesHandler provides access to LumiDuplicates DBS API.
+// Takes the following arguments: dataset
+func LumiDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	DBSGetHandler(w, r, "lumiduplicates")
+}
+
+// DuplicatesHandler provides access to Duplicates DBS API.
+// Takes the following arguments: dataset, checksum, scope, validFileOnly
+func DuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	DBSGetHandler(w, r, "duplicates")
+}
+
 // FileArrayHandler provides access to FileArray DBS API
 // POST API takes no argument, the payload should be supplied as JSON
 func FileArrayHandler(w http.ResponseWriter, r *http.Request) {
@@ -938,12 +1139,37 @@ func DatasetListHandler(w http.ResponseWriter, r *http.Request) {
 	DBSPostHandler(w, r, "datasetlist")
 }
 
+// LfnLookupHandler provides access to LfnLookup DBS API
+// POST API takes no argument, the payload should be supplied as JSON or
+// NDJSON stream of files
+func LfnLookupHandler(w http.ResponseWriter, r *http.Request) {
+	DBSPostHandler(w, r, "lfnlookup")
+}
+
 // FileParentsByLumiHandler provides access to FileParentsByLumi DBS API
 // POST API takes no argument, the payload should be supplied as JSON
 func FileParentsByLumiHandler(w http.ResponseWriter, r *http.Request) {
 	DBSPostHandler(w, r, "fileparentsbylumi")
 }
 
+// InferFileParentsHandler provides access to InsertFileParentsByLumi DBS API
+// POST API takes no argument, the payload should be supplied as JSON
+func InferFileParentsHandler(w http.ResponseWriter, r *http.Request) {
+	DBSPostHandler(w, r, "inferfileparents")
+}
+
+// FileAncestryHandler provides access to FileAncestry DBS API
+// POST API takes no argument, the payload should be supplied as JSON
+func FileAncestryHandler(w http.ResponseWriter, r *http.Request) {
+	DBSPostHandler(w, r, "fileancestry")
+}
+
+// FilesByMaskHandler provides access to FilesByMask DBS API
+// POST API takes no argument, the payload should be supplied as JSON
+func FilesByMaskHandler(w http.ResponseWriter, r *http.Request) {
+	DBSPostHandler(w, r, "filesbymask")
+}
+
 // BulkBlocksHandler provides access to BulkBlocks DBS API
 // POST API takes no argument, the payload should be supplied as JSON
 func BulkBlocksHandler(w http.ResponseWriter, r *http.Request) {
@@ -989,3 +1215,42 @@ func MigrationStatusHandler(w http.ResponseWriter, r *http.Request) {
 func MigrationTotalHandler(w http.ResponseWriter, r *http.Request) {
 	DBSGetHandler(w, r, "total")
 }
+
+// MigrationVerifyHandler provides access to VerifyMigration DBS API
+func MigrationVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	DBSGetHandler(w, r, "verify")
+}
+
+// MigrationCleanupHandler provides access to CleanupMigration DBS API
+// GET API reports migration requests which would be removed (dry_run=true),
+// while POST API removes them
+func MigrationCleanupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		DBSPostHandler(w, r, "cleanup")
+	} else {
+		DBSGetHandler(w, r, "cleanup")
+	}
+}
+
+// SubscriptionSubmitHandler provides access to SubmitSubscription DBS API
+// POST API takes no argument, the payload should be supplied as JSON
+func SubscriptionSubmitHandler(w http.ResponseWriter, r *http.Request) {
+	DBSPostHandler(w, r, "subscribe")
+}
+
+// SubscriptionStatusHandler provides access to StatusSubscription DBS API
+func SubscriptionStatusHandler(w http.ResponseWriter, r *http.Request) {
+	DBSGetHandler(w, r, "subscriptions")
+}
+
+// SubscriptionPauseHandler provides access to PauseSubscription DBS API
+// POST API takes no argument, the payload should be supplied as JSON
+func SubscriptionPauseHandler(w http.ResponseWriter, r *http.Request) {
+	DBSPostHandler(w, r, "pause")
+}
+
+// SubscriptionResumeHandler provides access to ResumeSubscription DBS API
+// POST API takes no argument, the payload should be supplied as JSON
+func SubscriptionResumeHandler(w http.ResponseWriter, r *http.Request) {
+	DBSPostHandler(w, r, "resume")
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"sync/atomic"
 	"time"
 
 	"github.com/dmwm/dbs2go/dbs"
@@ -125,6 +126,26 @@ type Metrics struct {
 	MigrationCompleted  uint64 `json:"migrationCompleted"`  // total number of completed migration requests across all services
 	MigrationQueued     uint64 `json:"migrationQueued"`     // total number of queued migration requests across all services
 	MigrationExistInDB  uint64 `json:"migrationExistInDB"`  // total number of exist in db migration requests across all services
+	MigrationSubscribed uint64 `json:"migrationSubscribed"` // total number of migration requests created by subscriptions
+
+	// remote calls metrics
+	RemoteCalls   uint64  `json:"remoteCalls"`   // total number of remote HTTP calls
+	RemoteErrors  uint64  `json:"remoteErrors"`  // total number of failed remote HTTP calls
+	RemoteRetries uint64  `json:"remoteRetries"` // total number of retried remote HTTP calls
+	AvgRemoteTime float64 `json:"avgRemoteTime"` // avg remote HTTP call time
+
+	// response cache metrics
+	CacheHits          uint64 `json:"cacheHits"`          // total number of response cache hits
+	CacheMisses        uint64 `json:"cacheMisses"`        // total number of response cache misses
+	CacheEvictions     uint64 `json:"cacheEvictions"`     // total number of evicted cache entries
+	CacheInvalidations uint64 `json:"cacheInvalidations"` // total number of invalidated cache entries
+	CacheEntries       uint64 `json:"cacheEntries"`       // number of response cache entries
+	CacheSize          uint64 `json:"cacheSize"`          // size of response cache in bytes
+
+	// prepared statements cache metrics
+	StmtCacheHits      uint64 `json:"stmtCacheHits"`      // total number of prepared statements cache hits
+	StmtCacheMisses    uint64 `json:"stmtCacheMisses"`    // total number of prepared statements cache misses
+	StmtCacheEvictions uint64 `json:"stmtCacheEvictions"` // total number of evicted prepared statements
 }
 
 func metrics() Metrics {
@@ -194,6 +215,28 @@ func metrics() Metrics {
 	metrics.MigrationCompleted = dbs.TotalCompleted
 	metrics.MigrationQueued = dbs.TotalQueued
 	metrics.MigrationExistInDB = dbs.TotalExistInDB
+	metrics.MigrationSubscribed = dbs.TotalSubscriptionRequests
+
+	// remote calls metrics
+	metrics.RemoteCalls = atomic.LoadUint64(&utils.RemoteMetrics.Calls)
+	metrics.RemoteErrors = atomic.LoadUint64(&utils.RemoteMetrics.Errors)
+	metrics.RemoteRetries = atomic.LoadUint64(&utils.RemoteMetrics.Retries)
+	metrics.AvgRemoteTime = utils.RemoteMetrics.AvgLatency()
+
+	// response cache metrics
+	if cache := dbs.ResponseCache; cache != nil {
+		metrics.CacheHits = atomic.LoadUint64(&cache.Hits)
+		metrics.CacheMisses = atomic.LoadUint64(&cache.Misses)
+		metrics.CacheEvictions = atomic.LoadUint64(&cache.Evictions)
+		metrics.CacheInvalidations = atomic.LoadUint64(&cache.Invalidations)
+		metrics.CacheEntries = uint64(cache.Len())
+		metrics.CacheSize = uint64(cache.Size())
+	}
+
+	// prepared statements cache metrics
+	metrics.StmtCacheHits = atomic.LoadUint64(&dbs.StmtCacheMetrics.Hits)
+	metrics.StmtCacheMisses = atomic.LoadUint64(&dbs.StmtCacheMetrics.Misses)
+	metrics.StmtCacheEvictions = atomic.LoadUint64(&dbs.StmtCacheMetrics.Evictions)
 
 	rstat.Update()
 
@@ -440,6 +483,55 @@ func promMetrics(prefix string) string {
 	out += fmt.Sprintf("# HELP %s_exist_in_db reports total number of exist in db migration requests\n", prefix)
 	out += fmt.Sprintf("# TYPE %s_exist_in_db counter\n", prefix)
 	out += fmt.Sprintf("%s_exist_in_db %v\n", prefix, data.MigrationExistInDB)
+
+	out += fmt.Sprintf("# HELP %s_subscribed reports total number of migration requests created by subscriptions\n", prefix)
+	out += fmt.Sprintf("# TYPE %s_subscribed counter\n", prefix)
+	out += fmt.Sprintf("%s_subscribed %v\n", prefix, data.MigrationSubscribed)
+
+	// remote calls metrics
+	out += fmt.Sprintf("# HELP %s_remote_calls reports total number of remote HTTP calls\n", prefix)
+	out += fmt.Sprintf("# TYPE %s_remote_calls counter\n", prefix)
+	out += fmt.Sprintf("%s_remote_calls %v\n", prefix, data.RemoteCalls)
+	out += fmt.Sprintf("# HELP %s_remote_errors reports total number of failed remote HTTP calls\n", prefix)
+	out += fmt.Sprintf("# TYPE %s_remote_errors counter\n", prefix)
+	out += fmt.Sprintf("%s_remote_errors %v\n", prefix, data.RemoteErrors)
+	out += fmt.Sprintf("# HELP %s_remote_retries reports total number of retried remote HTTP calls\n", prefix)
+	out += fmt.Sprintf("# TYPE %s_remote_retries counter\n", prefix)
+	out += fmt.Sprintf("%s_remote_retries %v\n", prefix, data.RemoteRetries)
+	out += fmt.Sprintf("# HELP %s_avg_remote_time reports average remote HTTP call time in seconds\n", prefix)
+	out += fmt.Sprintf("# TYPE %s_avg_remote_time gauge\n", prefix)
+	out += fmt.Sprintf("%s_avg_remote_time %v\n", prefix, data.AvgRemoteTime)
+
+	// response cache metrics
+	out += fmt.Sprintf("# HELP %s_cache_hits reports total number of response cache hits\n", prefix)
+	out += fmt.Sprintf("# TYPE %s_cache_hits counter\n", prefix)
+	out += fmt.Sprintf("%s_cache_hits %v\n", prefix, data.CacheHits)
+	out += fmt.Sprintf("# HELP %s_cache_misses reports total number of response cache misses\n", prefix)
+	out += fmt.Sprintf("# TYPE %s_cache_misses counter\n", prefix)
+	out += fmt.Sprintf("%s_cache_misses %v\n", prefix, data.CacheMisses)
+	out += fmt.Sprintf("# HELP %s_cache_evictions reports total number of evicted or expired response cache entries\n", prefix)
+	out += fmt.Sprintf("# TYPE %s_cache_evictions counter\n", prefix)
+	out += fmt.Sprintf("%s_cache_evictions %v\n", prefix, data.CacheEvictions)
+	out += fmt.Sprintf("# HELP %s_cache_invalidations reports total number of response cache entries invalidated by writer APIs\n", prefix)
+	out += fmt.Sprintf("# TYPE %s_cache_invalidations counter\n", prefix)
+	out += fmt.Sprintf("%s_cache_invalidations %v\n", prefix, data.CacheInvalidations)
+	out += fmt.Sprintf("# HELP %s_cache_entries reports number of response cache entries\n", prefix)
+	out += fmt.Sprintf("# TYPE %s_cache_entries gauge\n", prefix)
+	out += fmt.Sprintf("%s_cache_entries %v\n", prefix, data.CacheEntries)
+	out += fmt.Sprintf("# HELP %s_cache_size reports size of response cache in bytes\n", prefix)
+	out += fmt.Sprintf("# TYPE %s_cache_size gauge\n", prefix)
+	out += fmt.Sprintf("%s_cache_size %v\n", prefix, data.CacheSize)
+
+	// prepared statements cache metrics
+	out += fmt.Sprintf("# HELP %s_stmt_cache_hits reports total number of prepared statements cache hits\n", prefix)
+	out += fmt.Sprintf("# TYPE %s_stmt_cache_hits counter\n", prefix)
+	out += fmt.Sprintf("%s_stmt_cache_hits %v\n", prefix, data.StmtCacheHits)
+	out += fmt.Sprintf("# HELP %s_stmt_cache_misses reports total number of prepared statements cache misses\n", prefix)
+	out += fmt.Sprintf("# TYPE %s_stmt_cache_misses counter\n", prefix)
+	out += fmt.Sprintf("%s_stmt_cache_misses %v\n", prefix, data.StmtCacheMisses)
+	out += fmt.Sprintf("# HELP %s_stmt_cache_evictions reports total number of evicted prepared statements\n", prefix)
+	out += fmt.Sprintf("# TYPE %s_stmt_cache_evictions counter\n", prefix)
+	out += fmt.Sprintf("%s_stmt_cache_evictions %v\n", prefix, data.StmtCacheEvictions)
 	return out
 }
 
@@ -7,7 +7,6 @@ import (
 	"net/http"
 	"net/url"
 	"runtime"
-	"strings"
 	"time"
 
 	"github.com/dmwm/dbs2go/dbs"
@@ -134,19 +133,8 @@ func headerMiddleware(next http.Handler) http.Handler {
 		server := fmt.Sprintf("dbs2go (%s %s)", goVersion, tstamp)
 		w.Header().Add("Server", server)
 
-		// settng Etag and its expiration
-		if r.Method == "GET" && Config.Etag != "" && Config.CacheControl != "" {
-			etag := Etag(Config.Etag, false)
-			w.Header().Set("Etag", etag)
-			w.Header().Set("Cache-Control", Config.CacheControl) // 5 minutes
-			if match := r.Header.Get("If-None-Match"); match != "" {
-				if strings.Contains(match, etag) {
-					w.WriteHeader(http.StatusNotModified)
-					return
-				}
-			}
-		}
-
+		// ETag, Last-Modified and Cache-Control headers are set by
+		// individual GET APIs based on their content, see etag.go
 		next.ServeHTTP(w, r)
 	})
 }
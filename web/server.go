@@ -105,6 +105,12 @@ func Handlers() *mux.Router {
 		router.HandleFunc(basePath("/remove"), MigrationRemoveHandler).Methods("POST")
 		router.HandleFunc(basePath("/status"), MigrationStatusHandler).Methods("GET")
 		router.HandleFunc(basePath("/total"), MigrationTotalHandler).Methods("GET")
+		router.HandleFunc(basePath("/verify"), MigrationVerifyHandler).Methods("GET")
+		router.HandleFunc(basePath("/subscribe"), SubscriptionSubmitHandler).Methods("POST")
+		router.HandleFunc(basePath("/subscriptions"), SubscriptionStatusHandler).Methods("GET")
+		router.HandleFunc(basePath("/pause"), SubscriptionPauseHandler).Methods("POST")
+		router.HandleFunc(basePath("/resume"), SubscriptionResumeHandler).Methods("POST")
+		router.HandleFunc(basePath("/cleanup"), MigrationCleanupHandler).Methods("GET", "POST")
 		router.HandleFunc(basePath("/blocks"), BlocksHandler).Methods("GET")
 		router.HandleFunc(basePath("/bulkblocks"), BulkBlocksHandler).Methods("POST")
 		router.HandleFunc(basePath("/blockparents"), BlocksHandler).Methods("GET")
@@ -142,6 +148,11 @@ func Handlers() *mux.Router {
 		router.HandleFunc(basePath("/filelumis"), FileLumisHandler).Methods("GET")
 		router.HandleFunc(basePath("/datasetchildren"), DatasetChildrenHandler).Methods("GET")
 		router.HandleFunc(basePath("/datasetparents"), DatasetParentsHandler).Methods("GET")
+		router.HandleFunc(basePath("/lineage"), LineageHandler).Methods("GET")
+		router.HandleFunc(basePath("/lumimask"), LumiMaskHandler).Methods("GET")
+		router.HandleFunc(basePath("/compare"), CompareHandler).Methods("GET")
+		router.HandleFunc(basePath("/lumiduplicates"), LumiDuplicatesHandler).Methods("GET")
+		router.HandleFunc(basePath("/duplicates"), DuplicatesHandler).Methods("GET")
 		router.HandleFunc(basePath("/acquisitioneras_ci"), AcquisitionErasCiHandler).Methods("GET")
 
 		router.HandleFunc(basePath("/blockparents"), BlockParentsHandler).Methods("POST")
@@ -149,6 +160,10 @@ func Handlers() *mux.Router {
 		router.HandleFunc(basePath("/filelumis"), FileLumisHandler).Methods("POST")
 		router.HandleFunc(basePath("/datasetlist"), DatasetListHandler).Methods("POST")
 		router.HandleFunc(basePath("/fileparentsbylumi"), FileParentsByLumiHandler).Methods("POST")
+		router.HandleFunc(basePath("/fileancestry"), FileAncestryHandler).Methods("POST")
+		router.HandleFunc(basePath("/filesbymask"), FilesByMaskHandler).Methods("POST")
+		router.HandleFunc(basePath("/lfnlookup"), LfnLookupHandler).Methods("POST")
+		router.HandleFunc(basePath("/lumimask"), LumiMaskHandler).Methods("POST")
 
 		router.HandleFunc(basePath("/dbstats"), DBStatsHandler).Methods("GET")
 		router.HandleFunc(basePath("/status"), StatusHandler).Methods("GET")
@@ -183,6 +198,7 @@ func Handlers() *mux.Router {
 		router.HandleFunc(basePath("/outputconfigs"), OutputConfigsHandler).Methods("POST", "GET")
 		router.HandleFunc(basePath("/fileparents"), FileParentsHandler).Methods("POST", "GET")
 		router.HandleFunc(basePath("/fileparentsbylumi"), FileParentsByLumiHandler).Methods("POST", "GET")
+		router.HandleFunc(basePath("/inferfileparents"), InferFileParentsHandler).Methods("POST")
 		router.HandleFunc(basePath("/blockparents"), BlockParentsHandler).Methods("GET")
 		router.HandleFunc(basePath("/datasetparents"), DatasetParentsHandler).Methods("GET")
 	}
@@ -276,6 +292,7 @@ func dbMonitor(dbtype, dburi string, interval int) {
 			// if we get ORA error we should restart DB connection
 			log.Println("dbMonitor: unable to get test data query, error", err)
 			if dbs.DB != nil {
+				dbs.CloseStmtCache(dbs.DB)
 				dbs.DB.Close()
 			}
 			db, dberr := dbInit(dbtype, dburi)
@@ -290,6 +307,15 @@ func dbMonitor(dbtype, dburi string, interval int) {
 	}
 }
 
+// helper function to perform health checks of DB replicas
+// it should be used as goroutine in main server
+func replicaMonitor(interval int) {
+	for {
+		dbs.CheckReplicas()
+		time.Sleep(time.Duration(interval) * time.Second)
+	}
+}
+
 // Server represents main web server for DBS service
 //gocyclo:ignore
 func Server(configFile string) {
@@ -302,6 +328,11 @@ func Server(configFile string) {
 	utils.STATICDIR = Config.StaticDir
 	utils.BASE = Config.Base
 	utils.Localhost = fmt.Sprintf("http://localhost:%d", Config.Port)
+	utils.RemoteTimeout = Config.RemoteTimeout
+	utils.RemoteRetries = Config.RemoteRetries
+	utils.RemoteBackoff = Config.RemoteBackoff
+	utils.RemoteMaxSize = Config.RemoteMaxSize
+	utils.ParquetRowGroupSize = Config.ParquetRowGroupSize
 	log.SetFlags(0)
 	if Config.Verbose > 0 {
 		log.SetFlags(log.Lshortfile)
@@ -357,6 +388,17 @@ func Server(configFile string) {
 	dbs.FileLumiInsertMethod = Config.FileLumiInsertMethod
 	dbs.ApiParametersFile = Config.ApiParametersFile
 	dbs.TlsRefreshInterval = Config.TlsRefreshInterval
+	dbs.StmtCacheSize = Config.StmtCacheSize
+	dbs.GuardrailAction = Config.QueryGuardrails
+	dbs.GuardrailMaxRows = Config.GuardrailMaxRows
+	dbs.GuardrailMaxCost = Config.GuardrailMaxCost
+	dbs.LineageMethod = Config.LineageMethod
+	dbs.LineageMaxDepth = Config.LineageMaxDepth
+
+	// initialize response cache of reader APIs
+	if len(Config.ResponseCacheTTLs) > 0 {
+		dbs.ResponseCache = utils.NewLRUCache(Config.ResponseCacheSize)
+	}
 
 	// initialize templates
 	tmplData := make(map[string]interface{})
@@ -387,6 +429,23 @@ func Server(configFile string) {
 	dbs.DBTYPE = dbtype
 	defer dbs.DB.Close()
 
+	// setup read replicas of DBS DB, they should use the same DB type and owner
+	for _, fname := range Config.ReplicaDBFiles {
+		rtype, ruri, rowner := dbs.ParseDBFile(fname)
+		if rtype != dbtype || rowner != dbowner {
+			log.Fatalf("replica %s should have the same DB type and owner as primary DB", fname)
+		}
+		// we do not ping replica here since its health is checked by replicaMonitor
+		rdb, err := sql.Open(rtype, ruri)
+		if err != nil {
+			log.Fatal(err)
+		}
+		rdb.SetMaxOpenConns(Config.MaxDBConnections)
+		rdb.SetMaxIdleConns(Config.MaxIdleConnections)
+		dbs.Replicas = append(dbs.Replicas, dbs.NewReplica(dbs.ReplicaName(ruri), rdb))
+		defer rdb.Close()
+	}
+
 	// setup MigrationDB access
 	if Config.ServerType == "DBSMigration" || Config.ServerType == "DBSMigrate" {
 		log.Println("parse Config.MigrationDBFile:", Config.MigrationDBFile)
@@ -418,10 +477,20 @@ func Server(configFile string) {
 	dbs.MigrationServerInterval = Config.MigrationServerInterval
 	dbs.MigrationCleanupInterval = Config.MigrationCleanupInterval
 	dbs.MigrationCleanupOffset = Config.MigrationCleanupOffset
+	dbs.MigrationCleanupRetention = map[int]int64{
+		dbs.COMPLETED:   Config.MigrationCleanupCompleted,
+		dbs.TERM_FAILED: Config.MigrationCleanupTermFailed,
+		dbs.EXIST_IN_DB: Config.MigrationCleanupExistInDB,
+	}
+	dbs.MigrationCleanupArchive = Config.MigrationCleanupArchive
 	dbs.MigrationRetries = Config.MigrationRetries
+	dbs.MigrationVerify = Config.MigrationVerify
+	dbs.MigrationSubscriptionInterval = Config.MigrationSubscriptionInterval
 
 	// DBS bulkblocks API
 	dbs.ConcurrentBulkBlocks = Config.ConcurrentBulkBlocks
+	dbs.BulkBlocksLumiCheck = Config.BulkBlocksLumiCheck
+	dbs.BulkBlocksParentageCheck = Config.BulkBlocksParentageCheck
 
 	// init graphql
 	if Config.GraphQLSchema != "" {
@@ -499,11 +568,18 @@ func Server(configFile string) {
 		go dbMonitor(dbtype, dburi, Config.DBMonitoringInterval)
 	}
 
+	// start replicas health check goroutine
+	if len(dbs.Replicas) > 0 {
+		go replicaMonitor(Config.ReplicaCheckInterval)
+	}
+
 	migDone := make(chan bool)
-	//     clpDone := make(chan bool)
+	clpDone := make(chan bool)
 	if Config.ServerType == "DBSMigration" {
 		go dbs.MigrationServer(dbs.MigrationServerInterval, dbs.MigrationProcessTimeout, migDone)
-		//go dbs.MigrationCleanupServer(dbs.MigrationCleanupInterval, dbs.MigrationCleanupOffset, clpDone)
+		if Config.MigrationCleanup {
+			go dbs.MigrationCleanupServer(dbs.MigrationCleanupInterval, dbs.MigrationCleanupOffset, clpDone)
+		}
 	}
 
 	// properly stop our HTTP and Migration Servers
@@ -525,9 +601,9 @@ func Server(configFile string) {
 		migDone <- true
 	}
 	// send notification to stop cleanup migration server
-	//     if Config.ServerType == "DBSMigration" {
-	//         clpDone <- true
-	//     }
+	if Config.ServerType == "DBSMigration" && Config.MigrationCleanup {
+		clpDone <- true
+	}
 
 	// add extra timeout for shutdown service stuff
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)